@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/SparkNFT/key_server/abi"
 	"github.com/SparkNFT/key_server/config"
@@ -17,7 +19,6 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"golang.org/x/xerrors"
 )
 
@@ -28,66 +29,67 @@ const (
 )
 
 var (
-	ContractAddress map[string]common.Address
+	ContractAddress     map[string]common.Address
+	contractAddressLock sync.RWMutex
+	chainIDs            = make(map[string]*big.Int)
+	chainIDsLock        sync.Mutex
 
 	EventPublishHash  = common.HexToHash("0x072ee21d81ebd9fc5f68a2c36d04cbbd9eff1e2567a48dd7ecce61d5af159fad")
 	EventTransferHash = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
 )
 
-// Init generates ethclient and contract instance.
-func Init(chainName string) (contract *abi.SparkLink, client *ethclient.Client, err error) {
-	client, err = ethclient.Dial(config.C.Chain[chainName].RPCUrl)
+// Init returns contract instance of given chain, and the RPC pool of
+// chain it is bound to. The pool is shared: never close it.
+func Init(chainName string) (contract *abi.SparkLink, client Client, err error) {
+	chainConfig, ok := config.Get().Chain[chainName]
+	if !ok || chainConfig == nil {
+		return nil, nil, xerrors.Errorf("%w: %s", ErrChainNotConfigured, chainName)
+	}
+	pool, err := PoolOf(chainName)
 	if err != nil {
-		return nil, nil, xerrors.Errorf("error when dialing client: %w", err)
+		return nil, nil, err
 	}
-	contract, err = abi.NewSparkLink(common.HexToAddress(config.C.Chain[chainName].ContractAddress), client)
+	contract, err = abi.NewSparkLink(common.HexToAddress(chainConfig.ContractAddress), pool)
 	if err != nil {
 		return nil, nil, xerrors.Errorf("error when initlizing client: %w", err)
 	}
 
+	contractAddressLock.Lock()
+	defer contractAddressLock.Unlock()
 	if ContractAddress == nil {
 		ContractAddress = make(map[string]common.Address, 0)
 	}
-	ContractAddress[chainName] = common.HexToAddress(config.C.Chain[chainName].ContractAddress)
+	ContractAddress[chainName] = common.HexToAddress(chainConfig.ContractAddress)
 
-	return contract, client, nil
+	return contract, pool, nil
 }
 
-// GenerateTrasactOpts returns a transact option to be used when
-// interacting with contract.
-func GenerateTransactOps(client *ethclient.Client, private_hex string) (auth *bind.TransactOpts, err error) {
-	chain_id, err := client.NetworkID(context.Background())
-	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
-	}
-	private_key, err := crypto.HexToECDSA(private_hex)
-	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
-	}
-
-	auth, err = bind.NewKeyedTransactorWithChainID(private_key, chain_id)
-	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
-	}
-
-	nonce, err := client.PendingNonceAt(context.Background(), auth.From)
-	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
+// ChainIDOf returns chain ID of given chain. Result is cached after
+// first successful query. The query runs without holding the cache
+// lock, so an unreachable chain does not hold back others.
+func ChainIDOf(client Client, chainName string) (chain_id *big.Int, err error) {
+	chainIDsLock.Lock()
+	chain_id, ok := chainIDs[chainName]
+	chainIDsLock.Unlock()
+	if ok {
+		return chain_id, nil
 	}
-	auth.Nonce = big.NewInt(int64(nonce))
 
-	gas_tip_cap, err := client.SuggestGasTipCap(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	chain_id, err = client.ChainID(ctx)
 	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
+		return nil, xerrors.Errorf("error when fetching chain ID: %w", err)
 	}
-	auth.GasTipCap = gas_tip_cap
-	auth.GasLimit = uint64(10000000)
 
-	return auth, nil
+	chainIDsLock.Lock()
+	defer chainIDsLock.Unlock()
+	chainIDs[chainName] = chain_id
+	return chain_id, nil
 }
 
 // BlockHashOf returns block hash of a given block number.
-func BlockHashOf(client *ethclient.Client, block_number *big.Int) (block_hash common.Hash, err error) {
+func BlockHashOf(client Client, block_number *big.Int) (block_hash common.Hash, err error) {
 	block_header, err := client.HeaderByNumber(context.TODO(), block_number)
 	if err != nil {
 		return common.HexToHash("0x"), xerrors.Errorf("error when fetching block [%v] header: %w", block_number, err)
@@ -99,6 +101,10 @@ func BlockHashOf(client *ethclient.Client, block_number *big.Int) (block_hash co
 func IsOwnerOfNFT(contract *abi.SparkLink, nft_id *big.Int, address common.Address) (bool, error) {
 	found_address, err := contract.OwnerOf(nil, nft_id)
 	if err != nil {
+		// Contract reverts with "ERC721: owner query for nonexistent token"
+		if strings.Contains(err.Error(), "nonexistent") {
+			return false, xerrors.Errorf("%w: %s", ErrNFTNonexistent, nft_id.String())
+		}
 		return false, xerrors.Errorf("error when calling ownerOf: %w", err)
 	}
 
@@ -120,21 +126,28 @@ func RootNFTIdOf(nft_id uint64) (root uint64) {
 }
 
 // GetParentOf returns the parent of given NFT ID
-func GetParentOf(contract *abi.SparkLink, nft_id uint64) (parent uint64, err error) {
-	parent, err = contract.GetFatherByNFTId(nil, nft_id)
+func GetParentOf(ctx context.Context, contract *abi.SparkLink, nft_id uint64) (parent uint64, err error) {
+	parent, err = contract.GetFatherByNFTId(&bind.CallOpts{Context: ctx}, nft_id)
 
 	return parent, err
 }
 
 // GetAllLogsOf returns all logs of this contract in a given block,
-func GetAllLogsOf(client *ethclient.Client, chainName string, block_number *big.Int) (logs []types.Log, err error) {
+func GetAllLogsOf(client Client, chainName string, block_number *big.Int) (logs []types.Log, err error) {
+	_, logs, err = GetBlockLogsOf(context.TODO(), client, chainName, block_number)
+	return logs, err
+}
+
+// GetBlockLogsOf returns header of given block, and all logs of this
+// contract in it.
+func GetBlockLogsOf(ctx context.Context, client Client, chainName string, block_number *big.Int) (block_header *types.Header, logs []types.Log, err error) {
 	logrus.WithFields(logrus.Fields{"chain": chainName, "block_number": block_number.Uint64()}).Debugf("Fetching block headers")
-	block_header, err := client.HeaderByNumber(context.TODO(), block_number)
+	block_header, err = client.HeaderByNumber(ctx, block_number)
 	if err != nil {
 		// typical value:
 		// not found
 		// unknown block
-		return nil, xerrors.Errorf("error when heading block %d: %w", block_number.Uint64(), err)
+		return nil, nil, xerrors.Errorf("error when heading block %d: %w", block_number.Uint64(), err)
 	}
 	// block_hash := block_header.Hash()
 	// logrus.WithField("block_hash", block_hash.Hex()).Debugf("Block hash fetched")
@@ -144,18 +157,24 @@ func GetAllLogsOf(client *ethclient.Client, chainName string, block_number *big.
 		// FromBlock will not return err if block is not found
 		FromBlock: block_header.Number,
 		ToBlock: block_header.Number,
-		Addresses: []common.Address{ContractAddress[chainName]},
+		Addresses: []common.Address{contractAddressOf(chainName)},
 		Topics: [][]common.Hash{{
 			EventTransferHash,
 			EventPublishHash,
 		}},
 	}
 
-	logs, err = client.FilterLogs(context.TODO(), filter_query)
+	logs, err = client.FilterLogs(ctx, filter_query)
 	if err != nil {
-		return nil, xerrors.Errorf("error when getting all logs: %w", err)
+		return nil, nil, xerrors.Errorf("error when getting all logs: %w", err)
 	}
-	return logs, nil
+	return block_header, logs, nil
+}
+
+func contractAddressOf(chainName string) common.Address {
+	contractAddressLock.RLock()
+	defer contractAddressLock.RUnlock()
+	return ContractAddress[chainName]
 }
 
 // FilterEventPublish
@@ -221,9 +240,15 @@ func FilterEventTransfer(contract *abi.SparkLink, logs []types.Log) ([]abi.Spark
 // ValidateSignature validates if a string is signed by given address
 func ValidateSignature(pl, sig string, address common.Address) (result bool, err error) {
 	payload := []byte(pl)
-	signature := hexutil.MustDecode(sig)
+	signature, err := hexutil.Decode(sig)
+	if err != nil {
+		return false, xerrors.Errorf("%w: %s", ErrSignatureMalformed, err.Error())
+	}
+	if len(signature) != 65 {
+		return false, xerrors.Errorf("%w: length %d", ErrSignatureMalformed, len(signature))
+	}
 	if signature[64] != 27 && signature[64] != 28 {
-		return false, fmt.Errorf("Signature Recovery id not supported")
+		return false, xerrors.Errorf("%w: recovery id not supported", ErrSignatureMalformed)
 	}
 	signature[64] -= 27
 
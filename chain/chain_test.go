@@ -0,0 +1,79 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+// chainIDClient answers ChainID only, after release is closed.
+type chainIDClient struct {
+	Client
+	chain_id *big.Int
+	release  chan struct{}
+}
+
+func (client *chainIDClient) ChainID(ctx context.Context) (*big.Int, error) {
+	select {
+	case <-client.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if client.chain_id == nil {
+		return nil, xerrors.New("connection refused")
+	}
+	return client.chain_id, nil
+}
+
+func Test_ChainIDOf(t *testing.T) {
+	t.Cleanup(func() {
+		chainIDsLock.Lock()
+		delete(chainIDs, "test_slow")
+		delete(chainIDs, "test_fast")
+		delete(chainIDs, "test_failing")
+		chainIDsLock.Unlock()
+	})
+
+	t.Run("slow chain does not block others", func(t *testing.T) {
+		slow := &chainIDClient{chain_id: big.NewInt(1), release: make(chan struct{})}
+		slow_done := make(chan struct{})
+		go func() {
+			defer close(slow_done)
+			ChainIDOf(slow, "test_slow")
+		}()
+
+		fast := &chainIDClient{chain_id: big.NewInt(56), release: make(chan struct{})}
+		close(fast.release)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			chain_id, err := ChainIDOf(fast, "test_fast")
+			assert.Nil(t, err)
+			assert.Equal(t, int64(56), chain_id.Int64())
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("blocked by another chain")
+		}
+		close(slow.release)
+		<-slow_done
+	})
+
+	t.Run("failure is not cached", func(t *testing.T) {
+		failing := &chainIDClient{release: make(chan struct{})}
+		close(failing.release)
+		_, err := ChainIDOf(failing, "test_failing")
+		assert.NotNil(t, err)
+
+		failing.chain_id = big.NewInt(137)
+		chain_id, err := ChainIDOf(failing, "test_failing")
+		require.Nil(t, err)
+		assert.Equal(t, int64(137), chain_id.Int64())
+	})
+}
@@ -1,30 +1,70 @@
 package chain
 
 import (
+	"context"
+	"math/big"
+
 	"github.com/SparkNFT/key_server/abi"
 	"github.com/SparkNFT/key_server/config"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"golang.org/x/xerrors"
 )
 
-func GetERC20Info(erc20addr string) (name, symbol string, err error) {
-	// FIXME: chain-switchable
-	client, err := ethclient.Dial(config.C.Chain["etereum"].RPCUrl)
-	if err != nil {
-		return "", "", xerrors.Errorf("%w", err)
+var (
+	// NativeTokenAddress is the token address of NFTs paid with native
+	// currency of the chain, saved as "0x0".
+	NativeTokenAddress = common.Address{}
+)
+
+// ERC20Info is metadata of an ERC20 contract.
+type ERC20Info struct {
+	Name        string
+	Symbol      string
+	Decimals    uint8
+	TotalSupply *big.Int // nil if unknown
+}
+
+// IsNativeToken tells if token address stands for native currency.
+func IsNativeToken(address string) bool {
+	return address == "0x0" || (common.IsHexAddress(address) && common.HexToAddress(address) == NativeTokenAddress)
+}
+
+// NativeTokenOf returns native currency of a chain in the shape of an
+// ERC20 token. Its total supply is unknown.
+func NativeTokenOf(chainName string) (*ERC20Info, error) {
+	chainConfig, ok := config.Get().Chain[chainName]
+	if !ok || chainConfig == nil {
+		return nil, xerrors.Errorf("%w: %s", ErrChainNotConfigured, chainName)
 	}
-	erc20, err := abi.NewERC20(common.HexToAddress(erc20addr), client)
+	native := chainConfig.Native()
+	return &ERC20Info{
+		Name:     native.Name,
+		Symbol:   native.Symbol,
+		Decimals: native.Decimals,
+	}, nil
+}
+
+// ERC20InfoOf queries name, symbol, decimals and total supply of the
+// ERC20 contract at address.
+func ERC20InfoOf(ctx context.Context, client bind.ContractCaller, address common.Address) (*ERC20Info, error) {
+	erc20, err := abi.NewERC20Caller(address, client)
 	if err != nil {
-		return "", "", xerrors.Errorf("%w", err)
+		return nil, xerrors.Errorf("%w", err)
 	}
-	name, err = erc20.Name(nil)
-	if err != nil {
-		return "", "", xerrors.Errorf("%w", err)
+	opts := &bind.CallOpts{Context: ctx}
+	info := &ERC20Info{}
+	if info.Name, err = erc20.Name(opts); err != nil {
+		return nil, xerrors.Errorf("error when calling name of %s: %w", address.Hex(), err)
 	}
-	symbol, err = erc20.Symbol(nil)
-	if err != nil {
-		return "", "", xerrors.Errorf("%w", err)
+	if info.Symbol, err = erc20.Symbol(opts); err != nil {
+		return nil, xerrors.Errorf("error when calling symbol of %s: %w", address.Hex(), err)
+	}
+	if info.Decimals, err = erc20.Decimals(opts); err != nil {
+		return nil, xerrors.Errorf("error when calling decimals of %s: %w", address.Hex(), err)
+	}
+	if info.TotalSupply, err = erc20.TotalSupply(opts); err != nil {
+		return nil, xerrors.Errorf("error when calling totalSupply of %s: %w", address.Hex(), err)
 	}
-	return name, symbol, nil
+	return info, nil
 }
@@ -0,0 +1,95 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/SparkNFT/key_server/abi"
+	"github.com/SparkNFT/key_server/config"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+// fakeERC20 answers ERC20 calls of any address, counting them.
+type fakeERC20 struct {
+	t       *testing.T
+	calls   int
+	fail    bool
+	outputs map[string]interface{}
+}
+
+func (erc20 *fakeERC20) CodeAt(ctx context.Context, contract common.Address, block_number *big.Int) ([]byte, error) {
+	return []byte{0x60}, nil
+}
+
+func (erc20 *fakeERC20) CallContract(ctx context.Context, call ethereum.CallMsg, block_number *big.Int) ([]byte, error) {
+	erc20.calls += 1
+	if erc20.fail {
+		return nil, xerrors.New("connection refused")
+	}
+	parsed, err := abi.ERC20MetaData.GetAbi()
+	require.Nil(erc20.t, err)
+	method, err := parsed.MethodById(call.Data)
+	require.Nil(erc20.t, err)
+	return method.Outputs.Pack(erc20.outputs[method.Name])
+}
+
+func new_fake_erc20(t *testing.T) *fakeERC20 {
+	return &fakeERC20{t: t, outputs: map[string]interface{}{
+		"name":        "Test Token",
+		"symbol":      "TEST",
+		"decimals":    uint8(6),
+		"totalSupply": big.NewInt(1000000),
+	}}
+}
+
+func Test_ERC20InfoOf(t *testing.T) {
+	address := common.HexToAddress("0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F")
+
+	t.Run("success", func(t *testing.T) {
+		erc20 := new_fake_erc20(t)
+		info, err := ERC20InfoOf(context.Background(), erc20, address)
+		require.Nil(t, err)
+		assert.Equal(t, "Test Token", info.Name)
+		assert.Equal(t, "TEST", info.Symbol)
+		assert.Equal(t, uint8(6), info.Decimals)
+		assert.Equal(t, "1000000", info.TotalSupply.String())
+		assert.Equal(t, 4, erc20.calls)
+	})
+
+	t.Run("chain unreachable", func(t *testing.T) {
+		erc20 := new_fake_erc20(t)
+		erc20.fail = true
+		_, err := ERC20InfoOf(context.Background(), erc20, address)
+		assert.NotNil(t, err)
+	})
+}
+
+func Test_NativeTokenOf(t *testing.T) {
+	config.Set(&config.Config{Chain: map[string]*config.ChainConfig{
+		"ethereum": {},
+		"polygon":  {NativeCurrency: config.NativeCurrency{Name: "Matic", Symbol: "MATIC", Decimals: 18}},
+	}})
+	defer config.Set(nil)
+
+	for _, native := range []string{"0x0", NativeTokenAddress.Hex()} {
+		assert.True(t, IsNativeToken(native))
+	}
+	assert.False(t, IsNativeToken("0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F"))
+
+	token, err := NativeTokenOf("ethereum")
+	require.Nil(t, err)
+	assert.Equal(t, "ETH", token.Symbol)
+	assert.Equal(t, uint8(18), token.Decimals)
+	assert.Nil(t, token.TotalSupply)
+	token, err = NativeTokenOf("polygon")
+	require.Nil(t, err)
+	assert.Equal(t, "MATIC", token.Symbol)
+
+	_, err = NativeTokenOf("etereum")
+	assert.True(t, xerrors.Is(err, ErrChainNotConfigured))
+}
@@ -0,0 +1,15 @@
+package chain
+
+import "golang.org/x/xerrors"
+
+// Sentinel errors returned by chain functions. Callers should use
+// xerrors.Is() against these instead of matching error strings.
+var (
+	ErrNFTNonexistent     = xerrors.New("NFT not found on chain")
+	ErrNFTNotOwned        = xerrors.New("NFT not owned")
+	ErrSignatureMalformed = xerrors.New("signature malformed")
+	ErrChainNotConfigured = xerrors.New("chain not configured")
+	ErrTxReverted         = xerrors.New("transaction reverted")
+	ErrTxTimeout          = xerrors.New("transaction not mined in time")
+	ErrTxFeeTooHigh       = xerrors.New("transaction fee above limit")
+)
@@ -0,0 +1,670 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/metrics"
+	"github.com/SparkNFT/key_server/ratelimit"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Weight of the latest result in health score of an endpoint.
+	poolScoreWeight = 0.2
+	// An endpoint failing in a row is skipped for 1s, 2s, 4s... up to
+	// poolMaxBackoff.
+	poolMaxBackoff = time.Minute
+	// Replaced pools are closed after this, letting running calls end.
+	poolCloseDelay = time.Minute
+)
+
+// Client is what this server needs from an Ethereum node. Both
+// *ethclient.Client and *Pool implement it.
+type Client interface {
+	bind.ContractBackend
+	ChainID(ctx context.Context) (*big.Int, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	TransactionReceipt(ctx context.Context, tx_hash common.Hash) (*types.Receipt, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+var (
+	pools     = make(map[string]*Pool)
+	poolsLock sync.Mutex
+)
+
+func init() {
+	config.OnReload(func(old, new *config.Config) {
+		prunePools(new)
+	})
+}
+
+// PoolOf returns the RPC pool of given chain, shared by all callers of
+// this process. The pool is built again when endpoints of chain are
+// changed by a config reload.
+func PoolOf(chainName string) (*Pool, error) {
+	chainConfig, ok := config.Get().Chain[chainName]
+	if !ok || chainConfig == nil {
+		return nil, xerrors.Errorf("%w: %s", ErrChainNotConfigured, chainName)
+	}
+	endpoints := chainConfig.RPCEndpoints()
+
+	poolsLock.Lock()
+	defer poolsLock.Unlock()
+	if pool, ok := pools[chainName]; ok {
+		if pool.sameConfig(endpoints, chainConfig.RPC) {
+			return pool, nil
+		}
+		time.AfterFunc(poolCloseDelay, pool.Close)
+		delete(pools, chainName)
+	}
+
+	pool, err := NewPool(chainName, endpoints, chainConfig.RPC)
+	if err != nil {
+		return nil, err
+	}
+	pools[chainName] = pool
+	return pool, nil
+}
+
+// prunePools closes pools of chains removed or disabled in c, after
+// poolCloseDelay.
+func prunePools(c *config.Config) {
+	poolsLock.Lock()
+	defer poolsLock.Unlock()
+	for chainName, pool := range pools {
+		if chainConfig, ok := c.Chain[chainName]; ok && chainConfig != nil && chainConfig.Enabled {
+			continue
+		}
+		time.AfterFunc(poolCloseDelay, pool.Close)
+		delete(pools, chainName)
+	}
+}
+
+// Pool sends every request to the healthiest endpoint of a chain, and
+// fails over to the next one when an endpoint is down, too slow, rate
+// limited or lagging behind the others.
+type Pool struct {
+	chainName  string
+	endpoints  []*rpcEndpoint
+	poolConfig config.RPCPoolConfig
+	limits     *ratelimit.MemoryStore
+	stop       chan struct{}
+	stopOnce   sync.Once
+	log        *logrus.Entry
+}
+
+type rpcEndpoint struct {
+	config config.RPCEndpoint
+	name   string // Host only: URLs often contain API keys
+	rule   *ratelimit.Rule
+
+	lock      sync.Mutex // Guards fields below
+	client    *ethclient.Client
+	score     float64 // Moving average of successes, 1 is perfect
+	failures  uint    // In a row
+	downUntil time.Time
+	height    uint64 // Newest head seen
+	lagging   bool
+}
+
+// EndpointStatus is a snapshot of an endpoint of a pool.
+type EndpointStatus struct {
+	Name    string  `json:"name"`
+	Score   float64 `json:"score"`
+	Down    bool    `json:"down"`
+	Lagging bool    `json:"lagging"`
+	Height  uint64  `json:"height"`
+}
+
+// NewPool creates a pool over endpoints, and starts checking their
+// heads every pool_config.Interval(). Endpoints are dialed on first use.
+func NewPool(chainName string, endpoints []config.RPCEndpoint, pool_config config.RPCPoolConfig) (*Pool, error) {
+	if len(endpoints) == 0 {
+		return nil, xerrors.Errorf("no rpc_url configured for chain %s", chainName)
+	}
+	pool := &Pool{
+		chainName:  chainName,
+		poolConfig: pool_config,
+		limits:     ratelimit.NewMemoryStore(),
+		stop:       make(chan struct{}),
+		log:        logrus.WithFields(logrus.Fields{"module": "rpc_pool", "chain": chainName}),
+	}
+	for _, endpoint_config := range endpoints {
+		pool.endpoints = append(pool.endpoints, &rpcEndpoint{
+			config: endpoint_config,
+			name:   endpointName(endpoint_config.URL),
+			rule:   ratelimit.RuleFromConfig(endpoint_config.RateLimit),
+			score:  1,
+		})
+	}
+	go pool.checkHeads()
+	return pool, nil
+}
+
+func endpointName(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return "invalid"
+	}
+	return parsed.Host
+}
+
+func (pool *Pool) sameConfig(endpoints []config.RPCEndpoint, pool_config config.RPCPoolConfig) bool {
+	if len(endpoints) != len(pool.endpoints) || pool_config.MaxLag() != pool.poolConfig.MaxLag() ||
+		pool_config.Interval() != pool.poolConfig.Interval() {
+		return false
+	}
+	for i, endpoint := range endpoints {
+		current := pool.endpoints[i].config
+		if endpoint.URL != current.URL || endpoint.Timeout != current.Timeout ||
+			!sameRule(endpoint.RateLimit, current.RateLimit) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameRule(a, b *config.RateLimitRule) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Close stops head checks and closes all connections.
+func (pool *Pool) Close() {
+	pool.stopOnce.Do(func() {
+		close(pool.stop)
+		for _, endpoint := range pool.endpoints {
+			endpoint.lock.Lock()
+			if endpoint.client != nil {
+				endpoint.client.Close()
+				endpoint.client = nil
+			}
+			endpoint.lock.Unlock()
+		}
+	})
+}
+
+// Status returns health of every endpoint, in config order.
+func (pool *Pool) Status() []EndpointStatus {
+	result := make([]EndpointStatus, 0, len(pool.endpoints))
+	now := time.Now()
+	for _, endpoint := range pool.endpoints {
+		endpoint.lock.Lock()
+		result = append(result, EndpointStatus{
+			Name:    endpoint.name,
+			Score:   endpoint.score,
+			Down:    now.Before(endpoint.downUntil),
+			Lagging: endpoint.lagging,
+			Height:  endpoint.height,
+		})
+		endpoint.lock.Unlock()
+	}
+	return result
+}
+
+// candidates returns endpoints to try in order: usable ones by score,
+// then down or lagging ones as last resort.
+func (pool *Pool) candidates() []*rpcEndpoint {
+	type ranked struct {
+		endpoint *rpcEndpoint
+		usable   bool
+		score    float64
+	}
+	now := time.Now()
+	ranking := make([]ranked, 0, len(pool.endpoints))
+	for _, endpoint := range pool.endpoints {
+		endpoint.lock.Lock()
+		ranking = append(ranking, ranked{
+			endpoint: endpoint,
+			usable:   !now.Before(endpoint.downUntil) && !endpoint.lagging,
+			score:    endpoint.score,
+		})
+		endpoint.lock.Unlock()
+	}
+	sort.SliceStable(ranking, func(i, j int) bool {
+		if ranking[i].usable != ranking[j].usable {
+			return ranking[i].usable
+		}
+		return ranking[i].score > ranking[j].score
+	})
+
+	result := make([]*rpcEndpoint, 0, len(ranking))
+	for _, item := range ranking {
+		result = append(result, item.endpoint)
+	}
+	return result
+}
+
+// call runs fn on endpoints in order of preference until one gives an
+// answer. Errors telling about the request itself (reverts, not found,
+// invalid params...) are answers too. If min_height is not zero, only
+// endpoints having seen that block are used.
+func (pool *Pool) call(ctx context.Context, method string, min_height uint64, fn func(ctx context.Context, client *ethclient.Client) error) error {
+	for {
+		var last_err error
+		retry_after := time.Duration(0)
+		for _, endpoint := range pool.candidates() {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			wait, err := pool.take(endpoint)
+			if err != nil {
+				last_err = err
+				continue
+			}
+			if wait > 0 {
+				if retry_after == 0 || wait < retry_after {
+					retry_after = wait
+				}
+				continue
+			}
+			if min_height > 0 {
+				has_block, wait := pool.hasBlock(ctx, endpoint, min_height)
+				if wait > 0 {
+					if retry_after == 0 || wait < retry_after {
+						retry_after = wait
+					}
+					continue
+				}
+				if !has_block {
+					last_err = xerrors.Errorf("%s has not reached block %d: %w", endpoint.name, min_height, ethereum.NotFound)
+					continue
+				}
+			}
+
+			err = pool.callEndpoint(ctx, endpoint, method, fn)
+			if err == nil || !isEndpointError(ctx, err) {
+				return err
+			}
+			last_err = err
+		}
+
+		if retry_after == 0 {
+			if last_err == nil {
+				last_err = xerrors.New("no endpoint available")
+			}
+			return xerrors.Errorf("error when calling %s on chain %s: %w", method, pool.chainName, last_err)
+		}
+		// Every endpoint is rate limited.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retry_after):
+		}
+	}
+}
+
+// take consumes a request of endpoint's rate limit. Returns how long
+// to wait if limited.
+func (pool *Pool) take(endpoint *rpcEndpoint) (time.Duration, error) {
+	if endpoint.rule == nil {
+		return 0, nil
+	}
+	decision, err := pool.limits.Take(endpoint.config.URL, *endpoint.rule, time.Now())
+	if err != nil {
+		return 0, xerrors.Errorf("error when rate limiting %s: %w", endpoint.name, err)
+	}
+	if !decision.Allowed {
+		metrics.RPCRequests.WithLabelValues(pool.chainName, endpoint.name, "rate_limited").Inc()
+		return decision.RetryAfter, nil
+	}
+	return 0, nil
+}
+
+// hasBlock tells if endpoint has seen given block, asking its head if
+// not known yet. Asking counts against endpoint's rate limit: if
+// limited, returns how long to wait, and the block is unknown.
+func (pool *Pool) hasBlock(ctx context.Context, endpoint *rpcEndpoint, height uint64) (has_block bool, wait time.Duration) {
+	endpoint.lock.Lock()
+	seen := endpoint.height
+	endpoint.lock.Unlock()
+	if seen >= height {
+		return true, 0
+	}
+	wait, err := pool.take(endpoint)
+	if err != nil || wait > 0 {
+		return false, wait
+	}
+	err = pool.callEndpoint(ctx, endpoint, "eth_blockNumber", func(ctx context.Context, client *ethclient.Client) (err error) {
+		seen, err = client.BlockNumber(ctx)
+		return err
+	})
+	if err != nil {
+		return false, 0
+	}
+	endpoint.sawHeight(seen)
+	return seen >= height, 0
+}
+
+func (pool *Pool) callEndpoint(ctx context.Context, endpoint *rpcEndpoint, method string, fn func(ctx context.Context, client *ethclient.Client) error) error {
+	client, err := pool.dial(ctx, endpoint)
+	if err == nil {
+		call_ctx, cancel := context.WithTimeout(ctx, endpoint.config.Timeout.Duration())
+		err = fn(call_ctx, client)
+		cancel()
+	}
+
+	if err != nil && isEndpointError(ctx, err) {
+		pool.failed(endpoint, method, err)
+		return err
+	}
+	endpoint.lock.Lock()
+	endpoint.score += poolScoreWeight * (1 - endpoint.score)
+	endpoint.failures = 0
+	endpoint.lock.Unlock()
+	metrics.RPCRequests.WithLabelValues(pool.chainName, endpoint.name, "ok").Inc()
+	return err
+}
+
+func (pool *Pool) dial(ctx context.Context, endpoint *rpcEndpoint) (*ethclient.Client, error) {
+	endpoint.lock.Lock()
+	defer endpoint.lock.Unlock()
+	if endpoint.client != nil {
+		return endpoint.client, nil
+	}
+	dial_ctx, cancel := context.WithTimeout(ctx, endpoint.config.Timeout.Duration())
+	defer cancel()
+	client, err := ethclient.DialContext(dial_ctx, endpoint.config.URL)
+	if err != nil {
+		return nil, xerrors.Errorf("error when dialing %s: %w", endpoint.name, err)
+	}
+	endpoint.client = client
+	return client, nil
+}
+
+func (pool *Pool) failed(endpoint *rpcEndpoint, method string, err error) {
+	endpoint.lock.Lock()
+	endpoint.score -= poolScoreWeight * endpoint.score
+	endpoint.failures += 1
+	backoff := poolMaxBackoff
+	if endpoint.failures <= 6 {
+		backoff = time.Second << (endpoint.failures - 1)
+	}
+	endpoint.downUntil = time.Now().Add(backoff)
+	failures := endpoint.failures
+	endpoint.lock.Unlock()
+
+	metrics.RPCRequests.WithLabelValues(pool.chainName, endpoint.name, "error").Inc()
+	pool.log.WithFields(logrus.Fields{"endpoint": endpoint.name, "method": method, "failures": failures}).
+		Warnf("RPC endpoint failed, skipped for %s: %s", backoff, err.Error())
+}
+
+func (endpoint *rpcEndpoint) sawHeight(height uint64) {
+	endpoint.lock.Lock()
+	defer endpoint.lock.Unlock()
+	if height > endpoint.height {
+		endpoint.height = height
+	}
+}
+
+// isEndpointError tells if err is a fault of endpoint, so that another
+// one should be tried.
+func isEndpointError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || xerrors.Is(err, ethereum.NotFound) || xerrors.Is(err, rpc.ErrNotificationsUnsupported) {
+		return false
+	}
+	var rpc_err rpc.Error
+	if xerrors.As(err, &rpc_err) {
+		switch rpc_err.ErrorCode() {
+		case -32005, -32603: // Limit exceeded, internal error
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// isKnownTx tells if err rejects a transaction the node already has.
+// After a failover, nonce too low is counted as such.
+func isKnownTx(err error, failed_over bool) bool {
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, "already known") || strings.Contains(message, "known transaction") {
+		return true
+	}
+	return failed_over && strings.Contains(message, "nonce too low")
+}
+
+// checkHeads compares heads of all endpoints every interval, and marks
+// those lagging behind the highest one.
+func (pool *Pool) checkHeads() {
+	ticker := time.NewTicker(pool.poolConfig.Interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pool.stop:
+			return
+		case <-ticker.C:
+		}
+		pool.CheckHeads(context.Background())
+	}
+}
+
+// CheckHeads fetches head of every endpoint, and marks endpoints more
+// than max_lag_blocks behind the highest head as lagging.
+func (pool *Pool) CheckHeads(ctx context.Context) {
+	if len(pool.endpoints) < 2 {
+		return
+	}
+	heights := make([]uint64, len(pool.endpoints))
+	wait := sync.WaitGroup{}
+	for i, endpoint := range pool.endpoints {
+		wait.Add(1)
+		go func(i int, endpoint *rpcEndpoint) {
+			defer wait.Done()
+			if limited, err := pool.take(endpoint); limited > 0 || err != nil {
+				return
+			}
+			pool.callEndpoint(ctx, endpoint, "eth_blockNumber", func(ctx context.Context, client *ethclient.Client) (err error) {
+				heights[i], err = client.BlockNumber(ctx)
+				return err
+			})
+		}(i, endpoint)
+	}
+	wait.Wait()
+
+	highest := uint64(0)
+	for _, height := range heights {
+		if height > highest {
+			highest = height
+		}
+	}
+	for i, endpoint := range pool.endpoints {
+		if heights[i] == 0 {
+			continue // Failed, already backing off
+		}
+		endpoint.sawHeight(heights[i])
+		lagging := highest-heights[i] > pool.poolConfig.MaxLag()
+
+		endpoint.lock.Lock()
+		changed := lagging != endpoint.lagging
+		endpoint.lagging = lagging
+		endpoint.lock.Unlock()
+
+		metrics.RPCEndpointHead.WithLabelValues(pool.chainName, endpoint.name).Set(float64(heights[i]))
+		if changed && lagging {
+			pool.log.WithFields(logrus.Fields{"endpoint": endpoint.name, "height": heights[i], "highest": highest}).
+				Warnf("RPC endpoint lags behind others, avoided")
+		} else if changed {
+			pool.log.WithFields(logrus.Fields{"endpoint": endpoint.name, "height": heights[i]}).Infof("RPC endpoint caught up")
+		}
+	}
+}
+
+func (pool *Pool) BlockNumber(ctx context.Context) (result uint64, err error) {
+	err = pool.call(ctx, "eth_blockNumber", 0, func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.BlockNumber(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (pool *Pool) ChainID(ctx context.Context) (result *big.Int, err error) {
+	err = pool.call(ctx, "eth_chainId", 0, func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.ChainID(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (pool *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (result *types.Header, err error) {
+	err = pool.call(ctx, "eth_getBlockByNumber", heightOf(number), func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return result, err
+}
+
+// FilterLogs only asks endpoints having seen ToBlock: others return no
+// log instead of an error.
+func (pool *Pool) FilterLogs(ctx context.Context, query ethereum.FilterQuery) (result []types.Log, err error) {
+	err = pool.call(ctx, "eth_getLogs", heightOf(query.ToBlock), func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.FilterLogs(ctx, query)
+		return err
+	})
+	return result, err
+}
+
+func (pool *Pool) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (result ethereum.Subscription, err error) {
+	err = pool.subscribe(ctx, "eth_subscribe", func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.SubscribeFilterLogs(ctx, query, ch)
+		return err
+	})
+	return result, err
+}
+
+// SubscribeNewHead subscribes on the healthiest WebSocket endpoint.
+// Subscription ends with an error when that endpoint disconnects.
+func (pool *Pool) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (result ethereum.Subscription, err error) {
+	err = pool.subscribe(ctx, "eth_subscribe", func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.SubscribeNewHead(ctx, ch)
+		return err
+	})
+	return result, err
+}
+
+func (pool *Pool) CodeAt(ctx context.Context, contract common.Address, number *big.Int) (result []byte, err error) {
+	err = pool.call(ctx, "eth_getCode", heightOf(number), func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.CodeAt(ctx, contract, number)
+		return err
+	})
+	return result, err
+}
+
+func (pool *Pool) CallContract(ctx context.Context, call ethereum.CallMsg, number *big.Int) (result []byte, err error) {
+	err = pool.call(ctx, "eth_call", heightOf(number), func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.CallContract(ctx, call, number)
+		return err
+	})
+	return result, err
+}
+
+func (pool *Pool) PendingCodeAt(ctx context.Context, account common.Address) (result []byte, err error) {
+	err = pool.call(ctx, "eth_getCode", 0, func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.PendingCodeAt(ctx, account)
+		return err
+	})
+	return result, err
+}
+
+func (pool *Pool) PendingNonceAt(ctx context.Context, account common.Address) (result uint64, err error) {
+	err = pool.call(ctx, "eth_getTransactionCount", 0, func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.PendingNonceAt(ctx, account)
+		return err
+	})
+	return result, err
+}
+
+func (pool *Pool) SuggestGasPrice(ctx context.Context) (result *big.Int, err error) {
+	err = pool.call(ctx, "eth_gasPrice", 0, func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.SuggestGasPrice(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (pool *Pool) SuggestGasTipCap(ctx context.Context) (result *big.Int, err error) {
+	err = pool.call(ctx, "eth_maxPriorityFeePerGas", 0, func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.SuggestGasTipCap(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (pool *Pool) EstimateGas(ctx context.Context, call ethereum.CallMsg) (result uint64, err error) {
+	err = pool.call(ctx, "eth_estimateGas", 0, func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.EstimateGas(ctx, call)
+		return err
+	})
+	return result, err
+}
+
+// SendTransaction may reach more than one endpoint if one fails after
+// accepting it. A node already knowing tx has it, so that is success.
+// Once failed over, a nonce too low means an earlier endpoint may have
+// got tx mined: it is success too, TxManager then waits for the receipt.
+func (pool *Pool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	attempts := 0
+	return pool.call(ctx, "eth_sendRawTransaction", 0, func(ctx context.Context, client *ethclient.Client) error {
+		attempts += 1
+		err := client.SendTransaction(ctx, tx)
+		if err != nil && isKnownTx(err, attempts > 1) {
+			pool.log.WithField("tx", tx.Hash().Hex()).Infof("Transaction already sent: %s", err.Error())
+			return nil
+		}
+		return err
+	})
+}
+
+func (pool *Pool) TransactionReceipt(ctx context.Context, tx_hash common.Hash) (result *types.Receipt, err error) {
+	err = pool.call(ctx, "eth_getTransactionReceipt", 0, func(ctx context.Context, client *ethclient.Client) error {
+		result, err = client.TransactionReceipt(ctx, tx_hash)
+		return err
+	})
+	return result, err
+}
+
+// subscribe is call without request timeout: subscriptions live as long
+// as ctx. Endpoints without subscription support are skipped.
+func (pool *Pool) subscribe(ctx context.Context, method string, fn func(ctx context.Context, client *ethclient.Client) error) error {
+	var last_err error
+	for _, endpoint := range pool.candidates() {
+		client, err := pool.dial(ctx, endpoint)
+		if err == nil {
+			err = fn(ctx, client)
+		}
+		if err == nil {
+			return nil
+		}
+		if !xerrors.Is(err, rpc.ErrNotificationsUnsupported) {
+			pool.failed(endpoint, method, err)
+		}
+		last_err = err
+	}
+	return xerrors.Errorf("error when subscribing on chain %s: %w", pool.chainName, last_err)
+}
+
+// heightOf returns block number to wait for, or 0 for latest or
+// pending.
+func heightOf(number *big.Int) uint64 {
+	if number == nil || number.Sign() <= 0 {
+		return 0
+	}
+	return number.Uint64()
+}
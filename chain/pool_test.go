@@ -0,0 +1,261 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+// fakeNode is an `eth` JSON-RPC service at some head.
+type fakeNode struct {
+	height  uint64
+	delay   time.Duration
+	down    atomic.Bool
+	calls   atomic.Int64
+	sendErr string // Rejection of sent transactions, if any
+}
+
+func (node *fakeNode) BlockNumber() hexutil.Uint64 {
+	time.Sleep(node.delay)
+	return hexutil.Uint64(node.height)
+}
+
+func (node *fakeNode) ChainId() (*hexutil.Big, error) {
+	return nil, xerrors.New("not supported by this node")
+}
+
+func (node *fakeNode) GetLogs(query map[string]interface{}) []types.Log {
+	return []types.Log{{BlockNumber: node.height, Index: 1, Topics: []common.Hash{EventPublishHash}, Data: []byte{}}}
+}
+
+func (node *fakeNode) SendRawTransaction(raw hexutil.Bytes) (common.Hash, error) {
+	if node.sendErr != "" {
+		return common.Hash{}, xerrors.New(node.sendErr)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+func start_fake_node(t *testing.T, height uint64) (*fakeNode, config.RPCEndpoint) {
+	node := &fakeNode{height: height}
+	server := rpc.NewServer()
+	require.Nil(t, server.RegisterName("eth", node))
+	http_server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		node.calls.Add(1)
+		if node.down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(http_server.Close)
+	return node, config.RPCEndpoint{URL: http_server.URL, Timeout: config.Duration(time.Second)}
+}
+
+func new_test_pool(t *testing.T, endpoints ...config.RPCEndpoint) *Pool {
+	pool, err := NewPool("ethereum", endpoints, config.RPCPoolConfig{CheckInterval: config.Duration(time.Hour)})
+	require.Nil(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func Test_Pool(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fails over to next endpoint", func(t *testing.T) {
+		first, first_endpoint := start_fake_node(t, 100)
+		_, second_endpoint := start_fake_node(t, 100)
+		pool := new_test_pool(t, first_endpoint, second_endpoint)
+		first.down.Store(true)
+
+		height, err := pool.BlockNumber(ctx)
+		require.Nil(t, err)
+		assert.Equal(t, uint64(100), height)
+		status := pool.Status()
+		assert.True(t, status[0].Down)
+		assert.Less(t, status[0].Score, 1.0)
+		assert.False(t, status[1].Down)
+
+		// Skipped while backing off.
+		calls := first.calls.Load()
+		_, err = pool.BlockNumber(ctx)
+		require.Nil(t, err)
+		assert.Equal(t, calls, first.calls.Load())
+	})
+
+	t.Run("all endpoints down", func(t *testing.T) {
+		first, first_endpoint := start_fake_node(t, 100)
+		second, second_endpoint := start_fake_node(t, 100)
+		pool := new_test_pool(t, first_endpoint, second_endpoint)
+		first.down.Store(true)
+		second.down.Store(true)
+
+		_, err := pool.BlockNumber(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("request errors are answers", func(t *testing.T) {
+		_, first_endpoint := start_fake_node(t, 100)
+		second, second_endpoint := start_fake_node(t, 100)
+		pool := new_test_pool(t, first_endpoint, second_endpoint)
+
+		_, err := pool.ChainID(ctx)
+		assert.Contains(t, err.Error(), "not supported by this node")
+		assert.Equal(t, int64(0), second.calls.Load())
+		assert.False(t, pool.Status()[0].Down)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		slow, slow_endpoint := start_fake_node(t, 100)
+		slow.delay = 200 * time.Millisecond
+		slow_endpoint.Timeout = config.Duration(20 * time.Millisecond)
+		_, fast_endpoint := start_fake_node(t, 101)
+		pool := new_test_pool(t, slow_endpoint, fast_endpoint)
+
+		height, err := pool.BlockNumber(ctx)
+		require.Nil(t, err)
+		assert.Equal(t, uint64(101), height)
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		_, limited_endpoint := start_fake_node(t, 100)
+		limited_endpoint.RateLimit = &config.RateLimitRule{Requests: 1, PeriodSeconds: 3600}
+		_, other_endpoint := start_fake_node(t, 101)
+		pool := new_test_pool(t, limited_endpoint, other_endpoint)
+
+		height, err := pool.BlockNumber(ctx)
+		require.Nil(t, err)
+		assert.Equal(t, uint64(100), height)
+		height, err = pool.BlockNumber(ctx)
+		require.Nil(t, err)
+		assert.Equal(t, uint64(101), height)
+	})
+
+	t.Run("waits when every endpoint is rate limited", func(t *testing.T) {
+		_, endpoint := start_fake_node(t, 100)
+		endpoint.RateLimit = &config.RateLimitRule{Requests: 1, PeriodSeconds: 3600}
+		pool := new_test_pool(t, endpoint)
+		_, err := pool.BlockNumber(ctx)
+		require.Nil(t, err)
+
+		timeout_ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		_, err = pool.BlockNumber(timeout_ctx)
+		assert.True(t, xerrors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("lagging endpoint", func(t *testing.T) {
+		_, behind_endpoint := start_fake_node(t, 100)
+		_, ahead_endpoint := start_fake_node(t, 200)
+		pool := new_test_pool(t, behind_endpoint, ahead_endpoint)
+
+		pool.CheckHeads(ctx)
+		status := pool.Status()
+		assert.True(t, status[0].Lagging)
+		assert.False(t, status[1].Lagging)
+		height, err := pool.BlockNumber(ctx)
+		require.Nil(t, err)
+		assert.Equal(t, uint64(200), height)
+	})
+
+	t.Run("transaction known after failover is sent", func(t *testing.T) {
+		tx := types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000, GasPrice: big.NewInt(1)})
+		for _, rejection := range []string{"already known", "nonce too low"} {
+			first, first_endpoint := start_fake_node(t, 100)
+			second, second_endpoint := start_fake_node(t, 100)
+			second.sendErr = rejection
+			pool := new_test_pool(t, first_endpoint, second_endpoint)
+			first.down.Store(true) // May have accepted tx before failing
+
+			assert.Nil(t, pool.SendTransaction(ctx, tx), rejection)
+			assert.Equal(t, int64(1), second.calls.Load())
+		}
+
+		node, endpoint := start_fake_node(t, 100)
+		pool := new_test_pool(t, endpoint)
+		assert.Nil(t, pool.SendTransaction(ctx, tx))
+		node.sendErr = "already known"
+		assert.Nil(t, pool.SendTransaction(ctx, tx))
+		node.sendErr = "nonce too low"
+		assert.ErrorContains(t, pool.SendTransaction(ctx, tx), "nonce too low", "not sent by this pool")
+	})
+
+	t.Run("logs only from endpoints having the block", func(t *testing.T) {
+		_, behind_endpoint := start_fake_node(t, 100)
+		_, ahead_endpoint := start_fake_node(t, 103)
+		pool := new_test_pool(t, behind_endpoint, ahead_endpoint)
+
+		logs, err := pool.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(102), ToBlock: big.NewInt(102)})
+		require.Nil(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, uint64(103), logs[0].BlockNumber)
+
+		_, err = pool.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(104), ToBlock: big.NewInt(104)})
+		assert.True(t, xerrors.Is(err, ethereum.NotFound))
+	})
+
+	t.Run("head asked for block counts against rate limit", func(t *testing.T) {
+		behind, behind_endpoint := start_fake_node(t, 100)
+		behind_endpoint.RateLimit = &config.RateLimitRule{Requests: 1, PeriodSeconds: 3600}
+		_, ahead_endpoint := start_fake_node(t, 103)
+		pool := new_test_pool(t, behind_endpoint, ahead_endpoint)
+
+		logs, err := pool.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(102), ToBlock: big.NewInt(102)})
+		require.Nil(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, uint64(103), logs[0].BlockNumber)
+		assert.Equal(t, int64(0), behind.calls.Load())
+	})
+}
+
+func Test_PoolOf(t *testing.T) {
+	c := &config.Config{Chain: map[string]*config.ChainConfig{
+		"ethereum": {RPCUrl: "https://a.example.com/v3/secret", RPCUrls: []config.RPCEndpoint{{URL: "https://b.example.com"}}},
+	}}
+	config.Set(c)
+	defer config.Set(nil)
+
+	pool, err := PoolOf("ethereum")
+	require.Nil(t, err)
+	same, err := PoolOf("ethereum")
+	require.Nil(t, err)
+	assert.Same(t, pool, same)
+	assert.Equal(t, "a.example.com", pool.Status()[0].Name)
+
+	config.Set(&config.Config{Chain: map[string]*config.ChainConfig{
+		"ethereum": {RPCUrls: []config.RPCEndpoint{{URL: "https://b.example.com"}}},
+	}})
+	replaced, err := PoolOf("ethereum")
+	require.Nil(t, err)
+	assert.NotSame(t, pool, replaced)
+	assert.Len(t, replaced.Status(), 1)
+
+	_, err = PoolOf("etereum")
+	assert.True(t, xerrors.Is(err, ErrChainNotConfigured))
+
+	// Pools of chains removed or disabled by a reload are dropped.
+	prunePools(&config.Config{Chain: map[string]*config.ChainConfig{
+		"ethereum": {RPCUrls: []config.RPCEndpoint{{URL: "https://b.example.com"}}},
+	}})
+	poolsLock.Lock()
+	_, kept := pools["ethereum"]
+	poolsLock.Unlock()
+	assert.False(t, kept)
+}
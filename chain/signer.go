@@ -0,0 +1,203 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/xerrors"
+)
+
+// Signer signs transactions of the operator account.
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chain_id *big.Int) (*types.Transaction, error)
+}
+
+// NewSigner creates Signer of operator account of given chain, as
+// configured in `operator` of chain config.
+func NewSigner(ctx context.Context, chainName string) (Signer, error) {
+	chainConfig, ok := config.Get().Chain[chainName]
+	if !ok || chainConfig == nil {
+		return nil, xerrors.Errorf("%w: %s", ErrChainNotConfigured, chainName)
+	}
+
+	operator := chainConfig.Operator
+	switch operator.Signer {
+	case "", config.SignerKey:
+		return NewKeySigner(chainConfig.OperatorAccountPrivateKey)
+	case config.SignerKeystore:
+		return NewKeystoreSigner(operator.KeystorePath, operator.KeystorePassphrase)
+	case config.SignerExternal:
+		return NewExternalSigner(ctx, operator.ExternalURL, operator.Address)
+	default:
+		return nil, xerrors.Errorf("unknown operator signer %q", operator.Signer)
+	}
+}
+
+// KeySigner signs with a private key in memory.
+type KeySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewKeySigner parses a hex private key, with or without 0x.
+func NewKeySigner(private_hex string) (*KeySigner, error) {
+	if private_hex == "" {
+		return nil, xerrors.New("operator private key not configured")
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(private_hex, "0x"))
+	if err != nil {
+		return nil, xerrors.Errorf("error when parsing operator private key: %w", err)
+	}
+	return &KeySigner{key: key}, nil
+}
+
+func (signer *KeySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(signer.key.PublicKey)
+}
+
+func (signer *KeySigner) SignTx(_ context.Context, tx *types.Transaction, chain_id *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chain_id), signer.key)
+}
+
+// NewKeystoreSigner decrypts a go-ethereum keystore (UTC--...) file.
+func NewKeystoreSigner(path, passphrase string) (*KeySigner, error) {
+	if path == "" {
+		return nil, xerrors.New("operator keystore_path not configured")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("error when reading keystore: %w", err)
+	}
+	key, err := keystore.DecryptKey(content, passphrase)
+	if err != nil {
+		return nil, xerrors.Errorf("error when decrypting keystore %s: %w", path, err)
+	}
+	return &KeySigner{key: key.PrivateKey}, nil
+}
+
+// ExternalSigner asks a JSON-RPC signer (Clef, Web3Signer...) to sign
+// with `eth_signTransaction`. Keys never leave the signer.
+type ExternalSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// NewExternalSigner connects to signer at url. Account defaults to the
+// first one of `eth_accounts` if address is empty.
+func NewExternalSigner(ctx context.Context, url, address string) (*ExternalSigner, error) {
+	if url == "" {
+		return nil, xerrors.New("operator external_url not configured")
+	}
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, xerrors.Errorf("error when dialing signer: %w", err)
+	}
+	signer := &ExternalSigner{client: client}
+	if address != "" {
+		signer.address = common.HexToAddress(address)
+		return signer, nil
+	}
+
+	accounts := []common.Address{}
+	if err := client.CallContext(ctx, &accounts, "eth_accounts"); err != nil {
+		return nil, xerrors.Errorf("error when listing signer accounts: %w", err)
+	}
+	if len(accounts) == 0 {
+		return nil, xerrors.New("signer has no account")
+	}
+	signer.address = accounts[0]
+	return signer, nil
+}
+
+func (signer *ExternalSigner) Address() common.Address {
+	return signer.address
+}
+
+// SendTxArgs is the transaction object of `eth_signTransaction`.
+type SendTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                hexutil.Big     `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId,omitempty"`
+}
+
+// signTxResult is the result of `eth_signTransaction`: Clef and Geth
+// return an object, others only the raw transaction.
+type signTxResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+func (result *signTxResult) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return result.Raw.UnmarshalJSON(data)
+	}
+	type plain signTxResult
+	return json.Unmarshal(data, (*plain)(result))
+}
+
+func (signer *ExternalSigner) SignTx(ctx context.Context, tx *types.Transaction, chain_id *big.Int) (*types.Transaction, error) {
+	args := SendTxArgs{
+		From:    signer.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   hexutil.Big(*tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chain_id),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+
+	result := signTxResult{}
+	if err := signer.client.CallContext(ctx, &result, "eth_signTransaction", args); err != nil {
+		return nil, xerrors.Errorf("error when signing with external signer: %w", err)
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(result.Raw); err != nil {
+		return nil, xerrors.Errorf("error when decoding signed transaction: %w", err)
+	}
+
+	// Never trust signer to sign what was asked.
+	sender, err := types.Sender(types.LatestSignerForChainID(chain_id), signed)
+	if err != nil {
+		return nil, xerrors.Errorf("error when recovering signer: %w", err)
+	}
+	if sender != signer.address || !sameTx(tx, signed, chain_id) {
+		return nil, xerrors.New("external signer returned a different transaction")
+	}
+	return signed, nil
+}
+
+// sameTx tells if signed b sends the same call as a on chain_id, at the
+// same fees, ignoring signature.
+func sameTx(a, b *types.Transaction, chain_id *big.Int) bool {
+	if (a.To() == nil) != (b.To() == nil) || (a.To() != nil && *a.To() != *b.To()) {
+		return false
+	}
+	return a.Type() == b.Type() && b.ChainId().Cmp(chain_id) == 0 &&
+		a.Nonce() == b.Nonce() && a.Gas() == b.Gas() &&
+		a.GasFeeCap().Cmp(b.GasFeeCap()) == 0 && a.GasTipCap().Cmp(b.GasTipCap()) == 0 &&
+		a.Value().Cmp(b.Value()) == 0 && bytes.Equal(a.Data(), b.Data())
+}
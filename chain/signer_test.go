@@ -0,0 +1,179 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPrivateKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+)
+
+var (
+	testChainID = big.NewInt(1337)
+	testTo      = common.HexToAddress("0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F")
+)
+
+func test_tx() *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   testChainID,
+		Nonce:     7,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(100),
+		Gas:       21000,
+		To:        &testTo,
+		Value:     big.NewInt(42),
+		Data:      []byte{0x01, 0x02},
+	})
+}
+
+func assert_signed_by(t *testing.T, signed *types.Transaction, address common.Address) {
+	sender, err := types.Sender(types.LatestSignerForChainID(testChainID), signed)
+	require.Nil(t, err)
+	assert.Equal(t, address, sender)
+}
+
+func Test_KeySigner(t *testing.T) {
+	signer, err := NewKeySigner("0x" + testPrivateKey)
+	require.Nil(t, err)
+	signed, err := signer.SignTx(context.Background(), test_tx(), testChainID)
+	require.Nil(t, err)
+	assert_signed_by(t, signed, signer.Address())
+
+	_, err = NewKeySigner("")
+	assert.NotNil(t, err)
+	_, err = NewKeySigner("FFFF")
+	assert.NotNil(t, err)
+}
+
+func Test_KeystoreSigner(t *testing.T) {
+	private_key, err := crypto.HexToECDSA(testPrivateKey)
+	require.Nil(t, err)
+	key := &keystore.Key{
+		Address:    crypto.PubkeyToAddress(private_key.PublicKey),
+		PrivateKey: private_key,
+	}
+	content, err := keystore.EncryptKey(key, "passphrase", keystore.LightScryptN, keystore.LightScryptP)
+	require.Nil(t, err)
+	path := filepath.Join(t.TempDir(), "operator.json")
+	require.Nil(t, os.WriteFile(path, content, 0600))
+
+	signer, err := NewKeystoreSigner(path, "passphrase")
+	require.Nil(t, err)
+	assert.Equal(t, key.Address, signer.Address())
+	signed, err := signer.SignTx(context.Background(), test_tx(), testChainID)
+	require.Nil(t, err)
+	assert_signed_by(t, signed, key.Address)
+
+	_, err = NewKeystoreSigner(path, "wrong")
+	assert.NotNil(t, err)
+}
+
+// fakeClef signs every transaction with its key, or what tamper makes
+// of it.
+type fakeClef struct {
+	key    *KeySigner
+	tamper func(tx *types.DynamicFeeTx) types.TxData
+}
+
+func (clef *fakeClef) Accounts() []common.Address {
+	return []common.Address{clef.key.Address()}
+}
+
+func (clef *fakeClef) SignTransaction(args SendTxArgs) (map[string]interface{}, error) {
+	var data types.TxData = &types.DynamicFeeTx{
+		ChainID:   (*big.Int)(args.ChainID),
+		Nonce:     uint64(args.Nonce),
+		GasTipCap: (*big.Int)(args.MaxPriorityFeePerGas),
+		GasFeeCap: (*big.Int)(args.MaxFeePerGas),
+		Gas:       uint64(args.Gas),
+		To:        args.To,
+		Value:     (*big.Int)(&args.Value),
+		Data:      args.Data,
+	}
+	if clef.tamper != nil {
+		data = clef.tamper(data.(*types.DynamicFeeTx))
+	}
+	tx := types.NewTx(data)
+	chain_id := tx.ChainId()
+	if tx.Type() == types.LegacyTxType {
+		chain_id = (*big.Int)(args.ChainID)
+	}
+	signed, err := clef.key.SignTx(context.Background(), tx, chain_id)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": signed}, nil
+}
+
+func Test_ExternalSigner(t *testing.T) {
+	key, err := NewKeySigner(testPrivateKey)
+	require.Nil(t, err)
+	clef := &fakeClef{key: key}
+	server := rpc.NewServer()
+	require.Nil(t, server.RegisterName("eth", clef))
+	http_server := httptest.NewServer(server)
+	defer http_server.Close()
+
+	signer, err := NewExternalSigner(context.Background(), http_server.URL, "")
+	require.Nil(t, err)
+	assert.Equal(t, key.Address(), signer.Address())
+
+	signed, err := signer.SignTx(context.Background(), test_tx(), testChainID)
+	require.Nil(t, err)
+	assert_signed_by(t, signed, key.Address())
+	assert.Equal(t, big.NewInt(42), signed.Value())
+
+	t.Run("tampered", func(t *testing.T) {
+		defer func() { clef.tamper = nil }()
+		for name, tamper := range map[string]func(tx *types.DynamicFeeTx) types.TxData{
+			"value":    func(tx *types.DynamicFeeTx) types.TxData { tx.Value = big.NewInt(0); return tx },
+			"fee cap":  func(tx *types.DynamicFeeTx) types.TxData { tx.GasFeeCap = big.NewInt(1000); return tx },
+			"tip cap":  func(tx *types.DynamicFeeTx) types.TxData { tx.GasTipCap = big.NewInt(99); return tx },
+			"chain id": func(tx *types.DynamicFeeTx) types.TxData { tx.ChainID = big.NewInt(1); return tx },
+			"type": func(tx *types.DynamicFeeTx) types.TxData {
+				return &types.LegacyTx{Nonce: tx.Nonce, GasPrice: tx.GasFeeCap, Gas: tx.Gas, To: tx.To, Value: tx.Value, Data: tx.Data}
+			},
+		} {
+			clef.tamper = tamper
+			_, err := signer.SignTx(context.Background(), test_tx(), testChainID)
+			assert.NotNil(t, err, name)
+		}
+
+		// Legacy transaction at the same fees as asked is still another one.
+		legacy, err := key.SignTx(context.Background(), types.NewTx(&types.LegacyTx{
+			Nonce: 7, GasPrice: big.NewInt(100), Gas: 21000, To: &testTo, Value: big.NewInt(42), Data: []byte{0x01, 0x02},
+		}), testChainID)
+		require.Nil(t, err)
+		asked := types.NewTx(&types.DynamicFeeTx{
+			ChainID: testChainID, Nonce: 7, GasTipCap: big.NewInt(100), GasFeeCap: big.NewInt(100),
+			Gas: 21000, To: &testTo, Value: big.NewInt(42), Data: []byte{0x01, 0x02},
+		})
+		assert.False(t, sameTx(asked, legacy, testChainID))
+		assert.True(t, sameTx(asked, asked, testChainID))
+	})
+
+	t.Run("other account", func(t *testing.T) {
+		other, err := NewExternalSigner(context.Background(), http_server.URL, "0x0000000000000000000000000000000000000001")
+		require.Nil(t, err)
+		_, err = other.SignTx(context.Background(), test_tx(), testChainID)
+		assert.NotNil(t, err)
+	})
+}
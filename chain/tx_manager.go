@@ -0,0 +1,352 @@
+package chain
+
+import (
+	"context"
+	"flag"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+// TxBackend is the part of an Ethereum client used by TxManager.
+// *ethclient.Client implements it.
+type TxBackend interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, block_number *big.Int) ([]byte, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, tx_hash common.Hash) (*types.Receipt, error)
+}
+
+// TxOptions tunes TxManager. Zero values fall back to defaults.
+type TxOptions struct {
+	GasMarginPercent uint64        // Added to estimated gas. Defaults to 20
+	MaxFeeCap        *big.Int      // Upper bound of maxFeePerGas in wei. No bound if nil
+	ReceiptTimeout   time.Duration // Defaults to 5 minutes
+	StuckAfter       time.Duration // Fees are bumped if not mined after this. Defaults to 1 minute
+	FeeBumpPercent   uint64        // Nodes reject replacements bumping less than 10%. Defaults to 15
+	PollInterval     time.Duration // Defaults to 3 seconds
+}
+
+// TxOptionsFlags registers -timeout and -max-fee (in gwei) on fs for
+// commands sending transactions. The returned function builds TxOptions
+// from them once fs is parsed.
+func TxOptionsFlags(fs *flag.FlagSet) func() TxOptions {
+	timeout := fs.Duration("timeout", 5*time.Minute, "Max time waiting for transaction to be mined")
+	max_fee_gwei := fs.Int64("max-fee", 0, "Max fee per gas in gwei. 0 for no limit")
+	return func() TxOptions {
+		options := TxOptions{ReceiptTimeout: *timeout}
+		if *max_fee_gwei > 0 {
+			options.MaxFeeCap = new(big.Int).Mul(big.NewInt(*max_fee_gwei), big.NewInt(params.GWei))
+		}
+		return options
+	}
+}
+
+func (options TxOptions) withDefaults() TxOptions {
+	if options.GasMarginPercent == 0 {
+		options.GasMarginPercent = 20
+	}
+	if options.ReceiptTimeout == 0 {
+		options.ReceiptTimeout = 5 * time.Minute
+	}
+	if options.StuckAfter == 0 {
+		options.StuckAfter = time.Minute
+	}
+	if options.FeeBumpPercent < 10 {
+		options.FeeBumpPercent = 15
+	}
+	if options.PollInterval == 0 {
+		options.PollInterval = 3 * time.Second
+	}
+	return options
+}
+
+// TxManager sends EIP-1559 transactions of one signer and follows
+// them until mined. Nonces are tracked locally, so transactions can be
+// sent one after another without waiting for the node to see them.
+type TxManager struct {
+	backend  TxBackend
+	signer   Signer
+	chain_id *big.Int
+	options  TxOptions
+
+	nonceLock sync.Mutex
+	nonce     *uint64 // Next nonce. Fetched from node if nil
+}
+
+// NewTxManager creates TxManager sending as signer through backend.
+func NewTxManager(ctx context.Context, backend TxBackend, signer Signer, options TxOptions) (*TxManager, error) {
+	chain_id, err := backend.ChainID(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("error when fetching chain ID: %w", err)
+	}
+	return &TxManager{
+		backend:  backend,
+		signer:   signer,
+		chain_id: chain_id,
+		options:  options.withDefaults(),
+	}, nil
+}
+
+// From returns address transactions are sent from.
+func (m *TxManager) From() common.Address {
+	return m.signer.Address()
+}
+
+// Transact sends the transaction built by call, a method of an abigen
+// contract binding, with value attached, and waits for its receipt.
+func (m *TxManager) Transact(ctx context.Context, value *big.Int, call func(opts *bind.TransactOpts) (*types.Transaction, error)) (*types.Receipt, error) {
+	// Let binding only pack the call: price, gas and nonce given here
+	// are placeholders, and nothing is signed or sent.
+	opts := &bind.TransactOpts{
+		From:     m.From(),
+		Nonce:    big.NewInt(0),
+		Value:    value,
+		GasPrice: big.NewInt(1),
+		GasLimit: 1,
+		NoSend:   true,
+		Context:  ctx,
+		Signer: func(_ common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			return tx, nil
+		},
+	}
+	unsigned, err := call(opts)
+	if err != nil {
+		return nil, xerrors.Errorf("error when packing transaction: %w", err)
+	}
+
+	msg := ethereum.CallMsg{From: m.From(), To: unsigned.To(), Value: unsigned.Value(), Data: unsigned.Data()}
+	tx, err := m.Send(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	return m.Wait(ctx, tx, msg)
+}
+
+// Send estimates gas and fees of msg, then signs and sends it with the
+// next nonce.
+func (m *TxManager) Send(ctx context.Context, msg ethereum.CallMsg) (*types.Transaction, error) {
+	msg.From = m.From()
+	estimated, err := m.backend.EstimateGas(ctx, msg)
+	if err != nil {
+		return nil, revertError(err, "error when estimating gas")
+	}
+	gas := estimated + estimated*m.options.GasMarginPercent/100
+
+	tip, fee_cap, err := m.fees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.nonceLock.Lock()
+	defer m.nonceLock.Unlock()
+	if m.nonce == nil {
+		nonce, err := m.backend.PendingNonceAt(ctx, m.From())
+		if err != nil {
+			return nil, xerrors.Errorf("error when fetching nonce: %w", err)
+		}
+		m.nonce = &nonce
+	}
+
+	tx, err := m.signAndSend(ctx, &types.DynamicFeeTx{
+		ChainID:   m.chain_id,
+		Nonce:     *m.nonce,
+		GasTipCap: tip,
+		GasFeeCap: fee_cap,
+		Gas:       gas,
+		To:        msg.To,
+		Value:     msg.Value,
+		Data:      msg.Data,
+	})
+	if err != nil {
+		// Node knows better: resync nonce before next transaction.
+		m.nonce = nil
+		return nil, err
+	}
+	*m.nonce += 1
+	return tx, nil
+}
+
+// fees returns tip and fee cap: twice the latest base fee plus tip
+// covers base fee rising for several full blocks.
+func (m *TxManager) fees(ctx context.Context) (tip, fee_cap *big.Int, err error) {
+	tip, err = m.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("error when suggesting gas tip: %w", err)
+	}
+	head, err := m.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("error when fetching latest header: %w", err)
+	}
+	if head.BaseFee == nil {
+		return nil, nil, xerrors.New("chain does not support EIP-1559")
+	}
+	fee_cap = new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+
+	// Capped fee cap may be below base fee: transaction then waits
+	// until base fee drops.
+	if max := m.options.MaxFeeCap; max != nil && fee_cap.Cmp(max) > 0 {
+		fee_cap = new(big.Int).Set(max)
+		if tip.Cmp(max) > 0 {
+			tip = new(big.Int).Set(max)
+		}
+	}
+	return tip, fee_cap, nil
+}
+
+func (m *TxManager) signAndSend(ctx context.Context, unsigned *types.DynamicFeeTx) (*types.Transaction, error) {
+	tx, err := m.signer.SignTx(ctx, types.NewTx(unsigned), m.chain_id)
+	if err != nil {
+		return nil, xerrors.Errorf("error when signing transaction: %w", err)
+	}
+	if err := m.backend.SendTransaction(ctx, tx); err != nil {
+		return nil, xerrors.Errorf("error when sending transaction: %w", err)
+	}
+	logrus.WithFields(logrus.Fields{"module": "tx_manager", "tx": tx.Hash().Hex(), "nonce": tx.Nonce()}).
+		Infof("Transaction sent. Tip: %s, fee cap: %s, gas: %d", tx.GasTipCap(), tx.GasFeeCap(), tx.Gas())
+	return tx, nil
+}
+
+// SpeedUp replaces tx by the same transaction with bumped fees, so that
+// it is mined sooner. Fees are at least those needed now.
+func (m *TxManager) SpeedUp(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	bump := func(value *big.Int) *big.Int {
+		bumped := new(big.Int).Mul(value, big.NewInt(int64(100+m.options.FeeBumpPercent)))
+		return bumped.Div(bumped, big.NewInt(100))
+	}
+	tip, fee_cap := bump(tx.GasTipCap()), bump(tx.GasFeeCap())
+	if current_tip, current_fee_cap, err := m.fees(ctx); err == nil {
+		if current_tip.Cmp(tip) > 0 {
+			tip = current_tip
+		}
+		if current_fee_cap.Cmp(fee_cap) > 0 {
+			fee_cap = current_fee_cap
+		}
+	}
+	if fee_cap.Cmp(tip) < 0 {
+		fee_cap = new(big.Int).Set(tip)
+	}
+	if max := m.options.MaxFeeCap; max != nil && fee_cap.Cmp(max) > 0 {
+		return nil, xerrors.Errorf("%w: speeding up needs fee cap %s above %s", ErrTxFeeTooHigh, fee_cap, max)
+	}
+
+	return m.signAndSend(ctx, &types.DynamicFeeTx{
+		ChainID:   m.chain_id,
+		Nonce:     tx.Nonce(),
+		GasTipCap: tip,
+		GasFeeCap: fee_cap,
+		Gas:       tx.Gas(),
+		To:        tx.To(),
+		Value:     tx.Value(),
+		Data:      tx.Data(),
+	})
+}
+
+// Wait polls receipt of tx, speeding it up every StuckAfter, until one
+// of its versions is mined or ReceiptTimeout passes. A failed
+// transaction returns ErrTxReverted with the reason of msg replayed in
+// its block.
+func (m *TxManager) Wait(ctx context.Context, tx *types.Transaction, msg ethereum.CallMsg) (*types.Receipt, error) {
+	l := logrus.WithFields(logrus.Fields{"module": "tx_manager", "nonce": tx.Nonce()})
+	ctx, cancel := context.WithTimeout(ctx, m.options.ReceiptTimeout)
+	defer cancel()
+
+	sent := []*types.Transaction{tx}
+	latest_sent_at := time.Now()
+	for {
+		for _, candidate := range sent {
+			receipt, err := m.backend.TransactionReceipt(ctx, candidate.Hash())
+			if err == nil {
+				return m.checkReceipt(ctx, receipt, msg)
+			}
+			if !xerrors.Is(err, ethereum.NotFound) && ctx.Err() == nil {
+				l.Warnf("error when fetching receipt of %s: %s", candidate.Hash().Hex(), err.Error())
+			}
+		}
+
+		if time.Since(latest_sent_at) >= m.options.StuckAfter {
+			latest := sent[len(sent)-1]
+			replacement, err := m.SpeedUp(ctx, latest)
+			switch {
+			case err == nil:
+				l.Infof("Transaction %s stuck. Replaced by %s", latest.Hash().Hex(), replacement.Hash().Hex())
+				sent = append(sent, replacement)
+			case strings.Contains(err.Error(), "nonce too low"):
+				// One of the sent versions is mined: its receipt comes next round.
+				l.Debugf("Nonce used. Waiting for receipt.")
+			default:
+				l.Warnf("error when speeding up %s: %s", latest.Hash().Hex(), err.Error())
+			}
+			latest_sent_at = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, xerrors.Errorf("%w: %s", ErrTxTimeout, sent[len(sent)-1].Hash().Hex())
+		case <-time.After(m.options.PollInterval):
+		}
+	}
+}
+
+func (m *TxManager) checkReceipt(ctx context.Context, receipt *types.Receipt, msg ethereum.CallMsg) (*types.Receipt, error) {
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		return receipt, nil
+	}
+	// Replay in the block to learn why. Reason may be lost if state
+	// changed within the block.
+	_, err := m.backend.CallContract(ctx, msg, receipt.BlockNumber)
+	if err == nil {
+		return receipt, xerrors.Errorf("%w: %s", ErrTxReverted, receipt.TxHash.Hex())
+	}
+	return receipt, revertError(err, receipt.TxHash.Hex())
+}
+
+// revertError wraps err in ErrTxReverted with decoded revert reason if
+// err is a revert.
+func revertError(err error, action string) error {
+	reason, ok := RevertReason(err)
+	if !ok {
+		return xerrors.Errorf("%s: %w", action, err)
+	}
+	return xerrors.Errorf("%w: %s: %s", ErrTxReverted, action, reason)
+}
+
+// RevertReason decodes reason of a reverted call from err returned by
+// node. ok is false if err is not a revert.
+func RevertReason(err error) (reason string, ok bool) {
+	const reverted = "execution reverted"
+	if err == nil || !strings.Contains(err.Error(), reverted) {
+		return "", false
+	}
+
+	var data_err rpc.DataError
+	if xerrors.As(err, &data_err) {
+		if data, is_string := data_err.ErrorData().(string); is_string {
+			if raw, decode_err := hexutil.Decode(data); decode_err == nil {
+				if unpacked, unpack_err := abi.UnpackRevert(raw); unpack_err == nil {
+					return unpacked, true
+				}
+			}
+		}
+	}
+	// Some nodes only give reason in message.
+	message := err.Error()
+	message = message[strings.Index(message, reverted)+len(reverted):]
+	return strings.TrimPrefix(message, ": "), true
+}
@@ -0,0 +1,216 @@
+package chain
+
+import (
+	"context"
+	"flag"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+// revertDataError is an RPC error carrying revert data, like ethclient
+// returns.
+type revertDataError struct {
+	data string
+}
+
+func (err revertDataError) Error() string          { return "execution reverted" }
+func (err revertDataError) ErrorData() interface{} { return err.data }
+
+// Error(string) "not enough value"
+const revertNotEnoughValue = "0x08c379a0" +
+	"0000000000000000000000000000000000000000000000000000000000000020" +
+	"0000000000000000000000000000000000000000000000000000000000000010" +
+	"6e6f7420656e6f7567682076616c756500000000000000000000000000000000"
+
+// fakeBackend mines a transaction once its tip reaches mineTip.
+type fakeBackend struct {
+	lock     sync.Mutex
+	nonce    uint64
+	mineTip  *big.Int
+	failCall bool
+	sent     []*types.Transaction
+	receipts map[common.Hash]*types.Receipt
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{nonce: 5, mineTip: big.NewInt(0), receipts: map[common.Hash]*types.Receipt{}}
+}
+
+func (b *fakeBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return testChainID, nil
+}
+
+func (b *fakeBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100), BaseFee: big.NewInt(50)}, nil
+}
+
+func (b *fakeBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return b.nonce, nil
+}
+
+func (b *fakeBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(10), nil
+}
+
+func (b *fakeBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	if b.failCall {
+		return 0, revertDataError{data: revertNotEnoughValue}
+	}
+	return 100000, nil
+}
+
+func (b *fakeBackend) CallContract(ctx context.Context, call ethereum.CallMsg, block_number *big.Int) ([]byte, error) {
+	return nil, revertDataError{data: revertNotEnoughValue}
+}
+
+func (b *fakeBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.sent = append(b.sent, tx)
+	if tx.GasTipCap().Cmp(b.mineTip) >= 0 {
+		b.receipts[tx.Hash()] = &types.Receipt{
+			Status:      types.ReceiptStatusSuccessful,
+			TxHash:      tx.Hash(),
+			BlockNumber: big.NewInt(101),
+		}
+	}
+	return nil
+}
+
+func (b *fakeBackend) TransactionReceipt(ctx context.Context, tx_hash common.Hash) (*types.Receipt, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if receipt, ok := b.receipts[tx_hash]; ok {
+		return receipt, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func new_test_tx_manager(t *testing.T, backend *fakeBackend, options TxOptions) *TxManager {
+	signer, err := NewKeySigner(testPrivateKey)
+	require.Nil(t, err)
+	options.PollInterval = time.Millisecond
+	manager, err := NewTxManager(context.Background(), backend, signer, options)
+	require.Nil(t, err)
+	return manager
+}
+
+func test_call(opts *bind.TransactOpts) (*types.Transaction, error) {
+	signed, err := opts.Signer(opts.From, types.NewTx(&types.LegacyTx{
+		Nonce: opts.Nonce.Uint64(), GasPrice: opts.GasPrice, Gas: opts.GasLimit, To: &testTo, Value: opts.Value, Data: []byte{0xaa},
+	}))
+	return signed, err
+}
+
+func Test_TxManager_Transact(t *testing.T) {
+	t.Run("fees, gas and nonces", func(t *testing.T) {
+		backend := newFakeBackend()
+		manager := new_test_tx_manager(t, backend, TxOptions{})
+
+		for i := 0; i < 2; i++ {
+			receipt, err := manager.Transact(context.Background(), big.NewInt(42), test_call)
+			require.Nil(t, err)
+			assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+		}
+
+		require.Len(t, backend.sent, 2)
+		tx := backend.sent[0]
+		assert.Equal(t, uint8(types.DynamicFeeTxType), tx.Type())
+		assert.Equal(t, uint64(120000), tx.Gas())
+		assert.Equal(t, big.NewInt(10), tx.GasTipCap())
+		assert.Equal(t, big.NewInt(110), tx.GasFeeCap())
+		assert.Equal(t, big.NewInt(42), tx.Value())
+		assert.Equal(t, hexutil.Bytes{0xaa}, hexutil.Bytes(tx.Data()))
+		assert.Equal(t, uint64(5), tx.Nonce())
+		assert.Equal(t, uint64(6), backend.sent[1].Nonce())
+		assert_signed_by(t, tx, manager.From())
+	})
+
+	t.Run("fee cap limit", func(t *testing.T) {
+		backend := newFakeBackend()
+		manager := new_test_tx_manager(t, backend, TxOptions{MaxFeeCap: big.NewInt(80)})
+		_, err := manager.Transact(context.Background(), nil, test_call)
+		require.Nil(t, err)
+		assert.Equal(t, big.NewInt(80), backend.sent[0].GasFeeCap())
+	})
+
+	t.Run("speeds up stuck transaction", func(t *testing.T) {
+		backend := newFakeBackend()
+		backend.mineTip = big.NewInt(11)
+		manager := new_test_tx_manager(t, backend, TxOptions{StuckAfter: time.Millisecond})
+
+		receipt, err := manager.Transact(context.Background(), nil, test_call)
+		require.Nil(t, err)
+		require.Len(t, backend.sent, 2)
+		assert.Equal(t, backend.sent[0].Nonce(), backend.sent[1].Nonce())
+		assert.Equal(t, big.NewInt(11), backend.sent[1].GasTipCap())
+		assert.Equal(t, backend.sent[1].Hash(), receipt.TxHash)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		backend := newFakeBackend()
+		backend.mineTip = big.NewInt(1000)
+		manager := new_test_tx_manager(t, backend, TxOptions{ReceiptTimeout: 20 * time.Millisecond, MaxFeeCap: big.NewInt(200)})
+
+		_, err := manager.Transact(context.Background(), nil, test_call)
+		assert.True(t, xerrors.Is(err, ErrTxTimeout))
+	})
+
+	t.Run("revert reason on estimation", func(t *testing.T) {
+		backend := newFakeBackend()
+		backend.failCall = true
+		manager := new_test_tx_manager(t, backend, TxOptions{})
+
+		_, err := manager.Transact(context.Background(), nil, test_call)
+		assert.True(t, xerrors.Is(err, ErrTxReverted))
+		assert.Contains(t, err.Error(), "not enough value")
+		assert.Empty(t, backend.sent)
+	})
+
+	t.Run("revert reason of failed receipt", func(t *testing.T) {
+		backend := newFakeBackend()
+		manager := new_test_tx_manager(t, backend, TxOptions{})
+		tx, err := manager.Send(context.Background(), ethereum.CallMsg{To: &testTo})
+		require.Nil(t, err)
+		backend.receipts[tx.Hash()].Status = types.ReceiptStatusFailed
+
+		_, err = manager.Wait(context.Background(), tx, ethereum.CallMsg{To: &testTo})
+		assert.True(t, xerrors.Is(err, ErrTxReverted))
+		assert.Contains(t, err.Error(), "not enough value")
+	})
+}
+
+func Test_RevertReason(t *testing.T) {
+	reason, ok := RevertReason(revertDataError{data: revertNotEnoughValue})
+	assert.True(t, ok)
+	assert.Equal(t, "not enough value", reason)
+
+	reason, ok = RevertReason(xerrors.New("execution reverted: Ownable: caller is not the owner"))
+	assert.True(t, ok)
+	assert.Equal(t, "Ownable: caller is not the owner", reason)
+
+	_, ok = RevertReason(xerrors.New("connection refused"))
+	assert.False(t, ok)
+}
+
+func Test_TxOptionsFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	options := TxOptionsFlags(fs)
+	require.Nil(t, fs.Parse([]string{}))
+	assert.Equal(t, TxOptions{ReceiptTimeout: 5 * time.Minute}, options())
+
+	require.Nil(t, fs.Parse([]string{"-timeout", "1m", "-max-fee", "30"}))
+	assert.Equal(t, time.Minute, options().ReceiptTimeout)
+	assert.Equal(t, big.NewInt(30_000_000_000), options().MaxFeeCap)
+}
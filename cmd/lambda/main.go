@@ -4,14 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"strings"
 
 	my_config "github.com/SparkNFT/key_server/config"
 	"github.com/SparkNFT/key_server/controller"
 	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
 	"github.com/akrylysov/algnhsa"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"golang.org/x/xerrors"
 )
 
@@ -28,42 +27,48 @@ func init_config_from_aws_secret() {
 	secret_name := get_env("SECRET_NAME")
 	region := get_env("SECRET_REGION")
 
-	//Create a Secrets Manager client
-	cfg, err := config.LoadDefaultConfig(
-		context.Background(),
-		config.WithRegion(region),
-	)
+	ctx := context.Background()
+	secrets, err := my_config.NewAWSSecrets(ctx, region)
 	if err != nil {
-		panic(xerrors.Errorf("error when loading SDK config: %w", err))
+		panic(err)
 	}
-
-	client := secretsmanager.NewFromConfig(cfg)
-	input := secretsmanager.GetSecretValueInput{
-		SecretId:     aws.String(secret_name),
-		VersionStage: aws.String("AWSCURRENT"),
-	}
-	result, err := client.GetSecretValue(context.Background(), &input)
+	secret_string, err := secrets.GetSecret(ctx, secret_name)
 	if err != nil {
-		panic(xerrors.Errorf("error when getting secret value: %w", err))
+		panic(err)
 	}
 
-	// Decrypts secret using the associated KMS CMK.
-	// Depending on whether the secret is a string or binary, one of these fields will be populated.
-	if result.SecretString == nil {
-		panic(xerrors.New("error when getting secret string"))
-	}
-	secret_string := *result.SecretString
-
 	err = json.Unmarshal([]byte(secret_string), &my_config.C)
 	if err != nil {
 		panic(xerrors.Errorf("error when parsing config JSON: %w", err))
 	}
+	if err := my_config.ApplyEnv(&my_config.C); err != nil {
+		panic(err)
+	}
+	// Fields of config secret may refer to other secrets.
+	if err := my_config.ResolveSecrets(ctx, &my_config.C, secrets); err != nil {
+		panic(err)
+	}
+	if err := my_config.C.Validate(); err != nil {
+		panic(err)
+	}
 }
 
 func init() {
 	init_config_from_aws_secret()
+	// CHAINS: enabled chains separated by comma. All chains in config will be enabled if not given.
+	chains := make([]string, 0)
+	if env_chains := os.Getenv("CHAINS"); env_chains != "" {
+		chains = strings.Split(env_chains, ",")
+	}
+	if err := my_config.EnableChains(chains...); err != nil {
+		panic(xerrors.Errorf("error when enabling chains: %w", err))
+	}
+	// CORS_ALLOW_ORIGINS: allowed origins separated by comma. Overrides `cors.allow_origins` in secret.
+	if env_origins := os.Getenv("CORS_ALLOW_ORIGINS"); env_origins != "" {
+		my_config.C.Cors.AllowOrigins = strings.Split(env_origins, ",")
+	}
 	model.Init()
-	controller.Init()
+	controller.Init(repository.NewSQL(model.Engine))
 }
 
 func main() {
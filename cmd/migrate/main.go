@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/model"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	flagConfig = flag.String("config", "./config/config.json", "config.json file path")
+	flagDebug  = flag.Bool("debug", false, "Enable debug-level log")
+	flagTo     = flag.Uint("to", 0, "up: migrate up to this version (inclusive). 0 for latest")
+	flagSteps  = flag.Int("steps", 1, "down: number of migrations to revert")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] up|down|status\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+// Applies, reverts or lists versioned DB migrations.
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if *flagDebug {
+		log.SetLevel(log.DebugLevel)
+	} else {
+		log.SetLevel(log.InfoLevel)
+	}
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	config.ConfigPath = *flagConfig
+	// Never migrate implicitly here.
+	config.Overrides = append(config.Overrides, "db.auto_migrate=false")
+	config.Init()
+	model.Init()
+	defer model.Engine.Close()
+
+	switch flag.Arg(0) {
+	case "up":
+		applied, err := model.MigrateUp(*flagTo)
+		for _, migration := range applied {
+			fmt.Printf("Applied  %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+		fmt.Printf("%d migration(s) applied.\n", len(applied))
+	case "down":
+		if *flagSteps < 1 {
+			log.Fatalf("-steps must be positive")
+		}
+		reverted, err := model.MigrateDown(*flagSteps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+		fmt.Printf("%d migration(s) reverted.\n", len(reverted))
+	case "status":
+		statuses, err := model.MigrationStatuses()
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+		for _, status := range statuses {
+			applied_at := "pending"
+			if status.Applied {
+				applied_at = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-32s %s\n", status.Version, status.Name, applied_at)
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"math/big"
@@ -21,7 +22,7 @@ func main() {
 	config.ConfigPath = *flagConfig
 	config.Init()
 
-	contract, _, err := chain.Init(*flagChain)
+	contract, client, err := chain.Init(*flagChain)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -52,7 +53,20 @@ func main() {
 	}
 
 	fmt.Printf("Owner: %s\n", owner.String())
-	fmt.Printf("Token Address: %s\n", tokenAddr.String())
+	var token *chain.ERC20Info
+	if chain.IsNativeToken(tokenAddr.Hex()) {
+		token, err = chain.NativeTokenOf(*flagChain)
+	} else {
+		token, err = chain.ERC20InfoOf(context.Background(), client, tokenAddr)
+	}
+	switch {
+	case err != nil:
+		fmt.Printf("Token Address: %s (%s)\n", tokenAddr.String(), err.Error())
+	case chain.IsNativeToken(tokenAddr.Hex()):
+		fmt.Printf("Token: %s (%s, native currency)\n", token.Symbol, token.Name)
+	default:
+		fmt.Printf("Token: %s (%s, %d decimals) at %s\n", token.Symbol, token.Name, token.Decimals, tokenAddr.String())
+	}
 	fmt.Printf("Shill times: %d / %d\n", (totalTimes - remainTimes), totalTimes)
 	fmt.Printf("Parent: %d\n", parent)
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/SparkNFT/key_server/worker"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	flagConfig  = flag.String("config", "./config/config.json", "config.json file path")
+	flagDebug   = flag.Bool("debug", false, "Enable debug-level log")
+	flagChain   = flag.String("chain", "ethereum", "target chain")
+	flagFrom    = flag.Uint64("from", 0, "first block height (inclusive)")
+	flagTo      = flag.Uint64("to", 0, "last block height (inclusive)")
+	flagWorkers = flag.Int("workers", 4, "blocks fetched in parallel")
+	flagDryRun  = flag.Bool("dry-run", false, "only print what would change")
+)
+
+// Re-processes events of a block range, e.g. after a scanner bug is
+// fixed. Scanner checkpoint is not touched, so it is safe to run while
+// scanner is running.
+func main() {
+	flag.Parse()
+	if *flagDebug {
+		log.SetLevel(log.DebugLevel)
+	} else {
+		log.SetLevel(log.InfoLevel)
+	}
+	if *flagFrom == 0 || *flagTo == 0 {
+		panic("-from and -to must be given")
+	}
+
+	config.ConfigPath = *flagConfig
+	config.Init()
+	if err := config.EnableChains(*flagChain); err != nil {
+		panic(err.Error())
+	}
+	model.Init()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	summary, err := worker.Reindex(ctx, worker.ReindexOptions{
+		Store:   repository.NewSQL(model.Engine),
+		Chain:   *flagChain,
+		From:    *flagFrom,
+		To:      *flagTo,
+		Workers: *flagWorkers,
+		DryRun:  *flagDryRun,
+		Report:  printDiff,
+	})
+
+	verb := "Applied"
+	if *flagDryRun {
+		verb = "Would apply"
+	}
+	fmt.Printf(
+		"%s changes of %d blocks: %d added, %d removed, %d changed.\n",
+		verb, summary.Blocks, summary.Added, summary.Removed, summary.Changed,
+	)
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func printDiff(diff *worker.ReindexDiff) {
+	if diff.Empty() {
+		log.WithField("height", diff.Height).Debugf("No change")
+		return
+	}
+
+	fmt.Printf("Block %d\n", diff.Height)
+	for _, event := range diff.Added {
+		fmt.Printf("  + %s\n", describe(event))
+	}
+	for _, event := range diff.Removed {
+		fmt.Printf("  - %s (id %d)\n", describe(event), event.Id)
+	}
+	for _, event := range diff.Changed {
+		fmt.Printf("  ~ %s (id %d)\n", describe(event), event.Id)
+	}
+}
+
+func describe(event *model.Event) string {
+	return fmt.Sprintf(
+		"%s %s NFT %s %s -> %s",
+		event.Type, event.Key(), event.NFTId, event.From, event.To,
+	)
+}
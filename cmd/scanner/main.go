@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/controller"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/SparkNFT/key_server/worker"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Max time to wait for status server on shutdown.
+	SHUTDOWN_TIMEOUT = 10 * time.Second
+)
+
+var flagConfig = flag.String("config", "./config.json", "config.json path")
+var flagDebug = flag.Bool("debug", false, "Enable debug-level log")
+var flagMigrate = flag.Bool("migrate", false, "Apply pending DB migrations on startup (same as `db.auto_migrate` in config)")
+var flagChains = flag.String("chains", "", "Chains to scan, separeted by comma. If not given, all available chain in config file will be scanned.")
+var flagListen = flag.String("listen", "0.0.0.0:3001", "Address serving /metrics, /health/live and /api/v1/workers. Empty to disable.")
+var flagReloadInterval = flag.Duration("reload-interval", 10*time.Second, "How often config file is checked for changes. 0 to reload on SIGHUP only.")
+
+func init() {
+	flag.Var(&config.Overrides, "set", "Override a config `path.to.field=value`, like chain.ethereum.sleep=2s. Repeatable.")
+}
+
+// Standalone block scanner. Multiple instances can run at the same time:
+// only one of them scans a chain, others stand by.
+func main() {
+	flag.Parse()
+	if *flagDebug {
+		log.SetLevel(log.DebugLevel)
+	} else {
+		log.SetLevel(log.InfoLevel)
+	}
+
+	config.ConfigPath = *flagConfig
+	if *flagMigrate {
+		// As an override, so that config reloads keep it.
+		config.Overrides = append(config.Overrides, "db.auto_migrate=true")
+	}
+	config.Init()
+	model.Init()
+	enableChains()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go config.Watch(ctx, *flagReloadInterval)
+
+	if err := worker.StartBlockScanners(ctx, repository.NewSQL(model.Engine)); err != nil {
+		panic(xerrors.Errorf("error when starting block scanners: %w", err))
+	}
+
+	var server *http.Server
+	if *flagListen != "" {
+		server = &http.Server{Addr: *flagListen, Handler: statusEngine()}
+		go func() {
+			log.Infof("Status server listening at %s", *flagListen)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Errorf("error when opening status server: %s", err.Error())
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	log.Infof("Shutting down. Waiting for in-flight blocks to finish.")
+	worker.Wait()
+	if server != nil {
+		shutdown_ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+		defer cancel()
+		server.Shutdown(shutdown_ctx)
+	}
+	log.Infof("Bye.")
+}
+
+func statusEngine() *gin.Engine {
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	controller.RegisterStatusRoutes(engine)
+	return engine
+}
+
+func enableChains() {
+	if *flagChains == "" {
+		if err := config.EnableChains(); err != nil {
+			panic(err.Error())
+		}
+		return
+	}
+
+	if err := config.EnableChains(strings.Split(*flagChains, ",")...); err != nil {
+		panic(err.Error())
+	}
+}
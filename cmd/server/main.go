@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/SparkNFT/key_server/config"
 	"github.com/SparkNFT/key_server/controller"
 	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
 	"github.com/SparkNFT/key_server/worker"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/xerrors"
@@ -16,11 +23,21 @@ import (
 const (
 	ENVIRONMENT    = "development"
 	LISTEN_ADDRESS = "0.0.0.0:3000"
+	// Max time to wait for in-flight HTTP requests on shutdown.
+	SHUTDOWN_TIMEOUT = 30 * time.Second
 )
 
 var flagConfig = flag.String("config", "./config.json", "config.json path")
 var flagDebug = flag.Bool("debug", false, "Enable debug-level log")
+var flagMigrate = flag.Bool("migrate", false, "Apply pending DB migrations on startup (same as `db.auto_migrate` in config)")
 var flagChains = flag.String("chains", "", "All enabled chains, separeted by comma. If not given, all available chain in config file will be enabled.")
+var flagAPIOnly = flag.Bool("api-only", false, "Serve API only. Run cmd/scanner separately to scan blocks.")
+var flagCheckConfig = flag.Bool("check-config", false, "Load and validate config, print every problem found, then exit")
+var flagReloadInterval = flag.Duration("reload-interval", 10*time.Second, "How often config file is checked for changes. 0 to reload on SIGHUP only.")
+
+func init() {
+	flag.Var(&config.Overrides, "set", "Override a config `path.to.field=value`, like chain.ethereum.sleep=2s. Repeatable.")
+}
 
 func main() {
 	flag.Parse()
@@ -31,42 +48,80 @@ func main() {
 	}
 
 	config.ConfigPath = *flagConfig
+	if *flagCheckConfig {
+		checkConfig()
+		return
+	}
+	if *flagMigrate {
+		// As an override, so that config reloads keep it.
+		config.Overrides = append(config.Overrides, "db.auto_migrate=true")
+	}
 	config.Init()
 
 	model.Init()
-	controller.Init()
+	store := repository.NewSQL(model.Engine)
+	controller.Init(store)
 
 	enableChains()
 
-	for chainName, chainConfig := range config.C.Chain {
-		if chainConfig.Enabled == true {
-			worker.CheckBlockScannerConfig(chainName)
-			go worker.BlockScannerWorker(chainName)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go config.Watch(ctx, *flagReloadInterval)
+
+	if !*flagAPIOnly {
+		if err := worker.StartBlockScanners(ctx, store); err != nil {
+			panic(xerrors.Errorf("error when starting block scanners: %w", err))
 		}
 	}
 
-	err := controller.Engine.Run(LISTEN_ADDRESS)
-	if err != nil {
-		panic(xerrors.Errorf("error when opening controller: %w", err))
+	server := &http.Server{
+		Addr:    LISTEN_ADDRESS,
+		Handler: controller.Engine,
+	}
+	go func() {
+		log.Infof("Server listening at %s", LISTEN_ADDRESS)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			panic(xerrors.Errorf("error when opening controller: %w", err))
+		}
+	}()
+
+	<-ctx.Done()
+	log.Infof("Shutting down. Waiting for in-flight requests and blocks to finish.")
+	shutdown_ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancel()
+	if err := server.Shutdown(shutdown_ctx); err != nil {
+		log.Errorf("error when shutting down HTTP server: %s", err.Error())
 	}
+	worker.Wait()
+	log.Infof("Bye.")
+}
 
-	fmt.Printf("Server listening at %s", LISTEN_ADDRESS)
+// checkConfig exits non-zero if config is invalid. Nothing is connected.
+func checkConfig() {
+	loaded, err := config.Load(config.ConfigPath, config.Overrides)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	config.C = loaded
+	if *flagChains != "" {
+		if err := config.EnableChains(strings.Split(*flagChains, ",")...); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Config %s is valid.\n", config.ConfigPath)
 }
 
 func enableChains() {
-	if (*flagChains == "") { // Enable all chain in config
-		for _, chainConfig := range config.C.Chain {
-			chainConfig.Enabled = true
+	if *flagChains == "" { // Enable all chain in config
+		if err := config.EnableChains(); err != nil {
+			panic(err.Error())
 		}
 		return
 	}
 
-	enabledChains := strings.Split(*flagChains, ",")
-	for _, enabledChain := range enabledChains {
-		chainConfig, ok := config.C.Chain[enabledChain]
-		if !ok {
-			panic(fmt.Sprintf("Chain '%s' not found in config file.", enabledChain))
-		}
-		chainConfig.Enabled = true
+	if err := config.EnableChains(strings.Split(*flagChains, ",")...); err != nil {
+		panic(err.Error())
 	}
 }
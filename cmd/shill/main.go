@@ -5,44 +5,22 @@ import (
 	"flag"
 	"math/big"
 	"os"
-	"time"
 
 	"github.com/SparkNFT/key_server/chain"
 	"github.com/SparkNFT/key_server/config"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/xerrors"
 )
+
 var (
 	flagConfig = flag.String("config", "./config.json", "config.json file path")
-	flagNftId = flag.String("nftid", "0000000000000000000000000000001000000000000000000000000000000001", "NFT ID (Hexstring without '0x')")
+	txOptions  = chain.TxOptionsFlags(flag.CommandLine)
+	flagNftId  = flag.String("nftid", "0000000000000000000000000000001000000000000000000000000000000001", "NFT ID (Hexstring without '0x')")
 )
 
-func wait_tx(client *ethclient.Client, tx *types.Transaction) (receipt *types.Receipt) {
-	time.Sleep(time.Second * 3) // Make sure transaction is broadcasted
-	for {
-		_, pending, err := client.TransactionByHash(context.Background(), tx.Hash())
-		if err != nil {
-			panic(xerrors.Errorf("Error during waiting tx: %s", err.Error()))
-		}
-		if pending {
-			logrus.Debugf("Waiting for tx %s to finish...", tx.Hash())
-			time.Sleep(time.Second * 3)
-		} else {
-			logrus.Debugf("Tx %s finished.", tx.Hash())
-			break
-		}
-	}
-
-	receipt, err := client.TransactionReceipt(context.Background(), tx.Hash())
-	if err != nil {
-		panic(xerrors.Errorf("%w", err))
-	}
-	return receipt
-}
-
 func main() {
 	flag.Parse()
 	config.ConfigPath = *flagConfig
@@ -70,23 +48,27 @@ func main() {
 		panic(xerrors.Errorf("IsEditionExist() returned false for NFT ID: %+v", root_nft_id))
 	}
 
-	auth, err := chain.GenerateTransactOps(client, config.C.Chain["ethereum"].OperatorAccountPrivateKey)
+	signer, err := chain.NewSigner(context.Background(), chainName)
+	if err != nil {
+		panic(xerrors.Errorf("error when creating operator signer: %w", err))
+	}
+	tx_manager, err := chain.NewTxManager(context.Background(), client, signer, txOptions())
 	if err != nil {
 		panic(xerrors.Errorf("%w", err))
 	}
-	logrus.Debugf("Now using %s\n", auth.From.Hex())
+	logrus.Debugf("Now using %s\n", tx_manager.From().Hex())
 	value, err := contract.GetShillPriceByNFTId(nil, root_nft_id.Uint64())
 	if err != nil {
 		panic(xerrors.Errorf("error when getting shill price: %w", err))
 	}
 	logrus.Infof("Shill price: %v", value)
-	auth.Value = value
 
-	tx, err := contract.AcceptShill(auth, root_nft_id.Uint64())
+	receipt, err := tx_manager.Transact(context.Background(), value, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return contract.AcceptShill(opts, root_nft_id.Uint64())
+	})
 	if err != nil {
 		panic(xerrors.Errorf("%w", err))
 	}
-	receipt := wait_tx(client, tx)
 	if len(receipt.Logs) == 0 {
 		panic(xerrors.Errorf("Receipt Logs length is 0. Maybe this tx is failed."))
 	}
@@ -1,31 +1,54 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"os/signal"
+	"syscall"
 
 	"github.com/SparkNFT/key_server/config"
 	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
 	"github.com/SparkNFT/key_server/telegram"
+	"github.com/SparkNFT/key_server/worker"
 	"github.com/sirupsen/logrus"
 )
 
 var (
-	flag_config = flag.String("config", "./config.json", "config.json path")
+	flag_config  = flag.String("config", "./config.json", "config.json path")
+	flag_migrate = flag.Bool("migrate", false, "Apply pending DB migrations on startup (same as `db.auto_migrate` in config)")
 )
 
 func main() {
 	flag.Parse()
 	config.ConfigPath = *flag_config
+	if *flag_migrate {
+		// As an override, so that config reloads keep it.
+		config.Overrides = append(config.Overrides, "db.auto_migrate=true")
+	}
 	config.Init()
 	logrus.SetLevel(logrus.DebugLevel)
 
 	model.Init()
 	defer model.Engine.Close()
 
-	telegram.Init(false)
+	store := repository.NewSQL(model.Engine)
+	telegram.Init(false, store)
 	defer telegram.B.Close()
 
+	// Any number of bots may run: only one of them announces new issues.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	chainName := config.Get().Telegram.ChainName()
+	worker.Supervise(ctx, "telegram_notifier", chainName,
+		worker.WithLeaderLock(store, "telegram_notifier", chainName, telegram.NotifierWorker(store)))
+	go func() {
+		<-ctx.Done()
+		telegram.B.Stop()
+	}()
+
 	logrus.WithField("module", "main").Infof("Now listening Telegram messages...")
 	// Will block
 	telegram.B.Start()
+	worker.Wait()
 }
@@ -3,46 +3,166 @@ package config
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"sort"
 	"time"
 )
 
 var (
 	ConfigPath string = "./config/config.json"
 	C          Config
+	// Overrides are "path.to.field=value" given by `-set` flags.
+	Overrides OverrideFlags
 )
 
 type Config struct {
-	DB       DBConfig                `json:"db"`
-	Chain    map[string]*ChainConfig `json:"chain"`
-	Telegram TelegramConfig          `json:"telegram"`
-	Pinata   PinataConfig            `json:"pinata"`
+	DB        DBConfig                `json:"db"`
+	Chain     map[string]*ChainConfig `json:"chain"`
+	Telegram  TelegramConfig          `json:"telegram"`
+	Pinata    PinataConfig            `json:"pinata"`
+	RateLimit RateLimitConfig         `json:"rate_limit"`
+	Cors      CorsConfig              `json:"cors"`
+	Health    HealthConfig            `json:"health"`
+	Secrets   SecretsConfig           `json:"secrets"`
 }
 
+const (
+	DBDriverPostgres = "postgres"
+	DBDriverSQLite   = "sqlite"
+)
+
 type DBConfig struct {
+	// Driver is "postgres" (default) or "sqlite". SQLite is meant for
+	// local development and tests: it is used by one process only.
+	Driver string `json:"driver"`
+	// Path is the database file of SQLite. Other fields are for Postgres.
+	Path     string `json:"path"`
 	Host     string `json:"host"`
 	Port     int    `json:"port"`
 	User     string `json:"user"`
 	Password string `json:"password"`
 	DBName   string `json:"db_name"`
 	TZ       string `json:"tz"`
+	// AutoMigrate applies pending migrations when connecting. Otherwise
+	// run `cmd/migrate up` before deploying.
+	AutoMigrate bool `json:"auto_migrate"`
 }
 
 type ChainConfig struct {
 	Enabled                   bool
-	RPCUrl                    string        `json:"rpc_url"`
-	ContractAddress           string        `json:"contract_address"`
-	OperatorAccountPrivateKey string        `json:"operator_account_privkey"`
-	BlockHeight               uint64        `json:"block_height"`
-	BlockConfirmCount         uint16        `json:"block_confirm_count"`
-	SleepSeconds              time.Duration `json:"sleep_seconds"`
-	FailSleepSeconds          time.Duration `json:"fail_sleep_seconds"`
+	RPCUrl                    string            `json:"rpc_url"`  // Preferred endpoint
+	RPCUrls                   []RPCEndpoint     `json:"rpc_urls"` // Fallback endpoints
+	RPC                       RPCPoolConfig     `json:"rpc"`
+	ContractAddress           string            `json:"contract_address"`
+	OperatorAccountPrivateKey string            `json:"operator_account_privkey"` // Used by "key" signer
+	Operator                  OperatorConfig    `json:"operator"`
+	BlockHeight               uint64            `json:"block_height"`
+	BlockConfirmCount         uint16            `json:"block_confirm_count"`
+	ScanMode                  string            `json:"scan_mode"`  // "poll" (default) or "subscribe"
+	Sleep                     Duration          `json:"sleep"`      // Between two blocks
+	FailSleep                 Duration          `json:"fail_sleep"` // After a failed block
+	BlockLogRetention         BlockLogRetention `json:"block_log_retention"`
+	NativeCurrency            NativeCurrency    `json:"native_currency"`
+}
+
+// UnmarshalJSON also accepts `sleep_seconds` and `fail_sleep_seconds`
+// of older configs.
+func (chain *ChainConfig) UnmarshalJSON(data []byte) error {
+	type plain ChainConfig
+	if err := json.Unmarshal(data, (*plain)(chain)); err != nil {
+		return err
+	}
+
+	legacy := struct {
+		SleepSeconds     *Duration `json:"sleep_seconds"`
+		FailSleepSeconds *Duration `json:"fail_sleep_seconds"`
+	}{}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	if legacy.SleepSeconds != nil && chain.Sleep == 0 {
+		chain.Sleep = *legacy.SleepSeconds
+	}
+	if legacy.FailSleepSeconds != nil && chain.FailSleep == 0 {
+		chain.FailSleep = *legacy.FailSleepSeconds
+	}
+	return nil
+}
+
+const (
+	// ScanModePoll asks for chain head every `sleep`.
+	ScanModePoll = "poll"
+	// ScanModeSubscribe is woken up by new heads of a WebSocket
+	// endpoint, and polls while no subscription is alive.
+	ScanModeSubscribe = "subscribe"
+)
+
+const (
+	SignerKey      = "key"
+	SignerKeystore = "keystore"
+	SignerExternal = "external"
+)
+
+// OperatorConfig tells how cmd/publish and cmd/shill sign transactions
+// of the operator account.
+type OperatorConfig struct {
+	// Signer is "key" (default, uses operator_account_privkey),
+	// "keystore" or "external".
+	Signer string `json:"signer"`
+	// Encrypted go-ethereum keystore file, and its passphrase.
+	KeystorePath       string `json:"keystore_path"`
+	KeystorePassphrase string `json:"keystore_passphrase"`
+	// JSON-RPC endpoint of an external signer like Clef, and account to
+	// sign with (defaults to its first account).
+	ExternalURL string `json:"external_url"`
+	Address     string `json:"address"`
+}
+
+// NativeCurrency is the currency of a chain, paid for NFTs published
+// with token address 0x0. Defaults to Ether.
+type NativeCurrency struct {
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+	Decimals uint8  `json:"decimals"`
+}
+
+// Native returns native currency of chain, applying default.
+func (chain ChainConfig) Native() NativeCurrency {
+	if chain.NativeCurrency.Symbol == "" {
+		return NativeCurrency{Name: "Ether", Symbol: "ETH", Decimals: 18}
+	}
+	return chain.NativeCurrency
+}
+
+// BlockLogRetention limits how many BlockLogs are kept per chain. Logs
+// exceeding any non-zero limit are deleted. Keeps 100 blocks if both
+// are zero.
+type BlockLogRetention struct {
+	Count      uint64 `json:"count"`       // Latest N block heights
+	AgeSeconds uint   `json:"age_seconds"` // Created within N seconds
+}
+
+// Limits returns count and age limits, applying default.
+func (retention BlockLogRetention) Limits() (count uint64, age time.Duration) {
+	if retention.Count == 0 && retention.AgeSeconds == 0 {
+		return 100, 0
+	}
+	return retention.Count, time.Duration(retention.AgeSeconds) * time.Second
 }
 
 type TelegramConfig struct {
 	Token              string `json:"token"`
 	SparkLinkURLBase   string `json:"spark_link_url_base"`
 	BlockViewerURLBase string `json:"block_viewer_url_base"`
+	// Chain ERC20 addresses of binds live on. Defaults to "ethereum".
+	Chain string `json:"chain"`
+}
+
+// ChainName returns chain of ERC20 binds, applying default.
+func (telegram TelegramConfig) ChainName() string {
+	if telegram.Chain == "" {
+		return "ethereum"
+	}
+	return telegram.Chain
 }
 
 type PinataConfig struct {
@@ -50,25 +170,107 @@ type PinataConfig struct {
 	Secret string `json:"secret"` // Pinata-Secret-Api-Key
 }
 
-// Init initializes config
+type RateLimitConfig struct {
+	// Store is where token buckets live. "memory" (default) or "postgres".
+	// Use "postgres" when multiple server instances share the limit.
+	Store string `json:"store"`
+	// Routes maps "METHOD /path" (gin route path) to its limits.
+	Routes map[string]RouteRateLimit `json:"routes"`
+	// TrustedProxies are IPs or CIDRs of reverse proxies allowed to set
+	// client IP by X-Forwarded-For. None by default: client IP is the
+	// peer address.
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+type RouteRateLimit struct {
+	PerIP *RateLimitRule `json:"per_ip"`
+	// PerAccount limits the account a request is signed by. Charged by
+	// handlers once signature is verified.
+	PerAccount *RateLimitRule `json:"per_account"`
+}
+
+// RateLimitRule allows `Requests` requests every `PeriodSeconds`
+// seconds, with bursts up to `Burst` (defaults to `Requests`).
+type RateLimitRule struct {
+	Requests      uint `json:"requests"`
+	PeriodSeconds uint `json:"period_seconds"`
+	Burst         uint `json:"burst"`
+}
+
+type CorsConfig struct {
+	// AllowOrigins is a list of exact origins ("https://sparklink.io") or
+	// origins with one "*" wildcard ("https://*.sparklink.io").
+	// All origins are allowed if empty or contains "*".
+	AllowOrigins     []string `json:"allow_origins"`
+	AllowMethods     []string `json:"allow_methods"` // Defaults to GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS
+	AllowHeaders     []string `json:"allow_headers"` // Defaults to Origin, Content-Length, Content-Type
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAgeSeconds    uint     `json:"max_age_seconds"` // Defaults to 12 hours
+}
+
+// HealthConfig tunes `GET /health/ready`. Zero values fall back to
+// defaults.
+type HealthConfig struct {
+	TimeoutSeconds             uint   `json:"timeout_seconds"`               // Timeout of each check. Defaults to 5
+	MaxScannerLag              uint64 `json:"max_scanner_lag"`               // Blocks behind chain head. Defaults to 100
+	MaxScannerStaleSeconds     uint   `json:"max_scanner_stale_seconds"`     // Since last scanner progress. Defaults to 300
+	PinataCheckIntervalSeconds uint   `json:"pinata_check_interval_seconds"` // Pinata result is cached. Defaults to 60
+}
+
+func (health HealthConfig) Timeout() time.Duration {
+	return secondsOrDefault(health.TimeoutSeconds, 5)
+}
+
+func (health HealthConfig) ScannerLag() uint64 {
+	if health.MaxScannerLag == 0 {
+		return 100
+	}
+	return health.MaxScannerLag
+}
+
+func (health HealthConfig) ScannerStale() time.Duration {
+	return secondsOrDefault(health.MaxScannerStaleSeconds, 300)
+}
+
+func (health HealthConfig) PinataCheckInterval() time.Duration {
+	return secondsOrDefault(health.PinataCheckIntervalSeconds, 60)
+}
+
+func secondsOrDefault(seconds, default_seconds uint) time.Duration {
+	if seconds == 0 {
+		seconds = default_seconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Init loads config from ConfigPath, environment variables and
+// Overrides. Panics listing every problem if config is invalid.
 func Init() {
 	if len(C.Chain) > 0 {
 		return
 	}
 
-	config_content, err := ioutil.ReadFile(ConfigPath)
+	loaded, err := Load(ConfigPath, Overrides)
 	if err != nil {
-		panic(fmt.Sprintf("Error during opening config: %s", err.Error()))
+		panic(fmt.Sprintf("Error during loading config: %s", err.Error()))
 	}
+	C = loaded
+}
 
-	err = json.Unmarshal(config_content, &C)
-	if err != nil {
-		panic(fmt.Sprintf("Error during parsing config: %s", err.Error()))
+// GetDatabaseDriver returns driver name of configured database.
+func GetDatabaseDriver() string {
+	if C.DB.Driver == "" {
+		return DBDriverPostgres
 	}
+	return C.DB.Driver
 }
 
-// GetDatabaseDSN constructs a DSN string for postgresql db driver
+// GetDatabaseDSN constructs a DSN string for configured db driver
 func GetDatabaseDSN() string {
+	if GetDatabaseDriver() == DBDriverSQLite {
+		return "file:" + C.DB.Path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	}
+
 	template := "host=%s port=%d user=%s password=%s dbname=%s TimeZone=%s sslmode=disable"
 	return fmt.Sprintf(template,
 		C.DB.Host,
@@ -79,3 +281,45 @@ func GetDatabaseDSN() string {
 		C.DB.TZ,
 	)
 }
+
+// EnableChains marks given chains as enabled. All chains in config
+// will be enabled if no name is given. Chains are enabled the same
+// way on every Reload.
+func EnableChains(names ...string) error {
+	if len(names) == 0 {
+		enabledNames = nil
+		for _, chainConfig := range C.Chain {
+			chainConfig.Enabled = true
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		chainConfig, ok := C.Chain[name]
+		if !ok {
+			return fmt.Errorf("chain '%s' not found in config file", name)
+		}
+		chainConfig.Enabled = true
+	}
+	enabledNames = append([]string{}, names...)
+	return nil
+}
+
+// EnabledChains returns names of all enabled chains in alphabetical order.
+func EnabledChains() (names []string) {
+	chains := Get().Chain
+	names = make([]string, 0, len(chains))
+	for name, chainConfig := range chains {
+		if chainConfig.Enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsChainEnabled returns true if chain exists in config and is enabled.
+func IsChainEnabled(name string) bool {
+	chainConfig, ok := Get().Chain[name]
+	return ok && chainConfig != nil && chainConfig.Enabled
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Duration is read from a duration string ("1m30s", "500ms"), or from a
+// number of seconds for configs written before durations were
+// supported.
+type Duration time.Duration
+
+// ParseDuration parses a duration string or a number of seconds.
+func ParseDuration(s string) (Duration, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return Duration(seconds * float64(time.Second)), nil
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid duration %q: use a number of seconds or a string like \"1m30s\"", s)
+	}
+	return Duration(duration), nil
+}
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case float64:
+		*d = Duration(value * float64(time.Second))
+		return nil
+	case string:
+		parsed, err := ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	default:
+		return xerrors.Errorf("invalid duration %s", string(data))
+	}
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
@@ -0,0 +1,265 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/yaml"
+	"golang.org/x/xerrors"
+)
+
+// EnvPrefix starts names of environment variables overriding config
+// file, like SPARK_DB_PASSWORD or SPARK_CHAIN_ETHEREUM_RPC_URL.
+const EnvPrefix = "SPARK_"
+
+// Load reads config in layers: file at path (JSON, or YAML if it ends
+// with .yaml or .yml), then environment variables, then overrides
+// ("path.to.field=value"). secret:// references are then resolved, and
+// returned config is validated.
+func Load(path string, overrides []string) (Config, error) {
+	config := Config{}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, xerrors.Errorf("error when opening config: %w", err)
+	}
+	if err := Parse(content, filepath.Ext(path), &config); err != nil {
+		return config, xerrors.Errorf("error when parsing config %s: %w", path, err)
+	}
+	if err := ApplyEnv(&config); err != nil {
+		return config, err
+	}
+	if err := ApplyOverrides(&config, overrides); err != nil {
+		return config, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	if err := ResolveSecrets(ctx, &config, Secrets); err != nil {
+		return config, err
+	}
+	return config, config.Validate()
+}
+
+// Parse decodes JSON or YAML content into config, according to file
+// extension ext.
+func Parse(content []byte, ext string, config *Config) error {
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		converted, err := yaml.YAMLToJSON(content)
+		if err != nil {
+			return err
+		}
+		content = converted
+	}
+	return json.Unmarshal(content, config)
+}
+
+// ApplyEnv overrides fields of config by environment variables. Variable
+// name is EnvPrefix followed by JSON path of field, uppercased and joined
+// by "_". Map entries (like chains) must already exist in config.
+func ApplyEnv(config *Config) error {
+	problems := []string{}
+	for _, path := range fieldPaths(reflect.ValueOf(config).Elem(), nil) {
+		name := envName(path)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setPath(reflect.ValueOf(config).Elem(), path, value); err != nil {
+			problems = append(problems, name+": "+err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ApplyOverrides sets fields given as "path.to.field=value", like
+// "chain.ethereum.sleep=2s". Missing map entries are created.
+func ApplyOverrides(config *Config, overrides []string) error {
+	problems := []string{}
+	for _, override := range overrides {
+		path, value, ok := strings.Cut(override, "=")
+		if !ok {
+			problems = append(problems, override+": expected path.to.field=value")
+			continue
+		}
+		if err := setPath(reflect.ValueOf(config).Elem(), strings.Split(path, "."), value); err != nil {
+			problems = append(problems, path+": "+err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// OverrideFlags collects repeated `-set path.to.field=value` flags.
+type OverrideFlags []string
+
+func (overrides *OverrideFlags) String() string {
+	return strings.Join(*overrides, ",")
+}
+
+func (overrides *OverrideFlags) Set(value string) error {
+	*overrides = append(*overrides, value)
+	return nil
+}
+
+func envName(path []string) string {
+	name := strings.ToUpper(strings.Join(path, "_"))
+	return EnvPrefix + strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+var durationType = reflect.TypeOf(Duration(0))
+
+func isLeaf(t reflect.Type) bool {
+	return t == durationType || t.Kind() != reflect.Struct && t.Kind() != reflect.Map && t.Kind() != reflect.Ptr
+}
+
+// fieldPaths lists JSON paths of all settable leaf fields reachable in v.
+// Fields without JSON tag are runtime state and skipped.
+func fieldPaths(v reflect.Value, prefix []string) (paths [][]string) {
+	if isLeaf(v.Type()) {
+		return [][]string{prefix}
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return fieldPaths(v.Elem(), prefix)
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			paths = append(paths, fieldPaths(v.MapIndex(key), append(prefix[:len(prefix):len(prefix)], key.String()))...)
+		}
+		return paths
+	}
+	for i := 0; i < v.NumField(); i++ {
+		name := jsonName(v.Type().Field(i))
+		if name == "" {
+			continue
+		}
+		paths = append(paths, fieldPaths(v.Field(i), append(prefix[:len(prefix):len(prefix)], name))...)
+	}
+	return paths
+}
+
+// setPath parses value into field at JSON path under v. v must be
+// addressable.
+func setPath(v reflect.Value, path []string, value string) error {
+	if len(path) == 0 {
+		return setLeaf(v, value)
+	}
+	if isLeaf(v.Type()) {
+		return xerrors.Errorf("unknown field %q", strings.Join(path, "."))
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setPath(v.Elem(), path, value)
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := reflect.ValueOf(path[0]).Convert(v.Type().Key())
+		// Map entries are not addressable: edit a copy and put it back.
+		entry := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			entry.Set(existing)
+		}
+		if err := setPath(entry, path[1:], value); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, entry)
+		return nil
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		if jsonName(v.Type().Field(i)) == path[0] {
+			return setPath(v.Field(i), path[1:], value)
+		}
+	}
+	return xerrors.Errorf("unknown field %q", path[0])
+}
+
+func setLeaf(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		duration, err := ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(duration))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return xerrors.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return xerrors.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return xerrors.Errorf("invalid unsigned integer %q", value)
+		}
+		v.SetUint(parsed)
+	case reflect.Slice:
+		// Comma separated. Items which are not strings must unmarshal
+		// from a JSON string, like RPCEndpoint.
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			element := reflect.New(v.Type().Elem())
+			if unmarshaler, ok := element.Interface().(json.Unmarshaler); ok {
+				quoted, _ := json.Marshal(item)
+				if err := unmarshaler.UnmarshalJSON(quoted); err != nil {
+					return xerrors.Errorf("invalid item %q: %w", item, err)
+				}
+			} else if element.Elem().Kind() == reflect.String {
+				element.Elem().SetString(item)
+			} else {
+				return xerrors.Errorf("unsupported field type %s", v.Type())
+			}
+			items = reflect.Append(items, element.Elem())
+		}
+		v.Set(items)
+	default:
+		return xerrors.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
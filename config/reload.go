@@ -0,0 +1,137 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReloadHook is called after a reload swapped current config from old
+// to new. Both must not be modified.
+type ReloadHook func(old, new *Config)
+
+var (
+	current     atomic.Pointer[Config]
+	reloadLock  sync.Mutex
+	reloadHooks []ReloadHook
+	hooksLock   sync.Mutex
+
+	// Chains given to EnableChains. nil means all chains, including
+	// ones added by later reloads.
+	enabledNames []string
+)
+
+// Get returns config currently in effect: the latest one loaded by
+// Reload, or C if never reloaded. Long-running code should call Get
+// each time instead of keeping the result. Never modify it.
+func Get() *Config {
+	if loaded := current.Load(); loaded != nil {
+		return loaded
+	}
+	return &C
+}
+
+// Set makes c the config returned by Get, without calling hooks.
+// Set(nil) goes back to C.
+func Set(c *Config) {
+	current.Store(c)
+}
+
+// OnReload registers hook called after every successful Reload.
+func OnReload(hook ReloadHook) {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+	reloadHooks = append(reloadHooks, hook)
+}
+
+// Reload loads config again from ConfigPath and Overrides. Invalid
+// config is rejected as a whole and current config stays in effect.
+// Chains are enabled as given to EnableChains at startup.
+func Reload() error {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	loaded, err := Load(ConfigPath, Overrides)
+	if err != nil {
+		return err
+	}
+	for name, chainConfig := range loaded.Chain {
+		chainConfig.Enabled = enabledNames == nil || contains(enabledNames, name)
+	}
+
+	old := Get()
+	if !reflect.DeepEqual(old.DB, loaded.DB) {
+		logrus.WithField("module", "config").Warnf("db config changed. Restart to apply.")
+	}
+	Set(&loaded)
+	logrus.WithFields(logrus.Fields{"module": "config", "chains": EnabledChains()}).Infof("Config reloaded from %s", ConfigPath)
+
+	hooksLock.Lock()
+	hooks := append([]ReloadHook{}, reloadHooks...)
+	hooksLock.Unlock()
+	for _, hook := range hooks {
+		hook(old, &loaded)
+	}
+	return nil
+}
+
+// Watch reloads config when file at ConfigPath is modified (checked
+// every interval, disabled if zero) or SIGHUP is received, until ctx
+// is done.
+func Watch(ctx context.Context, interval time.Duration) {
+	l := logrus.WithFields(logrus.Fields{"module": "config", "path": ConfigPath})
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	modified := modTime(ConfigPath)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			l.Infof("SIGHUP received. Reloading config.")
+		case <-tick:
+			latest := modTime(ConfigPath)
+			if !latest.After(modified) {
+				continue
+			}
+			modified = latest
+			l.Infof("Config file modified. Reloading config.")
+		}
+		if err := Reload(); err != nil {
+			l.Errorf("Config not reloaded, keeping current one: %s", err.Error())
+		}
+	}
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RPCEndpoint is one JSON-RPC endpoint of a chain. In config it is a
+// URL string, or an object overriding pool defaults for this endpoint.
+type RPCEndpoint struct {
+	URL       string         `json:"url"`
+	Timeout   Duration       `json:"timeout"`    // Per request
+	RateLimit *RateLimitRule `json:"rate_limit"` // Requests sent to this endpoint
+}
+
+func (endpoint *RPCEndpoint) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &endpoint.URL)
+	}
+	type plain RPCEndpoint
+	return json.Unmarshal(data, (*plain)(endpoint))
+}
+
+// RPCPoolConfig tunes the pool of RPC endpoints of a chain.
+type RPCPoolConfig struct {
+	// Timeout of a request, unless set by endpoint. Defaults to 10s.
+	Timeout Duration `json:"timeout"`
+	// RateLimit of every endpoint, unless set by endpoint. Unlimited if
+	// nil.
+	RateLimit *RateLimitRule `json:"rate_limit"`
+	// MaxLagBlocks is how far an endpoint may fall behind the highest
+	// head seen on other endpoints before it is avoided. Defaults to 5.
+	MaxLagBlocks uint64 `json:"max_lag_blocks"`
+	// CheckInterval is how often heads of all endpoints are compared.
+	// Defaults to 30s.
+	CheckInterval Duration `json:"check_interval"`
+}
+
+func (pool RPCPoolConfig) RequestTimeout() time.Duration {
+	if pool.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return pool.Timeout.Duration()
+}
+
+func (pool RPCPoolConfig) MaxLag() uint64 {
+	if pool.MaxLagBlocks == 0 {
+		return 5
+	}
+	return pool.MaxLagBlocks
+}
+
+func (pool RPCPoolConfig) Interval() time.Duration {
+	if pool.CheckInterval <= 0 {
+		return 30 * time.Second
+	}
+	return pool.CheckInterval.Duration()
+}
+
+// RPCEndpoints returns endpoints of chain in order of preference:
+// `rpc_url` first, then `rpc_urls`, skipping duplicates. Timeout and
+// rate limit default to those of `rpc`.
+func (chain ChainConfig) RPCEndpoints() []RPCEndpoint {
+	all := chain.RPCUrls
+	if chain.RPCUrl != "" {
+		all = append([]RPCEndpoint{{URL: chain.RPCUrl}}, all...)
+	}
+
+	endpoints := make([]RPCEndpoint, 0, len(all))
+	seen := make(map[string]bool, len(all))
+	for _, endpoint := range all {
+		if endpoint.URL == "" || seen[endpoint.URL] {
+			continue
+		}
+		seen[endpoint.URL] = true
+		if endpoint.Timeout <= 0 {
+			endpoint.Timeout = Duration(chain.RPC.RequestTimeout())
+		}
+		if endpoint.RateLimit == nil {
+			endpoint.RateLimit = chain.RPC.RateLimit
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
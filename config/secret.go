@@ -0,0 +1,239 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// SecretPrefix starts string values of config which are resolved by a
+// SecretProvider: "secret://name" is the whole secret, and
+// "secret://name#field" is a field of a JSON object secret.
+const SecretPrefix = "secret://"
+
+const (
+	SecretProviderFile = "file"
+	SecretProviderEnv  = "env"
+	SecretProviderAWS  = "aws"
+
+	// Timeout of resolving all secrets of a config.
+	secretTimeout = 30 * time.Second
+)
+
+var (
+	// Secrets resolves secret:// references. Built from `secrets` of
+	// config when nil. Set it to FakeSecrets in tests.
+	Secrets SecretProvider
+
+	ErrSecretNotFound = xerrors.New("secret not found")
+)
+
+// SecretProvider is a backend storing secrets by name.
+type SecretProvider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// SecretsConfig selects SecretProvider resolving secret:// references.
+type SecretsConfig struct {
+	// Provider is "file", "env" or "aws".
+	Provider string `json:"provider"`
+	// Dir of file provider. Secret "name" is file Dir/name. Defaults to
+	// /run/secrets.
+	Dir string `json:"dir"`
+	// AWSRegion of Secrets Manager. Defaults to region of AWS SDK
+	// environment.
+	AWSRegion string `json:"aws_region"`
+}
+
+// NewSecretProvider builds SecretProvider configured by secrets.
+func NewSecretProvider(ctx context.Context, secrets SecretsConfig) (SecretProvider, error) {
+	switch secrets.Provider {
+	case SecretProviderFile:
+		dir := secrets.Dir
+		if dir == "" {
+			dir = "/run/secrets"
+		}
+		return FileSecrets{Dir: dir}, nil
+	case SecretProviderEnv:
+		return EnvSecrets{}, nil
+	case SecretProviderAWS:
+		return NewAWSSecrets(ctx, secrets.AWSRegion)
+	case "":
+		return nil, xerrors.New("secrets.provider is not set")
+	default:
+		return nil, xerrors.Errorf("unknown secrets.provider %q", secrets.Provider)
+	}
+}
+
+// FileSecrets reads secret "name" from file Dir/name, like Docker or
+// Kubernetes secrets. Trailing newlines are trimmed.
+type FileSecrets struct {
+	Dir string
+}
+
+func (secrets FileSecrets) GetSecret(ctx context.Context, name string) (string, error) {
+	cleaned := filepath.Clean(name)
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", xerrors.Errorf("secret name %q must be inside %s", name, secrets.Dir)
+	}
+	content, err := os.ReadFile(filepath.Join(secrets.Dir, cleaned))
+	if os.IsNotExist(err) {
+		return "", xerrors.Errorf("%s: %w", name, ErrSecretNotFound)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// EnvSecrets reads secret "name" from environment variable of the same
+// name.
+type EnvSecrets struct{}
+
+func (EnvSecrets) GetSecret(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", xerrors.Errorf("%s: %w", name, ErrSecretNotFound)
+	}
+	return value, nil
+}
+
+// FakeSecrets serves secrets from memory. For tests.
+type FakeSecrets map[string]string
+
+func (secrets FakeSecrets) GetSecret(ctx context.Context, name string) (string, error) {
+	value, ok := secrets[name]
+	if !ok {
+		return "", xerrors.Errorf("%s: %w", name, ErrSecretNotFound)
+	}
+	return value, nil
+}
+
+// ParseSecretRef splits "secret://name#field". field is empty if not
+// given.
+func ParseSecretRef(ref string) (name, field string, ok bool) {
+	if !strings.HasPrefix(ref, SecretPrefix) {
+		return "", "", false
+	}
+	name, field, _ = strings.Cut(strings.TrimPrefix(ref, SecretPrefix), "#")
+	return name, field, name != ""
+}
+
+// ResolveSecrets replaces every secret:// string in config with its
+// secret value, using provider, or the one configured in
+// config.Secrets if provider is nil. Each secret is fetched once.
+func ResolveSecrets(ctx context.Context, config *Config, provider SecretProvider) error {
+	root := reflect.ValueOf(config).Elem()
+	refs := map[string][]string{}
+	for _, path := range fieldPaths(root, nil) {
+		if path[0] == "secrets" {
+			continue
+		}
+		value := getPath(root, path)
+		if value.Kind() == reflect.String && strings.HasPrefix(value.String(), SecretPrefix) {
+			refs[strings.Join(path, ".")] = path
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	if provider == nil {
+		built, err := NewSecretProvider(ctx, config.Secrets)
+		if err != nil {
+			return &ValidationError{Problems: []string{"secrets.provider: " + err.Error()}}
+		}
+		provider = built
+	}
+
+	fetched := map[string]string{}
+	problems := []string{}
+	for name, path := range refs {
+		ref := getPath(root, path).String()
+		value, err := resolveSecretRef(ctx, provider, fetched, ref)
+		if err != nil {
+			problems = append(problems, name+": "+err.Error())
+			continue
+		}
+		if err := setPath(root, path, value); err != nil {
+			problems = append(problems, name+": "+err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func resolveSecretRef(ctx context.Context, provider SecretProvider, fetched map[string]string, ref string) (string, error) {
+	name, field, ok := ParseSecretRef(ref)
+	if !ok {
+		return "", xerrors.Errorf("%q must look like secret://name#field", ref)
+	}
+	secret, ok := fetched[name]
+	if !ok {
+		var err error
+		if secret, err = provider.GetSecret(ctx, name); err != nil {
+			return "", xerrors.Errorf("error when getting secret %s: %w", name, err)
+		}
+		fetched[name] = secret
+	}
+	if field == "" {
+		return secret, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", xerrors.Errorf("secret %s is not a JSON object", name)
+	}
+	raw, ok := fields[field]
+	if !ok {
+		return "", xerrors.Errorf("field %s of secret %s: %w", field, name, ErrSecretNotFound)
+	}
+	value := ""
+	if err := json.Unmarshal(raw, &value); err != nil {
+		// Numbers and others are used as written.
+		return string(raw), nil
+	}
+	return value, nil
+}
+
+// getPath returns field at JSON path under v, or an invalid Value if
+// not found.
+func getPath(v reflect.Value, path []string) reflect.Value {
+	for _, name := range path {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		case reflect.Struct:
+			found := reflect.Value{}
+			for i := 0; i < v.NumField(); i++ {
+				if jsonName(v.Type().Field(i)) == name {
+					found = v.Field(i)
+					break
+				}
+			}
+			v = found
+		default:
+			return reflect.Value{}
+		}
+		if !v.IsValid() {
+			return v
+		}
+	}
+	return v
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_config "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"golang.org/x/xerrors"
+)
+
+// AWSSecrets reads current version of secret "name" from AWS Secrets
+// Manager.
+type AWSSecrets struct {
+	client *secretsmanager.Client
+}
+
+// NewAWSSecrets creates a Secrets Manager client of region. Credentials
+// come from AWS SDK environment.
+func NewAWSSecrets(ctx context.Context, region string) (*AWSSecrets, error) {
+	options := []func(*aws_config.LoadOptions) error{}
+	if region != "" {
+		options = append(options, aws_config.WithRegion(region))
+	}
+	cfg, err := aws_config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, xerrors.Errorf("error when loading SDK config: %w", err)
+	}
+	return &AWSSecrets{client: secretsmanager.NewFromConfig(cfg)}, nil
+}
+
+func (secrets *AWSSecrets) GetSecret(ctx context.Context, name string) (string, error) {
+	input := secretsmanager.GetSecretValueInput{
+		SecretId:     aws.String(name),
+		VersionStage: aws.String("AWSCURRENT"),
+	}
+	result, err := secrets.client.GetSecretValue(ctx, &input)
+	if err != nil {
+		return "", xerrors.Errorf("error when getting secret value: %w", err)
+	}
+
+	// Depending on whether the secret is a string or binary, one of these fields will be populated.
+	if result.SecretString != nil {
+		return *result.SecretString, nil
+	}
+	if result.SecretBinary != nil {
+		return string(result.SecretBinary), nil
+	}
+	return "", xerrors.Errorf("%s: %w", name, ErrSecretNotFound)
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists every problem found in config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (err *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(err.Problems, "\n  - ")
+}
+
+var (
+	addressPattern    = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	privateKeyPattern = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{64}$`)
+)
+
+// Validate checks config as a whole, and returns a *ValidationError
+// listing all problems found, or nil.
+func (c Config) Validate() error {
+	problems := []string{}
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch c.DB.Driver {
+	case "", DBDriverPostgres:
+		if c.DB.Host == "" {
+			problem("db.host: required for postgres")
+		}
+		if c.DB.Port <= 0 || c.DB.Port > 65535 {
+			problem("db.port: %d is not a valid port", c.DB.Port)
+		}
+		if c.DB.User == "" {
+			problem("db.user: required for postgres")
+		}
+		if c.DB.DBName == "" {
+			problem("db.db_name: required for postgres")
+		}
+	case DBDriverSQLite:
+		if c.DB.Path == "" {
+			problem("db.path: required for sqlite")
+		}
+	default:
+		problem("db.driver: %q is not one of %q, %q", c.DB.Driver, DBDriverPostgres, DBDriverSQLite)
+	}
+
+	if len(c.Chain) == 0 {
+		problem("chain: at least one chain is required")
+	}
+	names := make([]string, 0, len(c.Chain))
+	for name := range c.Chain {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		chain := c.Chain[name]
+		prefix := "chain." + name
+		if chain == nil {
+			problem("%s: empty chain config", prefix)
+			continue
+		}
+		if chain.RPCUrl != "" || len(chain.RPCUrls) == 0 {
+			if err := validateURL(chain.RPCUrl, "http", "https", "ws", "wss"); err != "" {
+				problem("%s.rpc_url: %s", prefix, err)
+			}
+		}
+		for i, endpoint := range chain.RPCUrls {
+			if err := validateURL(endpoint.URL, "http", "https", "ws", "wss"); err != "" {
+				problem("%s.rpc_urls[%d]: %s", prefix, i, err)
+			}
+			if endpoint.RateLimit != nil && (endpoint.RateLimit.Requests == 0 || endpoint.RateLimit.PeriodSeconds == 0) {
+				problem("%s.rpc_urls[%d].rate_limit: requests and period_seconds must be positive", prefix, i)
+			}
+		}
+		if rule := chain.RPC.RateLimit; rule != nil && (rule.Requests == 0 || rule.PeriodSeconds == 0) {
+			problem("%s.rpc.rate_limit: requests and period_seconds must be positive", prefix)
+		}
+		if !addressPattern.MatchString(chain.ContractAddress) {
+			problem("%s.contract_address: %q is not a 0x-prefixed hex address", prefix, chain.ContractAddress)
+		}
+		if chain.OperatorAccountPrivateKey != "" && !privateKeyPattern.MatchString(chain.OperatorAccountPrivateKey) {
+			problem("%s.operator_account_privkey: must be 64 hex characters", prefix)
+		}
+		switch chain.Operator.Signer {
+		case "", SignerKey:
+		case SignerKeystore:
+			if chain.Operator.KeystorePath == "" {
+				problem("%s.operator.keystore_path: required for keystore signer", prefix)
+			}
+		case SignerExternal:
+			if err := validateURL(chain.Operator.ExternalURL, "http", "https", "ws", "wss"); err != "" {
+				problem("%s.operator.external_url: %s", prefix, err)
+			}
+		default:
+			problem("%s.operator.signer: %q is not one of %q, %q, %q", prefix, chain.Operator.Signer, SignerKey, SignerKeystore, SignerExternal)
+		}
+		if chain.Operator.Address != "" && !addressPattern.MatchString(chain.Operator.Address) {
+			problem("%s.operator.address: %q is not a 0x-prefixed hex address", prefix, chain.Operator.Address)
+		}
+		if chain.BlockHeight == 0 {
+			problem("%s.block_height: must be the height the contract was deployed at", prefix)
+		}
+		switch chain.ScanMode {
+		case "", ScanModePoll:
+		case ScanModeSubscribe:
+			if !hasWebSocket(chain.RPCEndpoints()) {
+				problem("%s.scan_mode: subscribe needs a ws:// or wss:// endpoint", prefix)
+			}
+		default:
+			problem("%s.scan_mode: %q is not one of %q, %q", prefix, chain.ScanMode, ScanModePoll, ScanModeSubscribe)
+		}
+		if chain.Sleep <= 0 {
+			problem("%s.sleep: must be positive, like \"1s\"", prefix)
+		}
+		if chain.FailSleep <= 0 {
+			problem("%s.fail_sleep: must be positive, like \"5s\"", prefix)
+		}
+		if (chain.NativeCurrency.Name != "" || chain.NativeCurrency.Decimals != 0) && chain.NativeCurrency.Symbol == "" {
+			problem("%s.native_currency.symbol: required", prefix)
+		}
+	}
+
+	for name, base := range map[string]string{
+		"telegram.spark_link_url_base":   c.Telegram.SparkLinkURLBase,
+		"telegram.block_viewer_url_base": c.Telegram.BlockViewerURLBase,
+	} {
+		if base == "" {
+			continue
+		}
+		if err := validateURL(base, "http", "https"); err != "" {
+			problem("%s: %s", name, err)
+		}
+	}
+
+	if c.Telegram.Chain != "" && c.Chain[c.Telegram.Chain] == nil {
+		problem("telegram.chain: %q is not a configured chain", c.Telegram.Chain)
+	}
+
+	switch c.RateLimit.Store {
+	case "", "memory", "postgres":
+	default:
+		problem("rate_limit.store: %q is not one of \"memory\", \"postgres\"", c.RateLimit.Store)
+	}
+	for i, proxy := range c.RateLimit.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problem("rate_limit.trusted_proxies[%d]: %q is neither an IP nor a CIDR", i, proxy)
+			}
+		}
+	}
+	routes := make([]string, 0, len(c.RateLimit.Routes))
+	for route := range c.RateLimit.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		prefix := "rate_limit.routes." + route
+		method, path, ok := strings.Cut(route, " ")
+		if !ok || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			problem("%s: route must look like \"POST /api/v1/key/claim\"", prefix)
+		}
+		limit := c.RateLimit.Routes[route]
+		for name, rule := range map[string]*RateLimitRule{"per_ip": limit.PerIP, "per_account": limit.PerAccount} {
+			if rule != nil && (rule.Requests == 0 || rule.PeriodSeconds == 0) {
+				problem("%s.%s: requests and period_seconds must be positive", prefix, name)
+			}
+		}
+	}
+
+	switch c.Secrets.Provider {
+	case "", SecretProviderFile, SecretProviderEnv, SecretProviderAWS:
+	default:
+		problem("secrets.provider: %q is not one of %q, %q, %q", c.Secrets.Provider, SecretProviderFile, SecretProviderEnv, SecretProviderAWS)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return &ValidationError{Problems: problems}
+}
+
+// validateURL returns a problem description, or "" if raw is an
+// absolute URL with one of given schemes.
+func validateURL(raw string, schemes ...string) string {
+	if raw == "" {
+		return "required"
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return fmt.Sprintf("%q is not an absolute URL", raw)
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%q must use one of schemes %s", raw, strings.Join(schemes, ", "))
+}
+
+func hasWebSocket(endpoints []RPCEndpoint) bool {
+	for _, endpoint := range endpoints {
+		if strings.HasPrefix(endpoint.URL, "ws://") || strings.HasPrefix(endpoint.URL, "wss://") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/SparkNFT/key_server/chain"
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/worker"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type ChainListResponse struct {
+	Chains []ChainInfo `json:"chains"`
+}
+
+// ChainInfo describes an enabled chain. Nullable fields are null when
+// they cannot be determined by this instance (e.g. RPC unreachable, or
+// scanner is not running in current process).
+type ChainInfo struct {
+	Name            string  `json:"name"`
+	ChainID         *uint64 `json:"chain_id"`
+	ContractAddress string  `json:"contract_address"`
+	ScannerHead     *uint64 `json:"scanner_head"`
+	ChainHead       *uint64 `json:"chain_head"`
+	Lag             *uint64 `json:"lag"`
+}
+
+// chain_list returns all enabled chains with scanner progress.
+func chain_list(c *gin.Context) {
+	chains := make([]ChainInfo, 0, len(config.Get().Chain))
+	for _, chainName := range config.EnabledChains() {
+		chains = append(chains, chain_info(chainName))
+	}
+
+	c.JSON(http.StatusOK, ChainListResponse{
+		Chains: chains,
+	})
+}
+
+func chain_info(chainName string) (info ChainInfo) {
+	l := logrus.WithFields(logrus.Fields{"module": "controller", "chain": chainName})
+	info = ChainInfo{Name: chainName}
+	if chainConfig, ok := config.Get().Chain[chainName]; ok && chainConfig != nil {
+		info.ContractAddress = chainConfig.ContractAddress
+	}
+
+	if chain_id, err := chain_id_of(chainName); err == nil {
+		info.ChainID = &chain_id
+	} else {
+		l.Warnf("error when fetching chain ID: %s", err.Error())
+	}
+
+	if status, ok := worker.ScannerStatusOf(chainName); ok {
+		head, chain_head, lag := status.Head, status.ChainHead, status.Lag()
+		info.ScannerHead = &head
+		info.ChainHead = &chain_head
+		info.Lag = &lag
+		return info
+	}
+
+	// Scanner is not running in this process. Use DB record instead.
+	if store == nil {
+		return info
+	}
+	checkpoint, err := store.BlockLogs().FindCheckpoint(chainName)
+	if err != nil {
+		l.Debugf("error when finding scanner head: %s", err.Error())
+		return info
+	}
+	info.ScannerHead = &checkpoint.BlockHeight
+	return info
+}
+
+func chain_id_of(chainName string) (chain_id uint64, err error) {
+	_, client, err := chain.Init(chainName)
+	if err != nil {
+		return 0, err
+	}
+	result, err := chain.ChainIDOf(client, chainName)
+	if err != nil {
+		return 0, err
+	}
+	return result.Uint64(), nil
+}
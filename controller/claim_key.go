@@ -2,11 +2,7 @@ package controller
 
 import (
 	"encoding/json"
-	"fmt"
-	"math/big"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/SparkNFT/key_server/chain"
@@ -14,13 +10,14 @@ import (
 	"github.com/SparkNFT/key_server/pinata"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
 )
 
 type ClaimKeyRequest struct {
-	Chain     string `json:"chain"`
-	NFTId     string `json:"nft_id"`
-	Account   string `json:"account"`
-	Signature string `json:"signature"`
+	Chain     string `json:"chain" binding:"required,chain"`
+	NFTId     string `json:"nft_id" binding:"required,nft_id"`
+	Account   string `json:"account" binding:"required,eth_addr"`
+	Signature string `json:"signature" binding:"required,hexadecimal"`
 }
 
 type ClaimKeyResponse struct {
@@ -36,48 +33,37 @@ type ClaimKeyPinataResponse struct {
 func claim_key(c *gin.Context) {
 	// JSON Unmarshal req
 	req := ClaimKeyRequest{}
-	err := c.BindJSON(&req)
+	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorMessage{
-			Message: err.Error(),
-		})
+		c.Error(bindingError(err))
 		return
 	}
 
 	// Check sig
 	if !claim_key_check_signature(&req) {
-		c.JSON(http.StatusBadRequest, ErrorMessage{
-			Message: "signature invalid",
-		})
+		c.Error(newAPIError(http.StatusBadRequest, CodeSignatureInvalid, "signature invalid", nil))
 		return
 	}
-
-	// Param validation
-	if claim_key_param_invalid(&req) {
-		c.JSON(http.StatusBadRequest, ErrorMessage{
-			Message: "param invalid",
-		})
+	if !rate_limit_account(c, req.Account) {
 		return
 	}
-	nft_id, err := strconv.ParseUint(req.NFTId, 10, 64)
+
+	// Already validated by binding
+	nft_id, err := model.ParseTokenId(req.NFTId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorMessage{
-			Message: "param invalid",
-		})
+		c.Error(newAPIError(http.StatusBadRequest, CodeParamInvalid, "param invalid", err))
 		return
 	}
-	root_nft_id := chain.RootNFTIdOf(nft_id)
+	root_nft_id := model.TokenId(chain.RootNFTIdOf(uint64(nft_id)))
 
 	// NFT ownership
 	if err = claim_key_check_nft(req.Chain, req.Account, nft_id); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorMessage{
-			Message: err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	// Get key
-	key, err := model.GetKey(req.Chain, root_nft_id)
+	key, err := store.Keys().Get(req.Chain, root_nft_id)
 	if err == nil {
 		// Success. Just return the key.
 		c.JSON(http.StatusOK, ClaimKeyResponse{
@@ -86,38 +72,35 @@ func claim_key(c *gin.Context) {
 		return
 	}
 
-	if !strings.Contains(err.Error(), "NFT not found") {
+	if !xerrors.Is(err, model.ErrKeyNotFound) {
 		// Something unexped happens
-		c.JSON(http.StatusInternalServerError, ErrorMessage{
-			Message: err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	// GetKey returns Not Found. So we create one.
 	// Before creating, make sure only Root NFT can create this key.
 	if root_nft_id != nft_id {
-		c.JSON(http.StatusNotFound, ErrorMessage{
-			Message: "Key haven't generated. Please wait for root owner to create this.",
-		})
+		c.Error(newAPIError(
+			http.StatusNotFound,
+			CodeKeyNotGenerated,
+			"Key haven't generated. Please wait for root owner to create this.",
+			nil,
+		))
 		return
 	}
 
 	// All set. Create this.
-	key_instance, err := model.CreateKey(req.Chain, req.Account, root_nft_id)
+	key_instance, err := store.Keys().Create(req.Chain, req.Account, root_nft_id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorMessage{
-			Message: err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	// Generate Pinata upload key
-	pinata_key, err := pinata.GenerateAPIKey(req.Chain, nft_id)
+	pinata_key, err := pinata.GenerateAPIKey(req.Chain, uint64(nft_id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorMessage{
-			Message: "Pinata error: " + err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 	// Revoke this key after 5min
@@ -135,10 +118,6 @@ func claim_key(c *gin.Context) {
 	})
 }
 
-func claim_key_param_invalid(req *ClaimKeyRequest) bool {
-	return (req.NFTId == "0" || req.Chain == "" || req.NFTId == "" || req.Signature == "" || req.Account == "")
-}
-
 func claim_key_check_signature(req *ClaimKeyRequest) bool {
 	signature_raw_payload := gin.H{
 		"account": req.Account,
@@ -155,19 +134,24 @@ func claim_key_check_signature(req *ClaimKeyRequest) bool {
 }
 
 // claim_key_check_nft checks if nft_id is exists and is owned by this account
-func claim_key_check_nft(chainName string, account string, nft_id uint64) error {
+func claim_key_check_nft(chainName string, account string, nft_id model.TokenId) error {
 	contract, _, err := chain.Init(chainName)
+	if err != nil {
+		if xerrors.Is(err, chain.ErrChainNotConfigured) {
+			return err
+		}
+		return newAPIError(http.StatusBadGateway, CodeChainUnavailable, "chain unavailable", err)
+	}
 
-	result, err := chain.IsOwnerOfNFT(contract, big.NewInt(int64(nft_id)), common.HexToAddress(account))
-
+	result, err := chain.IsOwnerOfNFT(contract, nft_id.Big(), common.HexToAddress(account))
 	if err != nil {
-		if strings.Contains(err.Error(), "nonexistent") {
-			return fmt.Errorf("not found")
+		if xerrors.Is(err, chain.ErrNFTNonexistent) {
+			return err
 		}
-		return err
+		return newAPIError(http.StatusBadGateway, CodeChainUnavailable, "chain unavailable", err)
 	}
 	if !result {
-		return fmt.Errorf("not owned")
+		return xerrors.Errorf("%w: %s", chain.ErrNFTNotOwned, nft_id)
 	}
 	return nil
 }
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"strings"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+// newCorsConfig converts config.CorsConfig into gin-contrib/cors config.
+func newCorsConfig(cors_config config.CorsConfig) (result cors.Config, err error) {
+	result = cors.DefaultConfig()
+	result.AllowCredentials = cors_config.AllowCredentials
+	if len(cors_config.AllowMethods) > 0 {
+		result.AllowMethods = cors_config.AllowMethods
+	}
+	if len(cors_config.AllowHeaders) > 0 {
+		result.AllowHeaders = cors_config.AllowHeaders
+	}
+	if cors_config.MaxAgeSeconds > 0 {
+		result.MaxAge = time.Duration(cors_config.MaxAgeSeconds) * time.Second
+	}
+
+	for _, origin := range cors_config.AllowOrigins {
+		if origin == "*" {
+			result.AllowAllOrigins = true
+			result.AllowOrigins = nil
+			break
+		}
+		if strings.Count(origin, "*") > 1 {
+			return cors.Config{}, xerrors.Errorf("only one * is allowed in CORS origin: %s", origin)
+		}
+		result.AllowOrigins = append(result.AllowOrigins, origin)
+	}
+	if len(result.AllowOrigins) == 0 {
+		result.AllowAllOrigins = true
+	}
+	result.AllowWildcard = true
+
+	// Browsers reject `Access-Control-Allow-Origin: *` with credentials.
+	if result.AllowAllOrigins && result.AllowCredentials {
+		return cors.Config{}, xerrors.New("CORS credentials cannot be allowed for all origins")
+	}
+	if err = result.Validate(); err != nil {
+		return cors.Config{}, xerrors.Errorf("invalid CORS config: %w", err)
+	}
+	return result, nil
+}
+
+// CORS middleware
+func middlewareCors(cors_config config.CorsConfig) (gin.HandlerFunc, error) {
+	result, err := newCorsConfig(cors_config)
+	if err != nil {
+		return nil, err
+	}
+	return cors.New(result), nil
+}
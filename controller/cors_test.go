@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func cors_engine(t *testing.T, cors_config config.CorsConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	middleware, err := middlewareCors(cors_config)
+	assert.Nil(t, err)
+
+	engine := gin.New()
+	engine.Use(middleware)
+	engine.POST("/api/v1/key/claim", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return engine
+}
+
+func preflight(engine *gin.Engine, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/key/claim", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func Test_middlewareCors(t *testing.T) {
+	t.Run("allow all by default", func(t *testing.T) {
+		engine := cors_engine(t, config.CorsConfig{})
+		recorder := preflight(engine, "https://anywhere.example")
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("exact and wildcard origins", func(t *testing.T) {
+		engine := cors_engine(t, config.CorsConfig{
+			AllowOrigins:     []string{"https://sparklink.io", "https://*.sparklink.io"},
+			AllowMethods:     []string{"GET", "POST"},
+			AllowHeaders:     []string{"Content-Type"},
+			AllowCredentials: true,
+			MaxAgeSeconds:    600,
+		})
+
+		for _, origin := range []string{"https://sparklink.io", "https://app.sparklink.io"} {
+			recorder := preflight(engine, origin)
+			assert.Equal(t, http.StatusNoContent, recorder.Code, origin)
+			assert.Equal(t, origin, recorder.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "GET,POST", recorder.Header().Get("Access-Control-Allow-Methods"))
+			assert.Equal(t, "Content-Type", recorder.Header().Get("Access-Control-Allow-Headers"))
+			assert.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Equal(t, "600", recorder.Header().Get("Access-Control-Max-Age"))
+		}
+
+		recorder := preflight(engine, "https://evil.example")
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := middlewareCors(config.CorsConfig{AllowCredentials: true})
+		assert.NotNil(t, err)
+
+		_, err = middlewareCors(config.CorsConfig{AllowOrigins: []string{"https://*.*.sparklink.io"}})
+		assert.NotNil(t, err)
+
+		_, err = middlewareCors(config.CorsConfig{AllowOrigins: []string{"sparklink.io"}})
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/SparkNFT/key_server/chain"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/pinata"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+// ErrorCode is a stable, machine-readable error identifier returned
+// in every error response. See doc/api.apib for the full list.
+type ErrorCode string
+
+const (
+	CodeParamInvalid     ErrorCode = "param_invalid"
+	CodeNFTIdOutOfRange  ErrorCode = "nft_id_out_of_range"
+	CodeSignatureInvalid ErrorCode = "signature_invalid"
+	CodeChainUnsupported ErrorCode = "chain_unsupported"
+	CodeNFTNotFound      ErrorCode = "nft_not_found"
+	CodeNFTNotOwned      ErrorCode = "nft_not_owned"
+	CodeKeyNotGenerated  ErrorCode = "key_not_generated"
+	CodeChainUnavailable ErrorCode = "chain_unavailable"
+	CodePinataError      ErrorCode = "pinata_error"
+	CodeRouteNotFound    ErrorCode = "route_not_found"
+	CodeRateLimited      ErrorCode = "rate_limited"
+	CodeInternalError    ErrorCode = "internal_error"
+)
+
+type ErrorMessage struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// APIError carries everything needed to render an error response.
+// Err is the underlying cause. It is logged but never sent to clients.
+type APIError struct {
+	Status  int
+	Code    ErrorCode
+	Message string
+	Err     error
+}
+
+func (e *APIError) Error() string {
+	if e.Err == nil {
+		return string(e.Code) + ": " + e.Message
+	}
+	return string(e.Code) + ": " + e.Message + ": " + e.Err.Error()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func newAPIError(status int, code ErrorCode, message string, err error) *APIError {
+	return &APIError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// errorMappings translates sentinel errors from other packages into
+// API errors. First match wins.
+var errorMappings = []struct {
+	target  error
+	status  int
+	code    ErrorCode
+	message string
+}{
+	{model.ErrNFTNotFound, http.StatusNotFound, CodeNFTNotFound, "not found"},
+	{model.ErrTokenIdOutOfRange, http.StatusBadRequest, CodeNFTIdOutOfRange, "nft_id out of range"},
+	{chain.ErrChainNotConfigured, http.StatusBadRequest, CodeChainUnsupported, "chain not supported"},
+	{chain.ErrNFTNonexistent, http.StatusBadRequest, CodeNFTNotFound, "not found"},
+	{chain.ErrNFTNotOwned, http.StatusBadRequest, CodeNFTNotOwned, "not owned"},
+	{chain.ErrSignatureMalformed, http.StatusBadRequest, CodeSignatureInvalid, "signature invalid"},
+	{pinata.ErrUnavailable, http.StatusBadGateway, CodePinataError, "pinata unavailable"},
+	{pinata.ErrUnauthorized, http.StatusBadGateway, CodePinataError, "pinata error"},
+	{pinata.ErrRequestFailed, http.StatusBadGateway, CodePinataError, "pinata error"},
+}
+
+// toAPIError finds out which API error should be responded for err.
+// Unknown errors become internal_error without leaking details.
+func toAPIError(err error) *APIError {
+	var api_err *APIError
+	if xerrors.As(err, &api_err) {
+		return api_err
+	}
+	for _, mapping := range errorMappings {
+		if xerrors.Is(err, mapping.target) {
+			return newAPIError(mapping.status, mapping.code, mapping.message, err)
+		}
+	}
+	return newAPIError(http.StatusInternalServerError, CodeInternalError, "internal error", err)
+}
+
+// middlewareError renders the last error attached by handlers using
+// c.Error(). Handlers should not write error responses by themselves.
+func middlewareError() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		api_err := toAPIError(c.Errors.Last().Err)
+		l := logrus.WithFields(logrus.Fields{
+			"module": "controller",
+			"path":   c.FullPath(),
+			"code":   api_err.Code,
+		})
+		if api_err.Status >= http.StatusInternalServerError {
+			l.Errorf("%+v", api_err.Err)
+		} else {
+			l.Debugf("%v", api_err.Err)
+		}
+
+		c.JSON(api_err.Status, ErrorMessage{
+			Code:    api_err.Code,
+			Message: api_err.Message,
+		})
+	}
+}
+
+// routeNotFound responds to requests matching no route.
+func routeNotFound(c *gin.Context) {
+	c.Error(newAPIError(http.StatusNotFound, CodeRouteNotFound, "route not found", nil))
+}
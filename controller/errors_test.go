@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SparkNFT/key_server/chain"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func serve_error(err error) (recorder *httptest.ResponseRecorder, body ErrorMessage) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middlewareError())
+	engine.NoRoute(routeNotFound)
+	engine.GET("/error", func(c *gin.Context) {
+		c.Error(err)
+	})
+
+	recorder = httptest.NewRecorder()
+	path := "/error"
+	if err == nil {
+		path = "/nowhere"
+	}
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+	return recorder, body
+}
+
+func Test_middlewareError(t *testing.T) {
+	t.Run("sentinel error", func(t *testing.T) {
+		recorder, body := serve_error(xerrors.Errorf("error when getting NFT: %w", model.ErrNFTNotFound))
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, CodeNFTNotFound, body.Code)
+		assert.Equal(t, "not found", body.Message)
+	})
+
+	t.Run("API error", func(t *testing.T) {
+		recorder, body := serve_error(newAPIError(http.StatusBadRequest, CodeParamInvalid, "param invalid", nil))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, CodeParamInvalid, body.Code)
+	})
+
+	t.Run("chain error", func(t *testing.T) {
+		recorder, body := serve_error(xerrors.Errorf("%w: 1", chain.ErrNFTNotOwned))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, CodeNFTNotOwned, body.Code)
+	})
+
+	t.Run("internal error is hidden", func(t *testing.T) {
+		recorder, body := serve_error(xerrors.New(`pq: relation "nft" does not exist`))
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Equal(t, CodeInternalError, body.Code)
+		assert.NotContains(t, body.Message, "pq")
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		recorder, body := serve_error(nil)
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, CodeRouteNotFound, body.Code)
+	})
+}
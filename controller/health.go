@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SparkNFT/key_server/chain"
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/pinata"
+	"github.com/SparkNFT/key_server/worker"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+type HealthStatus string
+
+const (
+	HealthOK      HealthStatus = "ok"
+	HealthFail    HealthStatus = "fail"
+	HealthSkipped HealthStatus = "skipped" // Not applicable to this instance
+)
+
+// Readiness is public, so a failing component only tells why in logs.
+const (
+	healthErrorUnreachable = "unreachable"
+	healthErrorTimeout     = "timeout"
+)
+
+type HealthResponse struct {
+	Status     HealthStatus               `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+type ComponentHealth struct {
+	Status    HealthStatus `json:"status"`
+	Error     string       `json:"error,omitempty"`
+	LatencyMs int64        `json:"latency_ms"`
+}
+
+type healthCheck func(ctx context.Context) (HealthStatus, error)
+
+var (
+	pinataHealth      ComponentHealth
+	pinataHealthAt    time.Time
+	pinataHealthOf    config.PinataConfig // Credentials checked, rechecked once rotated
+	pinataHealthLock  sync.Mutex
+	pinataHealthBusy  chan struct{} // Closed when the check running finishes. nil if none
+	pinataHealthCheck = pinata.TestAuthentication
+)
+
+// health_live tells if this process is able to serve requests at all.
+func health_live(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{
+		Status:     HealthOK,
+		Components: map[string]ComponentHealth{},
+	})
+}
+
+// health_ready checks all dependencies. Responds 503 if any of them
+// fails.
+func health_ready(c *gin.Context) {
+	checks := map[string]healthCheck{
+		"db":     check_db,
+		"pinata": check_pinata,
+	}
+	for _, chainName := range config.EnabledChains() {
+		chainName := chainName
+		checks["rpc:"+chainName] = func(ctx context.Context) (HealthStatus, error) {
+			return check_rpc(ctx, chainName)
+		}
+		checks["scanner:"+chainName] = func(ctx context.Context) (HealthStatus, error) {
+			return check_scanner(chainName)
+		}
+	}
+
+	response := run_health_checks(c.Request.Context(), checks)
+	status_code := http.StatusOK
+	if response.Status != HealthOK {
+		status_code = http.StatusServiceUnavailable
+	}
+	c.JSON(status_code, response)
+}
+
+// run_health_checks runs all checks in parallel, each with timeout given
+// in config.
+func run_health_checks(ctx context.Context, checks map[string]healthCheck) (response HealthResponse) {
+	response = HealthResponse{
+		Status:     HealthOK,
+		Components: make(map[string]ComponentHealth, len(checks)),
+	}
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for name, check := range checks {
+		name, check := name, check
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			component, err := run_health_check(ctx, check)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{"module": "controller", "component": name}).
+					Warnf("Health check failed: %s", err.Error())
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			response.Components[name] = component
+			if component.Status == HealthFail {
+				response.Status = HealthFail
+			}
+		}()
+	}
+	wg.Wait()
+	return response
+}
+
+// run_health_check gives the failure of check apart, to be logged only.
+func run_health_check(ctx context.Context, check healthCheck) (component ComponentHealth, err error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Get().Health.Timeout())
+	defer cancel()
+
+	type result struct {
+		status HealthStatus
+		err    error
+	}
+	start := time.Now()
+	done := make(chan result, 1)
+	go func() {
+		status, err := check(ctx)
+		done <- result{status, err}
+	}()
+
+	select {
+	case checked := <-done:
+		component, err = ComponentHealth{Status: checked.status}, checked.err
+		if err != nil {
+			component = ComponentHealth{Status: HealthFail, Error: healthErrorUnreachable}
+		}
+	case <-ctx.Done():
+		component, err = ComponentHealth{Status: HealthFail, Error: healthErrorTimeout}, ctx.Err()
+	}
+	component.LatencyMs = time.Since(start).Milliseconds()
+	return component, err
+}
+
+func check_db(ctx context.Context) (HealthStatus, error) {
+	if store == nil {
+		return HealthFail, xerrors.New("DB not initialized")
+	}
+	if err := store.Ping(ctx); err != nil {
+		return HealthFail, xerrors.Errorf("error when pinging DB: %w", err)
+	}
+	return HealthOK, nil
+}
+
+func check_rpc(ctx context.Context, chainName string) (HealthStatus, error) {
+	_, client, err := chain.Init(chainName)
+	if err != nil {
+		return HealthFail, err
+	}
+	if _, err = client.BlockNumber(ctx); err != nil {
+		return HealthFail, xerrors.Errorf("error when fetching newest block number: %w", err)
+	}
+	return HealthOK, nil
+}
+
+// check_scanner checks progress of scanner running in this process.
+func check_scanner(chainName string) (HealthStatus, error) {
+	status, ok := worker.ScannerStatusOf(chainName)
+	if !ok {
+		return HealthSkipped, nil
+	}
+	if lag := status.Lag(); lag > config.Get().Health.ScannerLag() {
+		return HealthFail, xerrors.Errorf("scanner lags %d blocks behind chain head", lag)
+	}
+	if stale := time.Since(status.HeadUpdatedAt); stale > config.Get().Health.ScannerStale() {
+		return HealthFail, xerrors.Errorf("scanner made no progress for %s", stale.Truncate(time.Second))
+	}
+	return HealthOK, nil
+}
+
+// check_pinata verifies Pinata credentials. Result is cached to avoid
+// calling Pinata on every probe, and probes arriving while Pinata is
+// being called wait for that call instead of making their own.
+func check_pinata(ctx context.Context) (HealthStatus, error) {
+	credentials := config.Get().Pinata
+	if credentials.Key == "" || credentials.Secret == "" {
+		return HealthFail, xerrors.New("Pinata credentials not configured")
+	}
+
+	for {
+		pinataHealthLock.Lock()
+		if time.Since(pinataHealthAt) <= config.Get().Health.PinataCheckInterval() && credentials == pinataHealthOf {
+			health := pinataHealth
+			pinataHealthLock.Unlock()
+			return pinata_health_result(health)
+		}
+		busy := pinataHealthBusy
+		if busy == nil {
+			pinataHealthBusy = make(chan struct{})
+			pinataHealthLock.Unlock()
+			return pinata_health_result(refresh_pinata_health(ctx, credentials))
+		}
+		pinataHealthLock.Unlock()
+
+		select {
+		case <-busy:
+		case <-ctx.Done():
+			return HealthFail, ctx.Err()
+		}
+	}
+}
+
+// refresh_pinata_health calls Pinata without holding pinataHealthLock,
+// then caches the result and wakes probes waiting for it.
+func refresh_pinata_health(ctx context.Context, credentials config.PinataConfig) ComponentHealth {
+	health := ComponentHealth{Status: HealthOK}
+	if err := pinataHealthCheck(ctx); err != nil {
+		health = ComponentHealth{Status: HealthFail, Error: err.Error()}
+	}
+
+	pinataHealthLock.Lock()
+	defer pinataHealthLock.Unlock()
+	pinataHealth = health
+	pinataHealthAt = time.Now()
+	pinataHealthOf = credentials
+	close(pinataHealthBusy)
+	pinataHealthBusy = nil
+	return health
+}
+
+func pinata_health_result(health ComponentHealth) (HealthStatus, error) {
+	if health.Status != HealthOK {
+		return HealthFail, xerrors.New(health.Error)
+	}
+	return HealthOK, nil
+}
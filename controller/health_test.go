@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func Test_run_health_checks(t *testing.T) {
+	config.C.Health = config.HealthConfig{TimeoutSeconds: 1}
+
+	t.Run("all ok", func(t *testing.T) {
+		response := run_health_checks(context.Background(), map[string]healthCheck{
+			"ok":      func(ctx context.Context) (HealthStatus, error) { return HealthOK, nil },
+			"skipped": func(ctx context.Context) (HealthStatus, error) { return HealthSkipped, nil },
+		})
+		assert.Equal(t, HealthOK, response.Status)
+		assert.Equal(t, HealthSkipped, response.Components["skipped"].Status)
+	})
+
+	t.Run("failed and timeout", func(t *testing.T) {
+		response := run_health_checks(context.Background(), map[string]healthCheck{
+			"ok":   func(ctx context.Context) (HealthStatus, error) { return HealthOK, nil },
+			"fail": func(ctx context.Context) (HealthStatus, error) { return HealthFail, xerrors.New("broken") },
+			"slow": func(ctx context.Context) (HealthStatus, error) {
+				time.Sleep(2 * time.Second)
+				return HealthOK, nil
+			},
+		})
+		assert.Equal(t, HealthFail, response.Status)
+		assert.Equal(t, HealthOK, response.Components["ok"].Status)
+		assert.Equal(t, "unreachable", response.Components["fail"].Error, "cause is only logged")
+		assert.Equal(t, "timeout", response.Components["slow"].Error)
+	})
+}
+
+func Test_check_pinata(t *testing.T) {
+	config.C.Pinata = config.PinataConfig{Key: "key", Secret: "secret"}
+	config.C.Health = config.HealthConfig{PinataCheckIntervalSeconds: 60}
+	calls := 0
+	defer func(original func(ctx context.Context) error) { pinataHealthCheck = original }(pinataHealthCheck)
+	pinataHealthCheck = func(ctx context.Context) error {
+		calls += 1
+		return xerrors.New("pinata unauthorized")
+	}
+	pinataHealthAt = time.Time{}
+
+	status, err := check_pinata(context.Background())
+	assert.Equal(t, HealthFail, status)
+	assert.EqualError(t, err, "pinata unauthorized")
+
+	// Cached
+	check_pinata(context.Background())
+	assert.Equal(t, 1, calls)
+
+	config.C.Pinata = config.PinataConfig{}
+	status, _ = check_pinata(context.Background())
+	assert.Equal(t, HealthFail, status)
+}
+
+func Test_check_pinata_hanging(t *testing.T) {
+	config.C.Pinata = config.PinataConfig{Key: "key", Secret: "secret"}
+	config.C.Health = config.HealthConfig{PinataCheckIntervalSeconds: 60}
+	defer func(original func(ctx context.Context) error) { pinataHealthCheck = original }(pinataHealthCheck)
+	calls := int32(0)
+	pinataHealthCheck = func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-ctx.Done() // Pinata never answers
+		return ctx.Err()
+	}
+	pinataHealthAt = time.Time{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan HealthStatus, 1)
+	go func() {
+		status, _ := check_pinata(ctx)
+		first <- status
+	}()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, time.Millisecond)
+
+	// Other probes wait for the running call, and give up with their ctx.
+	probe_ctx, probe_cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer probe_cancel()
+	status, err := check_pinata(probe_ctx)
+	assert.Equal(t, HealthFail, status)
+	assert.True(t, xerrors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Cache is not locked during the call.
+	assert.True(t, pinataHealthLock.TryLock())
+	pinataHealthLock.Unlock()
+
+	cancel()
+	assert.Equal(t, HealthFail, <-first)
+	status, _ = check_pinata(context.Background())
+	assert.Equal(t, HealthFail, status, "result is cached")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func Test_health_ready(t *testing.T) {
+	before_each_contract(t)
+	pinata_config := config.C.Pinata
+	defer func() { config.C.Pinata = pinata_config }()
+	config.C.Pinata = config.PinataConfig{}
+
+	recorder := httptest.NewRecorder()
+	Engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	response := HealthResponse{}
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, HealthFail, response.Status)
+	assert.Equal(t, HealthFail, response.Components["pinata"].Status)
+	assert.Contains(t, response.Components, "db")
+	for _, chainName := range config.EnabledChains() {
+		assert.Contains(t, response.Components, "rpc:"+chainName)
+		assert.Contains(t, response.Components, "scanner:"+chainName)
+	}
+}
+
+func Test_check_scanner_not_leader(t *testing.T) {
+	before_each_contract(t)
+	require.Nil(t, store.BlockLogs().SaveCheckpoint("ethereum", 1000, "0xaaaa"))
+
+	// No scanner of the chain runs here, e.g. after stepping down.
+	status, err := check_scanner("ethereum")
+	assert.Nil(t, err)
+	assert.Equal(t, HealthSkipped, status)
+
+	info := chain_info("ethereum")
+	require.NotNil(t, info.ScannerHead)
+	assert.Equal(t, uint64(1000), *info.ScannerHead)
+	assert.Nil(t, info.Lag)
+}
@@ -3,41 +3,69 @@ package controller
 import (
 	"net/http"
 
-	"github.com/gin-contrib/cors"
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/metrics"
+	"github.com/SparkNFT/key_server/ratelimit"
+	"github.com/SparkNFT/key_server/repository"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
 )
 
+// maxRequestBody is the largest request body accepted, in bytes.
+const maxRequestBody = 64 << 10
+
 var (
 	Engine *gin.Engine
+	// store is where handlers read and write data.
+	store repository.Store
 )
 
-type ErrorMessage struct {
-	Message string `json:"message"`
-}
-
-// CORS middleware
-func middlewareCors() gin.HandlerFunc {
-	cors_config := cors.DefaultConfig()
-	cors_config.AllowAllOrigins = true
-	cors_config.AllowWildcard = true
-	// cors_config.AllowOrigins = []string{CORS_ORIGIN_URL}
-	return cors.New(cors_config)
-}
-
-// Init initializes controller
-func Init() {
+// Init initializes controller with given store.
+func Init(s repository.Store) {
 	if Engine != nil {
 		return
 	}
+	store = s
+
+	rate_limit_store, err := ratelimit.NewStore(config.Get().RateLimit.Store, store)
+	if err != nil {
+		panic(xerrors.Errorf("error when initializing rate limit store: %w", err))
+	}
+
+	cors_middleware, err := middlewareCors(config.Get().Cors)
+	if err != nil {
+		panic(xerrors.Errorf("error when initializing CORS: %w", err))
+	}
 
+	registerValidators()
 	Engine = gin.Default()
-	Engine.Use(middlewareCors())
+	if err := Engine.SetTrustedProxies(config.Get().RateLimit.TrustedProxies); err != nil {
+		panic(xerrors.Errorf("error when setting trusted proxies: %w", err))
+	}
+	Engine.Use(middlewareMetrics())
+	Engine.Use(cors_middleware)
+	Engine.Use(middlewareError())
+	Engine.Use(middlewareBodyLimit(maxRequestBody))
+	Engine.Use(middlewareRateLimit(rate_limit_store))
+	Engine.NoRoute(routeNotFound)
 	Engine.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status": "OK",
 		})
 	})
+	RegisterStatusRoutes(Engine)
+	Engine.GET("/health/ready", health_ready)
+	Engine.GET("/api/v1/openapi.json", openapi)
+	Engine.GET("/api/v1/chains", chain_list)
 	Engine.GET("/api/v1/nft/info", nft_info)
 	Engine.GET("/api/v1/nft/list", nft_list)
 	Engine.POST("/api/v1/key/claim", claim_key)
 }
+
+// RegisterStatusRoutes serves /health/live, /metrics and /api/v1/workers
+// on engine, so that processes serving no API report the same way.
+func RegisterStatusRoutes(engine gin.IRoutes) {
+	engine.GET("/health/live", health_live)
+	engine.GET("/metrics", gin.WrapH(metrics.Handler()))
+	engine.GET("/api/v1/workers", worker_list)
+}
@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/SparkNFT/key_server/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// middlewareMetrics observes request latency per gin route. Unmatched
+// paths are grouped into one label to keep cardinality low.
+func middlewareMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
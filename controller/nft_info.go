@@ -1,18 +1,17 @@
 package controller
 
 import (
-	"fmt"
 	"net/http"
-	"strconv"
-	"strings"
 
 	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
 )
 
 type NFTInfoRequest struct {
-	Chain string `form:"chain"`
-	NFTId uint64 `form:"nft_id"`
+	Chain string `form:"chain" binding:"required,chain"`
+	NFTId string `form:"nft_id" binding:"required,nft_id"`
 }
 
 type NFTInfoResponse struct {
@@ -26,58 +25,49 @@ type NFTInfoResponse struct {
 func nft_info(c *gin.Context) {
 	var req NFTInfoRequest
 	err := c.ShouldBindQuery(&req)
-	if err != nil || req.NFTId == 0 {
-		c.JSON(http.StatusBadRequest, ErrorMessage{
-			Message: "Parse param error",
-		})
+	if err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	// Already validated by binding
+	nft_id, err := model.ParseTokenId(req.NFTId)
+	if err != nil {
+		c.Error(newAPIError(http.StatusBadRequest, CodeParamInvalid, "param invalid", err))
 		return
 	}
 
-	nft, err := model.FindNFT(req.Chain, req.NFTId)
+	nft, err := store.NFTs().Find(req.Chain, nft_id)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			c.JSON(http.StatusNotFound, ErrorMessage{
-				Message: err.Error(),
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, ErrorMessage{
-			Message: fmt.Sprintf("Error when getting NFT: %s", err.Error()),
-		})
+		c.Error(xerrors.Errorf("error when getting NFT: %w", err))
 		return
 	}
 
-	count, err := model.ChildrenCount(req.Chain, nft.NFTID)
+	count, err := repository.ChildrenCount(store.NFTs(), req.Chain, nft.NFTID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorMessage{
-			Message: fmt.Sprintf("Erorr when counting NFT children: %s", err.Error()),
-		})
+		c.Error(xerrors.Errorf("error when counting NFT children: %w", err))
 		return
 	}
 
-	suggest, err := nft.Suggest(nil)
+	suggest, err := repository.Suggest(store.NFTs(), nft, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorMessage{
-			Message: fmt.Sprintf("Erorr when suggesting next NFT: %s", err.Error()),
-		})
+		c.Error(xerrors.Errorf("error when suggesting next NFT: %w", err))
 		return
 	}
 	if suggest == nil {
-		suggest = &model.NFT{NFTID: uint64(0)}
+		suggest = &model.NFT{NFTID: model.TokenId(0)}
 	}
 
-	tree, err := model.ChildrenTree(req.Chain, nft.NFTID)
+	tree, err := repository.ChildrenTree(store.NFTs(), req.Chain, nft.NFTID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorMessage{
-			Message: fmt.Sprintf("Erorr when fetching NFT Tree: %s", err.Error()),
-		})
+		c.Error(xerrors.Errorf("error when fetching NFT Tree: %w", err))
 		return
 	}
 
 	c.JSON(http.StatusOK, NFTInfoResponse{
 		ChildrenCount: count,
 		Tree:          tree,
-		Suggest:       strconv.FormatUint(suggest.NFTID, 10),
+		Suggest:       suggest.NFTID.String(),
 		MaxShillTimes: int(nft.MaxShillCount),
 		ShillTimes:    int(nft.ShillCount),
 	})
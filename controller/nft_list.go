@@ -2,16 +2,14 @@ package controller
 
 import (
 	"net/http"
-	"strconv"
 
-	"github.com/SparkNFT/key_server/model"
 	"github.com/gin-gonic/gin"
-	"xorm.io/builder"
+	"golang.org/x/xerrors"
 )
 
 type NFTListRequest struct {
-	Owner string `form:"owner"`
-	Chain string `form:"chain"`
+	Owner string `form:"owner" binding:"required,eth_addr"`
+	Chain string `form:"chain" binding:"required,chain"`
 }
 
 type NFTListResponse struct {
@@ -23,24 +21,19 @@ func nft_list(c *gin.Context) {
 	var req NFTListRequest
 	err := c.ShouldBindQuery(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorMessage{
-			Message: "Parse param error",
-		})
+		c.Error(bindingError(err))
 		return
 	}
 
-	nfts := make([]string, 0, 10)
-	err = model.Engine.Where(builder.Eq{"owner": req.Owner, "chain": req.Chain}).Iterate(new(model.NFT), func(i int, bean interface{})error{
-		nft := bean.(*model.NFT)
-		nfts = append(nfts, strconv.FormatUint(nft.NFTID, 10))
-		return nil
-	})
+	owned, err := store.NFTs().FindByOwner(req.Chain, req.Owner)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorMessage{
-			Message: "Error when fetching user NFTs",
-		})
+		c.Error(xerrors.Errorf("error when fetching user NFTs: %w", err))
 		return
 	}
+	nfts := make([]string, 0, len(owned))
+	for _, nft := range owned {
+		nfts = append(nfts, nft.NFTID.String())
+	}
 
 	c.JSON(http.StatusOK, NFTListResponse{
 		NFT: nfts,
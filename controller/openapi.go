@@ -0,0 +1,19 @@
+package controller
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPISpec is the OpenAPI 3 document of all routes registered in
+// Init(). Keep it in sync when adding or changing a route;
+// openapi_test.go will complain otherwise.
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+func openapi(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", OpenAPISpec)
+}
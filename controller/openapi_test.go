@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+type contract_case struct {
+	name   string
+	method string
+	path   string
+	body   string
+	status int
+}
+
+var contract_cases = []contract_case{
+	{name: "health", method: http.MethodGet, path: "/health", status: http.StatusOK},
+	{name: "openapi", method: http.MethodGet, path: "/api/v1/openapi.json", status: http.StatusOK},
+	{name: "metrics", method: http.MethodGet, path: "/metrics", status: http.StatusOK},
+	{name: "live", method: http.MethodGet, path: "/health/live", status: http.StatusOK},
+	{name: "chains", method: http.MethodGet, path: "/api/v1/chains", status: http.StatusOK},
+	{name: "workers", method: http.MethodGet, path: "/api/v1/workers", status: http.StatusOK},
+	{name: "info disabled chain", method: http.MethodGet, path: "/api/v1/nft/info?chain=disabled&nft_id=4294967297", status: http.StatusBadRequest},
+	{name: "info unknown chain", method: http.MethodGet, path: "/api/v1/nft/info?chain=nowhere&nft_id=4294967297", status: http.StatusBadRequest},
+	{name: "info invalid id", method: http.MethodGet, path: "/api/v1/nft/info?chain=ethereum&nft_id=abc", status: http.StatusBadRequest},
+	{name: "info zero id", method: http.MethodGet, path: "/api/v1/nft/info?chain=ethereum&nft_id=0", status: http.StatusBadRequest},
+	{name: "info id out of range", method: http.MethodGet, path: "/api/v1/nft/info?chain=ethereum&nft_id=18446744073709551616", status: http.StatusBadRequest},
+	{name: "list invalid owner", method: http.MethodGet, path: "/api/v1/nft/list?chain=ethereum&owner=0x123", status: http.StatusBadRequest},
+	{name: "claim empty body", method: http.MethodPost, path: "/api/v1/key/claim", body: `{}`, status: http.StatusBadRequest},
+	{name: "claim invalid nft_id", method: http.MethodPost, path: "/api/v1/key/claim", status: http.StatusBadRequest,
+		body: `{"chain":"ethereum","nft_id":"-1","account":"0xbb137c332cecbc8844a009f5ede4493085f81846","signature":"0x00"}`},
+	{name: "claim signature invalid", method: http.MethodPost, path: "/api/v1/key/claim", status: http.StatusBadRequest,
+		body: `{"chain":"ethereum","nft_id":"21474836480","account":"0xbb137c332cecbc8844a009f5ede4493085f81846","signature":"0x44cdf673f4261846803dc12d9427246c49a1141573cf371f685775a7059ea0b17d6fcae84b0d371adeefb30676f00819d63e4b5bfa17775ec03f6d4ed7eb563a1b"}`},
+	{name: "list", method: http.MethodGet, path: "/api/v1/nft/list?chain=ethereum&owner=0x0000004215285644116B17436372D569A4ED3A1D", status: http.StatusOK},
+	{name: "info", method: http.MethodGet, path: "/api/v1/nft/info?chain=ethereum&nft_id=4294967297", status: http.StatusOK},
+	{name: "info not found", method: http.MethodGet, path: "/api/v1/nft/info?chain=ethereum&nft_id=1", status: http.StatusNotFound},
+}
+
+func load_openapi(t *testing.T) (doc *openapi3.T, router routers.Router) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(OpenAPISpec)
+	require.Nil(t, err)
+	require.Nil(t, doc.Validate(loader.Context))
+	router, err = legacy.NewRouter(doc)
+	require.Nil(t, err)
+	return doc, router
+}
+
+// before_each_contract serves handlers with an in-memory store holding
+// a root NFT and its child.
+func before_each_contract(t *testing.T) {
+	if len(config.C.Chain) == 0 {
+		config.C.Chain = map[string]*config.ChainConfig{
+			"ethereum": {Enabled: true},
+			"disabled": {Enabled: false},
+		}
+	}
+	if Engine != nil {
+		return
+	}
+
+	memory := repository.NewMemory()
+	owner := "0x0000004215285644116B17436372D569A4ED3A1D"
+	err := memory.NFTs().Create([]*model.NFT{
+		{Chain: "ethereum", NFTID: 4294967297, MaxShillCount: 10, ShillCount: 1, Owner: owner},
+		{Chain: "ethereum", NFTID: 4294967298, Parent: 4294967297, MaxShillCount: 10, Owner: owner},
+	})
+	require.Nil(t, err)
+	Init(memory)
+}
+
+func Test_OpenAPIRoutes(t *testing.T) {
+	before_each_contract(t)
+	doc, _ := load_openapi(t)
+
+	t.Run("every route is documented", func(t *testing.T) {
+		for _, route := range Engine.Routes() {
+			path_item := doc.Paths.Find(route.Path)
+			if assert.NotNil(t, path_item, "route %s not in openapi.json", route.Path) {
+				assert.NotNil(t, path_item.GetOperation(route.Method), "%s %s not in openapi.json", route.Method, route.Path)
+			}
+		}
+	})
+
+	t.Run("every documented operation is routed", func(t *testing.T) {
+		routes := make(map[string]bool)
+		for _, route := range Engine.Routes() {
+			routes[route.Method+" "+route.Path] = true
+		}
+		for path, path_item := range doc.Paths {
+			for method := range path_item.Operations() {
+				assert.True(t, routes[method+" "+path], "%s %s documented but not routed", method, path)
+			}
+		}
+	})
+}
+
+func Test_OpenAPIContract(t *testing.T) {
+	_, router := load_openapi(t)
+
+	for _, tc := range contract_cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			before_each_contract(t)
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := serve_contract(t, router, req)
+			assert.Equal(t, tc.status, recorder.Code, recorder.Body.String())
+		})
+	}
+}
+
+// serve_contract serves req, and checks the response against
+// openapi.json.
+func serve_contract(t *testing.T, router routers.Router, req *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	Engine.ServeHTTP(recorder, req)
+
+	route, path_params, err := router.FindRoute(req)
+	require.Nil(t, err)
+	err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: path_params,
+			Route:      route,
+		},
+		Status: recorder.Code,
+		Header: recorder.Header(),
+		Body:   io.NopCloser(bytes.NewReader(recorder.Body.Bytes())),
+	})
+	assert.Nil(t, err)
+	return recorder
+}
+
+// fakeChain is an `eth` JSON-RPC service whose contract says every NFT
+// is owned by owner.
+type fakeChain struct {
+	owner common.Address
+}
+
+func (node *fakeChain) BlockNumber() hexutil.Uint64 {
+	return 100
+}
+
+func (node *fakeChain) Call(args map[string]interface{}, block string) hexutil.Bytes {
+	return common.LeftPadBytes(node.owner.Bytes(), 32)
+}
+
+// with_fake_chain points chain "ethereum" to a fakeChain until test
+// ends.
+func with_fake_chain(t *testing.T, owner common.Address) {
+	server := rpc.NewServer()
+	require.Nil(t, server.RegisterName("eth", &fakeChain{owner: owner}))
+	http_server := httptest.NewServer(server)
+
+	original := config.C.Chain["ethereum"]
+	faked := *original
+	faked.RPCUrl = http_server.URL
+	faked.ContractAddress = "0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F"
+	config.C.Chain["ethereum"] = &faked
+	t.Cleanup(func() {
+		config.C.Chain["ethereum"] = original
+		http_server.Close()
+	})
+}
+
+func Test_OpenAPIContract_claim(t *testing.T) {
+	before_each_contract(t)
+	_, router := load_openapi(t)
+	private_key, err := crypto.GenerateKey()
+	require.Nil(t, err)
+	account := crypto.PubkeyToAddress(private_key.PublicKey)
+	with_fake_chain(t, account)
+
+	// Key of root NFT is generated, so its child gets it.
+	_, err = store.Keys().Create("ethereum", account.Hex(), 4294967297)
+	if err != nil {
+		require.True(t, xerrors.Is(err, model.ErrKeyExists))
+	}
+	payload, err := json.Marshal(map[string]string{"account": account.Hex(), "chain": "ethereum", "nft_id": "4294967298"})
+	require.Nil(t, err)
+	signature, err := crypto.Sign(accounts.TextHash(payload), private_key)
+	require.Nil(t, err)
+	signature[64] += 27
+	body, err := json.Marshal(ClaimKeyRequest{
+		Chain:     "ethereum",
+		NFTId:     "4294967298",
+		Account:   account.Hex(),
+		Signature: hexutil.Encode(signature),
+	})
+	require.Nil(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/key/claim", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := serve_contract(t, router, req)
+	assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+}
+
+func Test_OpenAPIContract_ready(t *testing.T) {
+	before_each_contract(t)
+	_, router := load_openapi(t)
+	with_fake_chain(t, common.Address{})
+	pinata_config := config.C.Pinata
+	defer func(original func(ctx context.Context) error) {
+		pinataHealthCheck = original
+		pinataHealthAt = time.Time{}
+		config.C.Pinata = pinata_config
+	}(pinataHealthCheck)
+	pinataHealthCheck = func(ctx context.Context) error { return nil }
+	pinataHealthAt = time.Time{}
+
+	t.Run("ready", func(t *testing.T) {
+		config.C.Pinata = config.PinataConfig{Key: "key", Secret: "secret"}
+		recorder := serve_contract(t, router, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		config.C.Pinata = config.PinataConfig{}
+		recorder := serve_contract(t, router, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code, recorder.Body.String())
+	})
+}
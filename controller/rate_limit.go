@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// rateLimitAccountKey holds the per-account limiter of current route in
+// gin context. See rate_limit_account.
+const rateLimitAccountKey = "rate_limit_account"
+
+// middlewareRateLimit applies limits in config.Get().RateLimit.Routes to
+// matching routes. Per-IP limits are charged here. Per-account limits
+// are charged by handlers through rate_limit_account, once they know
+// who signed the request. Requests are let through if store fails.
+func middlewareRateLimit(store ratelimit.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		route_config, ok := config.Get().RateLimit.Routes[route]
+		if !ok || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+
+		if rule := ratelimit.RuleFromConfig(route_config.PerIP); rule != nil {
+			if !take_rate_limit(c, store, route, "ip", c.ClientIP(), *rule) {
+				c.Abort()
+				return
+			}
+		}
+		if rule := ratelimit.RuleFromConfig(route_config.PerAccount); rule != nil {
+			c.Set(rateLimitAccountKey, func(account string) bool {
+				return take_rate_limit(c, store, route, "account", strings.ToLower(account), *rule)
+			})
+		}
+
+		c.Next()
+	}
+}
+
+// rate_limit_account charges per-account limit of current route to
+// account, which must be verified already. Returns false with an error
+// set on c if limit is exceeded.
+func rate_limit_account(c *gin.Context, account string) bool {
+	take, ok := c.Get(rateLimitAccountKey)
+	if !ok {
+		return true
+	}
+	return take.(func(account string) bool)(account)
+}
+
+// take_rate_limit takes a token of subject's bucket. Returns false with
+// an error set on c if bucket is empty.
+func take_rate_limit(c *gin.Context, store ratelimit.Store, route, name, subject string, rule ratelimit.Rule) bool {
+	l := logrus.WithFields(logrus.Fields{
+		"module":  "controller",
+		"route":   route,
+		"limit":   name,
+		"subject": subject,
+	})
+
+	decision, err := store.Take(route+"|"+name+"|"+subject, rule, time.Now())
+	if err != nil {
+		l.Errorf("Rate limit store failed, letting request through: %s", err.Error())
+		return true
+	}
+	if decision.Allowed {
+		l.WithField("remaining", decision.Remaining).Debugf("Rate limit passed")
+		return true
+	}
+
+	retry_after := int(math.Ceil(decision.RetryAfter.Seconds()))
+	l.WithField("retry_after", retry_after).Warnf("Rate limit exceeded")
+	c.Header("Retry-After", strconv.Itoa(retry_after))
+	c.Error(newAPIError(http.StatusTooManyRequests, CodeRateLimited, "too many requests", nil))
+	return false
+}
+
+// middlewareBodyLimit caps request bodies at max bytes. Reading more
+// fails, which binding reports as invalid params.
+func middlewareBodyLimit(max int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		}
+		c.Next()
+	}
+}
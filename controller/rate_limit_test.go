@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rate_limit_engine serves "/limited", which takes `account` of body as
+// signer if `signed` is true, like claim_key does after checking
+// signature.
+func rate_limit_engine(t *testing.T, route_config config.RouteRateLimit) (engine *gin.Engine, bodies *[]string) {
+	gin.SetMode(gin.TestMode)
+	config.Set(&config.Config{RateLimit: config.RateLimitConfig{Routes: map[string]config.RouteRateLimit{
+		"POST /limited": route_config,
+	}}})
+	t.Cleanup(func() { config.Set(nil) })
+
+	bodies = &[]string{}
+	engine = gin.New()
+	require.Nil(t, engine.SetTrustedProxies(nil))
+	engine.Use(middlewareError())
+	engine.Use(middlewareBodyLimit(128))
+	engine.Use(middlewareRateLimit(ratelimit.NewMemoryStore()))
+	engine.POST("/limited", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(newAPIError(http.StatusBadRequest, CodeParamInvalid, "param invalid", err))
+			return
+		}
+		req := struct {
+			Account string `json:"account"`
+			Signed  bool   `json:"signed"`
+		}{}
+		json.Unmarshal(body, &req)
+		if !req.Signed {
+			c.Error(newAPIError(http.StatusBadRequest, CodeSignatureInvalid, "signature invalid", nil))
+			return
+		}
+		if !rate_limit_account(c, req.Account) {
+			return
+		}
+		*bodies = append(*bodies, string(body))
+		c.Status(http.StatusOK)
+	})
+	engine.POST("/free", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return engine, bodies
+}
+
+func post(engine *gin.Engine, path, remote_addr, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.RemoteAddr = remote_addr
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func Test_middlewareRateLimit(t *testing.T) {
+	rule := &config.RateLimitRule{Requests: 1, PeriodSeconds: 60}
+	signed := `{"signed":true}`
+
+	t.Run("per IP", func(t *testing.T) {
+		engine, _ := rate_limit_engine(t, config.RouteRateLimit{PerIP: rule})
+		assert.Equal(t, http.StatusOK, post(engine, "/limited", "10.0.0.1:1234", signed).Code)
+
+		recorder := post(engine, "/limited", "10.0.0.1:1234", signed)
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+		assert.Contains(t, recorder.Body.String(), string(CodeRateLimited))
+
+		assert.Equal(t, http.StatusOK, post(engine, "/limited", "10.0.0.2:1234", signed).Code)
+	})
+
+	t.Run("spoofed X-Forwarded-For", func(t *testing.T) {
+		engine, _ := rate_limit_engine(t, config.RouteRateLimit{PerIP: rule})
+		assert.Equal(t, http.StatusOK, post(engine, "/limited", "10.0.0.1:1234", signed, "X-Forwarded-For", "1.1.1.1").Code)
+		assert.Equal(t, http.StatusTooManyRequests, post(engine, "/limited", "10.0.0.1:1234", signed, "X-Forwarded-For", "2.2.2.2").Code)
+		assert.Equal(t, http.StatusTooManyRequests, post(engine, "/limited", "10.0.0.1:1234", signed, "X-Real-IP", "3.3.3.3").Code)
+	})
+
+	t.Run("X-Forwarded-For of trusted proxy", func(t *testing.T) {
+		engine, _ := rate_limit_engine(t, config.RouteRateLimit{PerIP: rule})
+		require.Nil(t, engine.SetTrustedProxies([]string{"10.0.0.0/8"}))
+		assert.Equal(t, http.StatusOK, post(engine, "/limited", "10.0.0.1:1234", signed, "X-Forwarded-For", "1.1.1.1").Code)
+		assert.Equal(t, http.StatusOK, post(engine, "/limited", "10.0.0.1:1234", signed, "X-Forwarded-For", "2.2.2.2").Code)
+		assert.Equal(t, http.StatusTooManyRequests, post(engine, "/limited", "10.0.0.2:1234", signed, "X-Forwarded-For", "2.2.2.2").Code)
+	})
+
+	t.Run("per account", func(t *testing.T) {
+		engine, bodies := rate_limit_engine(t, config.RouteRateLimit{PerAccount: rule})
+		body := `{"account":"0xbb137c332cecbc8844a009f5ede4493085f81846","signed":true}`
+		assert.Equal(t, http.StatusOK, post(engine, "/limited", "10.0.0.1:1234", body).Code)
+		assert.Equal(t, []string{body}, *bodies)
+
+		upper := `{"account":"0xBB137C332CECBC8844A009F5EDE4493085F81846","signed":true}`
+		assert.Equal(t, http.StatusTooManyRequests, post(engine, "/limited", "10.0.0.2:1234", upper).Code)
+	})
+
+	t.Run("unsigned requests do not use account quota", func(t *testing.T) {
+		engine, _ := rate_limit_engine(t, config.RouteRateLimit{PerAccount: rule})
+		forged := `{"account":"0xbb137c332cecbc8844a009f5ede4493085f81846"}`
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusBadRequest, post(engine, "/limited", "10.0.0.1:1234", forged).Code)
+		}
+		body := `{"account":"0xbb137c332cecbc8844a009f5ede4493085f81846","signed":true}`
+		assert.Equal(t, http.StatusOK, post(engine, "/limited", "10.0.0.1:1234", body).Code)
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		engine, bodies := rate_limit_engine(t, config.RouteRateLimit{PerIP: rule})
+		large := `{"signed":true,"padding":"` + strings.Repeat("a", 200) + `"}`
+		assert.Equal(t, http.StatusBadRequest, post(engine, "/limited", "10.0.0.1:1234", large).Code)
+		assert.Empty(t, *bodies)
+	})
+
+	t.Run("route not configured", func(t *testing.T) {
+		engine, _ := rate_limit_engine(t, config.RouteRateLimit{PerIP: rule})
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, post(engine, "/free", "10.0.0.1:1234", "").Code)
+		}
+	})
+}
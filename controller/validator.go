@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/xerrors"
+)
+
+// registerValidators adds custom binding tags used in request structs:
+//
+//   - `chain`: chain name exists in config and is enabled
+//   - `nft_id`: non-zero token id in decimal string, fitting into uint64
+func registerValidators() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		panic("controller: gin validator engine is not go-playground/validator")
+	}
+
+	// Report field names the same way clients send them.
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+
+	if err := validate.RegisterValidation("chain", validateChain); err != nil {
+		panic(xerrors.Errorf("error when registering chain validator: %w", err))
+	}
+	if err := validate.RegisterValidation("nft_id", validateNFTId); err != nil {
+		panic(xerrors.Errorf("error when registering nft_id validator: %w", err))
+	}
+}
+
+func validateChain(fl validator.FieldLevel) bool {
+	return config.IsChainEnabled(fl.Field().String())
+}
+
+func validateNFTId(fl validator.FieldLevel) bool {
+	nft_id, err := model.ParseTokenId(fl.Field().String())
+	return err == nil && nft_id != 0
+}
+
+// bindingError converts binding / validation error into param_invalid
+// API error, listing all invalid fields in message.
+func bindingError(err error) *APIError {
+	var validation_errors validator.ValidationErrors
+	if !xerrors.As(err, &validation_errors) {
+		return newAPIError(http.StatusBadRequest, CodeParamInvalid, "param invalid", err)
+	}
+
+	fields := make([]string, 0, len(validation_errors))
+	for _, field_error := range validation_errors {
+		if field_error.Tag() == "nft_id" {
+			_, parse_err := model.ParseTokenId(fmt.Sprint(field_error.Value()))
+			if xerrors.Is(parse_err, model.ErrTokenIdOutOfRange) {
+				return newAPIError(
+					http.StatusBadRequest,
+					CodeNFTIdOutOfRange,
+					"nft_id out of range: must fit into uint64",
+					err,
+				)
+			}
+		}
+		if field_error.Tag() == "chain" {
+			return newAPIError(
+				http.StatusBadRequest,
+				CodeChainUnsupported,
+				fmt.Sprintf("chain not supported: %v", field_error.Value()),
+				err,
+			)
+		}
+		fields = append(fields, field_error.Field())
+	}
+	return newAPIError(
+		http.StatusBadRequest,
+		CodeParamInvalid,
+		"param invalid: "+strings.Join(fields, ", "),
+		err,
+	)
+}
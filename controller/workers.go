@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/SparkNFT/key_server/worker"
+	"github.com/gin-gonic/gin"
+)
+
+type WorkerListResponse struct {
+	Workers []worker.WorkerStatus `json:"workers"`
+}
+
+// worker_list returns status of background workers running in this
+// instance.
+func worker_list(c *gin.Context) {
+	c.JSON(http.StatusOK, WorkerListResponse{
+		Workers: worker.Statuses(),
+	})
+}
@@ -0,0 +1,117 @@
+// Package metrics defines Prometheus collectors of this server. All
+// collectors are registered in default registry and exposed by Handler.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "spark"
+)
+
+var (
+	// Block scanner
+	ScannerHead = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "scanner", Name: "head_block",
+		Help: "Latest block height fully scanned.",
+	}, []string{"chain"})
+	ScannerChainHead = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "scanner", Name: "chain_head_block",
+		Help: "Newest block height seen on chain.",
+	}, []string{"chain"})
+	ScannerConfirmedHead = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "scanner", Name: "confirmed_head_block",
+		Help: "Newest block height with enough confirmations to be scanned.",
+	}, []string{"chain"})
+	ScannerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "scanner", Name: "lag_blocks",
+		Help: "How many blocks scanner is behind chain head.",
+	}, []string{"chain"})
+	ScannerBlocksProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "scanner", Name: "blocks_processed_total",
+		Help: "Blocks fully scanned.",
+	}, []string{"chain"})
+	ScannerEventsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "scanner", Name: "events_processed_total",
+		Help: "Contract events saved, by event type.",
+	}, []string{"chain", "type"})
+	ScannerFetchFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "scanner", Name: "fetch_failures_total",
+		Help: "Failed block fetches, by reason.",
+	}, []string{"chain", "reason"})
+
+	// RPC pools
+	RPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "rpc", Name: "requests_total",
+		Help: "JSON-RPC requests by endpoint host and result: ok, error or rate_limited.",
+	}, []string{"chain", "endpoint", "result"})
+	RPCEndpointHead = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "rpc", Name: "endpoint_head_block",
+		Help: "Newest block height reported by endpoint in the latest head check.",
+	}, []string{"chain", "endpoint"})
+
+	// Worker liveness
+	WorkerUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "worker", Name: "up",
+		Help: "1 if worker goroutine is running.",
+	}, []string{"worker", "chain"})
+	WorkerHeartbeat = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "worker", Name: "last_heartbeat_timestamp_seconds",
+		Help: "Unix time of the latest loop iteration of worker.",
+	}, []string{"worker", "chain"})
+	WorkerLeader = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "worker", Name: "leader",
+		Help: "1 if this instance holds leader lock of worker.",
+	}, []string{"worker", "chain"})
+
+	// HTTP API
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "http", Name: "request_duration_seconds",
+		Help:    "HTTP request latencies, by gin route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Pinata
+	PinataRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "pinata", Name: "request_duration_seconds",
+		Help:    "Pinata API call latencies.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+	PinataErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "pinata", Name: "errors_total",
+		Help: "Failed Pinata API calls, by reason.",
+	}, []string{"endpoint", "reason"})
+
+	// Telegram
+	TelegramSendFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "telegram", Name: "send_failures_total",
+		Help: "Failed Telegram Bot API calls, by API method and reason.",
+	}, []string{"method", "reason"})
+	TelegramNotifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "telegram", Name: "notifications_total",
+		Help: "New issues announced to Telegram groups, by result (sent, skipped).",
+	}, []string{"result"})
+)
+
+// Handler serves all registered metrics in Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// WorkerHeartbeatNow marks worker as alive. Should be called on every
+// loop iteration.
+func WorkerHeartbeatNow(worker, chain string) {
+	WorkerUp.WithLabelValues(worker, chain).Set(1)
+	WorkerHeartbeat.WithLabelValues(worker, chain).Set(float64(time.Now().Unix()))
+}
+
+// WorkerStopped marks worker as not running.
+func WorkerStopped(worker, chain string) {
+	WorkerUp.WithLabelValues(worker, chain).Set(0)
+}
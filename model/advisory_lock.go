@@ -0,0 +1,100 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"sync"
+
+	"golang.org/x/xerrors"
+	"xorm.io/xorm"
+)
+
+// AdvisoryLock is a Postgres session-level advisory lock. It is held as
+// long as its dedicated DB connection is alive, so a crashed holder
+// releases it automatically.
+//
+// SQLite databases are used by a single process, so locks are kept in
+// memory instead. conn is nil in that case.
+type AdvisoryLock struct {
+	Name string
+	key  int64
+	conn *sql.Conn
+}
+
+var (
+	localLocks     = make(map[int64]bool)
+	localLocksLock sync.Mutex
+)
+
+// advisoryLockKey maps lock name into the bigint key space of
+// pg_advisory_lock.
+func advisoryLockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return int64(hash.Sum64())
+}
+
+// TryAdvisoryLock acquires lock of given name without waiting. Returns
+// nil lock if it is held by others.
+func TryAdvisoryLock(ctx context.Context, engine *xorm.Engine, name string) (lock *AdvisoryLock, err error) {
+	if isSQLiteEngine(engine) {
+		return tryLocalLock(name), nil
+	}
+
+	conn, err := engine.DB().Conn(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("error when opening DB connection for lock %s: %w", name, err)
+	}
+
+	key := advisoryLockKey(name)
+	acquired := false
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired)
+	if err != nil {
+		conn.Close()
+		return nil, xerrors.Errorf("error when acquiring lock %s: %w", name, err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+	return &AdvisoryLock{Name: name, key: key, conn: conn}, nil
+}
+
+func tryLocalLock(name string) *AdvisoryLock {
+	localLocksLock.Lock()
+	defer localLocksLock.Unlock()
+	key := advisoryLockKey(name)
+	if localLocks[key] {
+		return nil
+	}
+	localLocks[key] = true
+	return &AdvisoryLock{Name: name, key: key}
+}
+
+// Alive checks if connection holding the lock is still usable.
+func (lock *AdvisoryLock) Alive(ctx context.Context) error {
+	if lock.conn == nil {
+		return nil
+	}
+	if err := lock.conn.PingContext(ctx); err != nil {
+		return xerrors.Errorf("lost connection holding lock %s: %w", lock.Name, err)
+	}
+	return nil
+}
+
+// Release unlocks and closes its connection.
+func (lock *AdvisoryLock) Release() error {
+	if lock.conn == nil {
+		localLocksLock.Lock()
+		defer localLocksLock.Unlock()
+		delete(localLocks, lock.key)
+		return nil
+	}
+	defer lock.conn.Close()
+	_, err := lock.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lock.key)
+	if err != nil {
+		return xerrors.Errorf("error when releasing lock %s: %w", lock.Name, err)
+	}
+	return nil
+}
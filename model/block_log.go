@@ -23,13 +23,16 @@ func (BlockLog) TableName() string {
 	return "block_logs"
 }
 
-func BlockLogStart(session *xorm.Session, chainName string, height uint64) (err error) {
+func BlockLogStart(db xorm.Interface, chainName string, height uint64) (err error) {
 	bl := &BlockLog{
 		Chain: chainName,
 		BlockHeight: height,
 		Scanned:     false,
 	}
-	affected, err := session.Insert(bl)
+	affected, err := db.Insert(bl)
+	if IsUniqueViolation(err) {
+		return xerrors.Errorf("%w: height %d", ErrBlockLogExists, height)
+	}
 	if err != nil {
 		return xerrors.Errorf("error when starting a BlockLog at height %d: %w", height, err)
 	}
@@ -41,8 +44,8 @@ func BlockLogStart(session *xorm.Session, chainName string, height uint64) (err
 	return nil
 }
 
-func BlockLogFinish(session *xorm.Session, chainName string, height uint64) (err error) {
-	affected, err := session.Cols("scanned").Update(BlockLog{
+func BlockLogFinish(db xorm.Interface, chainName string, height uint64) (err error) {
+	affected, err := db.Cols("scanned").Update(BlockLog{
 		Scanned: true,
 	}, BlockLog{
 		Chain: chainName,
@@ -52,41 +55,58 @@ func BlockLogFinish(session *xorm.Session, chainName string, height uint64) (err
 		return xerrors.Errorf("error when updating block log %d: %w", height, err)
 	}
 	if affected == 0 {
-		return xerrors.Errorf("Block height %d not found", height)
+		return xerrors.Errorf("%w: height %d", ErrBlockLogNotFound, height)
 	}
 	logrus.WithFields(logrus.Fields{"chain": chainName, "height": height}).WithField("model", "BlockLog").Debugf("Height finished in DB")
 
 	return nil
 }
 
-func BlockLogFindFirst(chainName string) (result *BlockLog, err error) {
+func BlockLogFindFirst(db xorm.Interface, chainName string) (result *BlockLog, err error) {
 	result = &BlockLog{}
-	found, err := Engine.Where(builder.Eq{"scanned": true, "chain": chainName}).Desc("block_height").Get(result)
+	found, err := db.Where(builder.Eq{"scanned": true, "chain": chainName}).Desc("block_height").Get(result)
 	if err != nil {
 		return nil, xerrors.Errorf("error when finding first block log: %w", err)
 	}
 	if !found {
-		return nil, xerrors.Errorf("found height failed")
+		return nil, xerrors.Errorf("found height failed: %w", ErrBlockLogNotFound)
 	}
 	logrus.WithFields(logrus.Fields{"chain": chainName, "height": result.BlockHeight}).WithField("model", "BlockLog").Debugf("Height found in DB.")
 
 	return result, nil
 }
 
-func BlockLogClean(chainName string) (err error) {
-	latest_log, err := BlockLogFindFirst(chainName)
+// BlockLogClean deletes old BlockLogs of a chain by height. Logs below
+// latest scanned height minus keep_count, or created before keep_age,
+// are deleted. A zero limit is ignored. Latest scanned log is always
+// kept.
+func BlockLogClean(db xorm.Interface, chainName string, keep_count uint64, keep_age time.Duration) (affected int64, err error) {
+	if keep_count == 0 && keep_age == 0 {
+		return 0, nil
+	}
+	latest_log, err := BlockLogFindFirst(db, chainName)
 	if err != nil {
-		return xerrors.Errorf("%w", err)
+		return 0, xerrors.Errorf("%w", err)
+	}
+
+	conditions := builder.Or()
+	if keep_count > 0 && latest_log.BlockHeight >= keep_count {
+		conditions = conditions.Or(builder.Lte{"block_height": latest_log.BlockHeight - keep_count})
+	}
+	if keep_age > 0 {
+		conditions = conditions.Or(builder.Lt{"created_at": time.Now().Add(-keep_age)})
 	}
-	id := latest_log.Id
-	if id <= uint64(100) {
-		return nil
+	if !conditions.IsValid() {
+		return 0, nil
 	}
 
-	// TODO: reserved log amount should be configurable
-	_, err = Engine.Where(builder.Lt{"id": (id - 100)}).And(builder.Eq{"chain": chainName}).Delete(&BlockLog{})
+	affected, err = db.
+		Where(builder.Eq{"chain": chainName}).
+		And(builder.Lt{"block_height": latest_log.BlockHeight}).
+		And(conditions).
+		Delete(&BlockLog{})
 	if err != nil {
-		return xerrors.Errorf("%w", err)
+		return 0, xerrors.Errorf("error when cleaning block logs: %w", err)
 	}
-	return nil
+	return affected, nil
 }
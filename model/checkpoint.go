@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// Checkpoint is the last block fully scanned of a chain. Scanner
+// resumes from the block after it. BlockHash is compared with parent
+// hash of the next block to detect reorgs.
+type Checkpoint struct {
+	Chain       string `xorm:"'chain' pk varchar(64)"`
+	BlockHeight uint64 `xorm:"'block_height' notnull"`
+	BlockHash   string `xorm:"'block_hash' notnull"`
+
+	CreatedAt time.Time `xorm:"'created_at' created"`
+	UpdatedAt time.Time `xorm:"'updated_at' updated"`
+}
+
+func (Checkpoint) TableName() string {
+	return "checkpoints"
+}
+
+// CheckpointSave moves checkpoint of a chain to given block.
+func CheckpointSave(db xorm.Interface, chainName string, height uint64, hash string) (err error) {
+	now := time.Now()
+	_, err = db.Exec(
+		"INSERT INTO checkpoints (chain, block_height, block_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?)"+
+			onConflictUpdate([]string{"chain"}, "block_height", "block_hash", "updated_at"),
+		chainName, height, hash, now, now,
+	)
+	if err != nil {
+		return xerrors.Errorf("error when saving checkpoint at height %d: %w", height, err)
+	}
+	logrus.WithFields(logrus.Fields{"chain": chainName, "height": height, "model": "Checkpoint"}).Debugf("Checkpoint saved")
+	return nil
+}
+
+// CheckpointFind returns checkpoint of given chain.
+func CheckpointFind(db xorm.Interface, chainName string) (result *Checkpoint, err error) {
+	result = &Checkpoint{}
+	found, err := db.Where(builder.Eq{"chain": chainName}).Get(result)
+	if err != nil {
+		return nil, xerrors.Errorf("error when finding checkpoint: %w", err)
+	}
+	if !found {
+		return nil, xerrors.Errorf("%w: %s", ErrCheckpointNotFound, chainName)
+	}
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// Queries which are not the same in Postgres and SQLite are built by
+// helpers below, so that the rest of model stays dialect-free.
+
+// isSQLite tells if Engine is connected to a SQLite database.
+func isSQLite() bool {
+	return isSQLiteEngine(Engine)
+}
+
+// isSQLiteEngine tells if engine is connected to a SQLite database.
+func isSQLiteEngine(engine *xorm.Engine) bool {
+	return engine != nil && engine.Dialect().URI().DBType == schemas.SQLITE
+}
+
+// onConflictDoNothing renders an `ON CONFLICT` clause ignoring rows
+// conflicting on given unique columns.
+func onConflictDoNothing(columns ...string) string {
+	return " ON CONFLICT (" + strings.Join(columns, ", ") + ") DO NOTHING"
+}
+
+// onConflictUpdate renders an `ON CONFLICT` clause overwriting given
+// columns of the existing row with the inserted ones.
+func onConflictUpdate(columns []string, updates ...string) string {
+	sets := make([]string, 0, len(updates))
+	for _, column := range updates {
+		sets = append(sets, column+" = EXCLUDED."+column)
+	}
+	return " ON CONFLICT (" + strings.Join(columns, ", ") + ") DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+// deleteUsing renders a DELETE of rows `a` in table joined with rows `b`
+// of the same table. SQLite has no `DELETE ... USING`.
+func deleteUsing(table string, condition string) string {
+	if isSQLite() {
+		return `DELETE FROM "` + table + `" AS a WHERE EXISTS (SELECT 1 FROM "` + table + `" b WHERE ` + condition + `)`
+	}
+	return `DELETE FROM "` + table + `" a USING "` + table + `" b WHERE ` + condition
+}
+
+// forUpdate locks rows found by session until transaction ends. SQLite
+// has no row locks: writers are already serialized by its database
+// lock and the single connection of the engine.
+func forUpdate(session *xorm.Session) *xorm.Session {
+	if isSQLiteEngine(session.Engine()) {
+		return session
+	}
+	return session.ForUpdate()
+}
+
+// lockTransaction serializes transactions locking the same name, until
+// the transaction ends.
+func lockTransaction(session *xorm.Session, name string) error {
+	if isSQLiteEngine(session.Engine()) {
+		return nil
+	}
+	_, err := session.Exec("SELECT pg_advisory_xact_lock(?)", advisoryLockKey(name))
+	return err
+}
+
+// IsUniqueViolation tells if err is caused by a unique index, in any
+// supported dialect.
+func IsUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	pq_err := &pq.Error{}
+	if xerrors.As(err, &pq_err) {
+		return pq_err.Code == "23505"
+	}
+	return strings.Contains(err.Error(), "duplicate key value") ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
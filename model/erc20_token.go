@@ -0,0 +1,57 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// ERC20Token caches metadata of an ERC20 contract on a chain. Address
+// is saved lowercased.
+type ERC20Token struct {
+	Chain       string `xorm:"'chain' pk varchar(64)"`
+	Address     string `xorm:"'address' pk varchar(42)"`
+	Name        string `xorm:"'name' notnull"`
+	Symbol      string `xorm:"'symbol' notnull"`
+	Decimals    uint8  `xorm:"'decimals' notnull"`
+	TotalSupply string `xorm:"'total_supply' varchar(78) notnull"` // Decimal string, at UpdatedAt
+
+	CreatedAt time.Time `xorm:"'created_at' created"`
+	UpdatedAt time.Time `xorm:"'updated_at' updated"`
+}
+
+func (ERC20Token) TableName() string {
+	return "erc20_tokens"
+}
+
+// ERC20TokenSave creates or refreshes cached metadata of a token.
+func ERC20TokenSave(db xorm.Interface, token *ERC20Token) (err error) {
+	now := time.Now()
+	token.Address = strings.ToLower(token.Address)
+	_, err = db.Exec(
+		"INSERT INTO erc20_tokens (chain, address, name, symbol, decimals, total_supply, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"+
+			onConflictUpdate([]string{"chain", "address"}, "name", "symbol", "decimals", "total_supply", "updated_at"),
+		token.Chain, token.Address, token.Name, token.Symbol, token.Decimals, token.TotalSupply, now, now,
+	)
+	if err != nil {
+		return xerrors.Errorf("error when saving ERC20 token %s: %w", token.Address, err)
+	}
+	token.UpdatedAt = now
+	return nil
+}
+
+// ERC20TokenFind returns cached metadata of a token.
+func ERC20TokenFind(db xorm.Interface, chainName string, address string) (result *ERC20Token, err error) {
+	result = &ERC20Token{}
+	found, err := db.Where(builder.Eq{"chain": chainName, "address": strings.ToLower(address)}).Get(result)
+	if err != nil {
+		return nil, xerrors.Errorf("error when finding ERC20 token: %w", err)
+	}
+	if !found {
+		return nil, xerrors.Errorf("%w: %s %s", ErrERC20TokenNotFound, chainName, address)
+	}
+	return result, nil
+}
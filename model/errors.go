@@ -0,0 +1,20 @@
+package model
+
+import "golang.org/x/xerrors"
+
+// Sentinel errors returned by model functions. Callers should use
+// xerrors.Is() against these instead of matching error strings.
+var (
+	ErrNFTNotFound        = xerrors.New("NFT not found")
+	ErrKeyNotFound        = xerrors.New("key not found")
+	ErrKeyExists          = xerrors.New("key exists")
+	ErrBlockLogNotFound   = xerrors.New("block log not found")
+	ErrBlockLogExists     = xerrors.New("block log exists")
+	ErrInvalidAddress     = xerrors.New("invalid address")
+	ErrCheckpointNotFound = xerrors.New("checkpoint not found")
+	ErrTokenIdInvalid     = xerrors.New("token id invalid")
+	ErrTokenIdOutOfRange  = xerrors.New("token id out of range")
+	ErrERC20TokenNotFound = xerrors.New("ERC20 token not found")
+	ErrTGCursorNotFound   = xerrors.New("telegram cursor not found")
+	ErrEventNotFound      = xerrors.New("event not found")
+)
@@ -1,12 +1,15 @@
 package model
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/SparkNFT/key_server/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/xerrors"
+	"xorm.io/builder"
 	"xorm.io/xorm"
 )
 
@@ -19,18 +22,24 @@ const (
 
 type Event struct {
 	Id          uint64 `xorm:"pk autoincr"`
-	Chain       string `xorm:"'chain' index notnull"`
+	Chain       string `xorm:"'chain' index notnull unique(chain_tx_event)"`
 	BlockHeight uint64 `xorm:"'block_height' index notnull"`
-	Index       uint   `xorm:"'event_index'"`
-	TxHash      string `xorm:"'tx_hash'"`
+	Index       uint   `xorm:"'event_index' unique(chain_tx_event)"`
+	TxHash      string `xorm:"'tx_hash' unique(chain_tx_event)"`
 	TxIndex     uint   `xorm:"'tx_index'"`
 
 	Type      EventType `xorm:"'type' index notnull"`
 	From      string    `xorm:"'from' index notnull"`
 	To        string    `xorm:"'to' index notnull"`
-	NFTId     uint64    `xorm:"'nft_id' index notnull"`
+	NFTId     TokenId   `xorm:"'nft_id' NUMERIC(78,0) index notnull"`
 	TokenAddr string    `xorm:"'token_addr' index"`
 
+	// Token id of Transfer as emitted (uint256, decimal). Events with
+	// TokenIdOverflow set carry an id not fitting into NFTId, which is
+	// left 0 and never used to index NFTs.
+	RawTokenId      string `xorm:"'raw_token_id' varchar(78)"`
+	TokenIdOverflow bool   `xorm:"'token_id_overflow' index notnull default(false)"`
+
 	CreatedAt time.Time `xorm:"'created_at' created"`
 	UpdatedAt time.Time `xorm:"'updated_at' updated"`
 }
@@ -65,22 +74,37 @@ func (event Event) IsMint() bool {
 
 // NFTId = (IssueId(32bit) << 32) | EditionId(32bit)
 func (event Event) IssueId() uint32 {
-	return uint32(event.NFTId >> 32)
+	return event.NFTId.IssueId()
 }
 
 // NFTId = (IssueId(32bit) << 32) | EditionId(32bit)
 func (event Event) EditionId() uint32 {
-	return uint32(event.NFTId)
+	return event.NFTId.EditionId()
 }
 
-func CreateFromBlockEventPublish(session *xorm.Session, chainName string, logs []abi.SparkLinkPublish) (events []*Event, err error) {
-	l := logrus.WithFields(logrus.Fields{"chain": chainName, "count": len(logs), "model": "Event", "type": "Publish"})
-	if len(logs) == 0 {
-		l.Debugf("No Publish event parsed.")
-		return nil, nil
-	}
+// Key identifies an event on chain: (tx_hash, event_index) is unique
+// within a chain.
+func (event Event) Key() string {
+	return fmt.Sprintf("%s:%d", strings.ToLower(event.TxHash), event.Index)
+}
+
+// SameAs returns true if both events carry the same on-chain data.
+func (event Event) SameAs(other Event) bool {
+	return event.Chain == other.Chain &&
+		event.BlockHeight == other.BlockHeight &&
+		event.Key() == other.Key() &&
+		event.TxIndex == other.TxIndex &&
+		event.Type == other.Type &&
+		event.From == other.From &&
+		event.To == other.To &&
+		event.NFTId == other.NFTId &&
+		event.TokenAddr == other.TokenAddr &&
+		event.RawTokenId == other.RawTokenId &&
+		event.TokenIdOverflow == other.TokenIdOverflow
+}
 
-	l.Debugf("Start parsing logs")
+// EventsFromPublish converts parsed Publish logs into events.
+func EventsFromPublish(chainName string, logs []abi.SparkLinkPublish) (events []*Event) {
 	events = make([]*Event, 0, len(logs))
 	for _, log := range logs {
 		event := &Event{
@@ -92,52 +116,146 @@ func CreateFromBlockEventPublish(session *xorm.Session, chainName string, logs [
 			Type:        EventTypePublish,
 			From:        common.HexToAddress("0x0").Hex(),
 			To:          log.Publisher.Hex(),
-			NFTId:       log.RootNFTId,
+			NFTId:       TokenId(log.RootNFTId),
 			TokenAddr:   log.TokenAddr.Hex(),
+			RawTokenId:  TokenId(log.RootNFTId).String(),
 		}
 		events = append(events, event)
 	}
-	l.Debugf("Publish event ready to be inserted: %+v", events)
+	return events
+}
 
-	affected, err := session.Insert(events)
-	l.WithField("affected", affected).Debugf("Insert finished")
-	if err != nil || int(affected) != len(events) {
-		return nil, xerrors.Errorf("error when inserting Publish Event to DB: %w", err)
+// EventsFromTransfer converts parsed Transfer logs into events. Token
+// ids not fitting into TokenId are logged and flagged with
+// TokenIdOverflow.
+func EventsFromTransfer(chainName string, logs []abi.SparkLinkTransfer) (events []*Event) {
+	events = make([]*Event, 0, len(logs))
+	for _, log := range logs {
+		nft_id, err := TokenIdFromBig(log.TokenId)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"chain":    chainName,
+				"model":    "Event",
+				"tx_hash":  log.Raw.TxHash.Hex(),
+				"index":    log.Raw.Index,
+				"token_id": log.TokenId.String(),
+			}).Warnf("Transfer token id flagged: %s", err.Error())
+		}
+		event := &Event{
+			Chain:           chainName,
+			BlockHeight:     log.Raw.BlockNumber,
+			Index:           log.Raw.Index,
+			TxHash:          log.Raw.TxHash.Hex(),
+			TxIndex:         log.Raw.TxIndex,
+			Type:            EventTypeTransfer,
+			From:            log.From.Hex(),
+			To:              log.To.Hex(),
+			NFTId:           nft_id,
+			TokenAddr:       "",
+			RawTokenId:      log.TokenId.String(),
+			TokenIdOverflow: err != nil,
+		}
+		events = append(events, event)
 	}
+	return events
+}
 
+// FindEventsInBlock returns all events saved for given block, ordered by
+// event index.
+func FindEventsInBlock(db xorm.Interface, chainName string, height uint64) (events []*Event, err error) {
+	events = make([]*Event, 0)
+	err = db.Where(builder.Eq{"chain": chainName, "block_height": height}).Asc("event_index", "id").Find(&events)
+	if err != nil {
+		return nil, xerrors.Errorf("error when finding events of block %d: %w", height, err)
+	}
 	return events, nil
 }
 
-func CreateFromBlockEventTransfer(session *xorm.Session, chainName string, logs []abi.SparkLinkTransfer) (events []*Event, err error) {
-	l := logrus.WithFields(logrus.Fields{"chain": chainName, "count": len(logs), "model": "Event", "type": "Transfer"})
-	if len(logs) == 0 {
-		l.Debugf("No Transfer event parsed.")
-		return nil, nil
+// FindPublishEventsBetween returns Publish events of blocks from..to
+// (inclusive), in the order they happened.
+func FindPublishEventsBetween(db xorm.Interface, chainName string, from, to uint64) (events []*Event, err error) {
+	events = make([]*Event, 0)
+	err = db.
+		Where(builder.Eq{"chain": chainName, "type": EventTypePublish}).
+		And(builder.Between{Col: "block_height", LessVal: from, MoreVal: to}).
+		Asc("block_height", "event_index", "id").
+		Find(&events)
+	if err != nil {
+		return nil, xerrors.Errorf("error when finding Publish events of blocks %d-%d: %w", from, to, err)
 	}
+	return events, nil
+}
 
-	events = make([]*Event, 0, len(logs))
-	l.Debugf("Start parsing logs")
-	for _, log := range logs {
-		event := &Event{
-			Chain:       chainName,
-			BlockHeight: log.Raw.BlockNumber,
-			Index:       log.Raw.Index,
-			TxHash:      log.Raw.TxHash.Hex(),
-			TxIndex:     log.Raw.TxIndex,
-			Type:        EventTypeTransfer,
-			From:        log.From.Hex(),
-			To:          log.To.Hex(),
-			NFTId:       log.TokenId.Uint64(),
-			TokenAddr:   "",
+// HasLaterTransfer returns true if a Transfer of the same NFT after
+// given event is saved.
+func HasLaterTransfer(db xorm.Interface, event *Event) (bool, error) {
+	later := builder.Or(
+		builder.Gt{"block_height": event.BlockHeight},
+		builder.And(builder.Eq{"block_height": event.BlockHeight}, builder.Gt{"event_index": event.Index}),
+	)
+	found, err := db.
+		Where(builder.Eq{"chain": event.Chain, "nft_id": event.NFTId, "type": EventTypeTransfer}).
+		And(later).
+		Exist(&Event{})
+	if err != nil {
+		return false, xerrors.Errorf("error when finding later transfer of %s: %w", event.NFTId, err)
+	}
+	return found, nil
+}
+
+// FindLatestTransfer returns the last Transfer of a NFT saved, or
+// ErrEventNotFound if there is none.
+func FindLatestTransfer(db xorm.Interface, chainName string, nft_id TokenId) (event *Event, err error) {
+	event = &Event{}
+	found, err := db.
+		Where(builder.Eq{"chain": chainName, "nft_id": nft_id, "type": EventTypeTransfer, "token_id_overflow": false}).
+		Desc("block_height", "event_index", "id").
+		Get(event)
+	if err != nil {
+		return nil, xerrors.Errorf("error when finding latest transfer of %s: %w", nft_id, err)
+	}
+	if !found {
+		return nil, xerrors.Errorf("%w: transfer of %s", ErrEventNotFound, nft_id)
+	}
+	return event, nil
+}
+
+// InsertEventsIgnoreExisting inserts events, skipping those already
+// saved (same chain, tx_hash and event_index), so that re-processing a
+// block never duplicates events.
+func InsertEventsIgnoreExisting(db xorm.Interface, events []*Event) (affected int64, err error) {
+	now := time.Now()
+	for _, event := range events {
+		result, err := db.Exec(
+			`INSERT INTO events (chain, block_height, event_index, tx_hash, tx_index, type, "from", "to", nft_id, token_addr, raw_token_id, token_id_overflow, created_at, updated_at) `+
+				`VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`+onConflictDoNothing("chain", "tx_hash", "event_index"),
+			event.Chain, event.BlockHeight, event.Index, event.TxHash, event.TxIndex, event.Type,
+			event.From, event.To, event.NFTId, event.TokenAddr, event.RawTokenId, event.TokenIdOverflow, now, now,
+		)
+		if err != nil {
+			return affected, xerrors.Errorf("error when inserting event %s: %w", event.Key(), err)
 		}
-		events = append(events, event)
+		count, _ := result.RowsAffected()
+		affected += count
 	}
+	return affected, nil
+}
 
-	affected, err := session.Insert(events)
-	l.WithField("affected", affected).Debugf("Insert finished")
-	if err != nil || int(affected) != len(logs) {
-		return nil, xerrors.Errorf("error when inserting Transfer Event to DB: %w", err)
+// UpdateEvent saves on-chain data of an event.
+func UpdateEvent(db xorm.Interface, event *Event) (err error) {
+	_, err = db.ID(event.Id).
+		Cols("block_height", "tx_index", "type", "from", "to", "nft_id", "token_addr", "raw_token_id", "token_id_overflow").
+		Update(event)
+	if err != nil {
+		return xerrors.Errorf("error when updating event %d: %w", event.Id, err)
 	}
+	return nil
+}
 
-	return events, nil
+// DeleteEvent deletes an event by id.
+func DeleteEvent(db xorm.Interface, id uint64) (err error) {
+	if _, err = db.ID(id).Delete(&Event{}); err != nil {
+		return xerrors.Errorf("error when deleting event %d: %w", id, err)
+	}
+	return nil
 }
@@ -4,8 +4,10 @@ import (
 	"fmt"
 
 	"github.com/SparkNFT/key_server/config"
+	"github.com/sirupsen/logrus"
 
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 	"xorm.io/xorm"
 )
 
@@ -20,13 +22,33 @@ func Init() {
 	}
 
 	var err error
-	Engine, err = xorm.NewEngine("postgres", config.GetDatabaseDSN())
+	switch driver := config.GetDatabaseDriver(); driver {
+	case config.DBDriverPostgres, config.DBDriverSQLite:
+		Engine, err = xorm.NewEngine(driver, config.GetDatabaseDSN())
+	default:
+		err = fmt.Errorf("unknown db.driver %q", driver)
+	}
 	if err != nil {
 		panic(fmt.Sprintf("error during init ORM: %s", err.Error()))
 	}
+	if isSQLite() {
+		// SQLite allows one writer at a time. Sharing one connection
+		// avoids "database is locked" between transactions.
+		Engine.SetMaxOpenConns(1)
+	}
+
+	if config.Get().DB.AutoMigrate {
+		if _, err = MigrateUp(0); err != nil {
+			panic(fmt.Sprintf("error during DB migration: %s", err.Error()))
+		}
+		return
+	}
 
-	err = Engine.Sync2(&Key{}, &NFT{}, &BlockLog{}, &Event{})// TODO: finish &TelegramBind{}, &TelegramGroup{}
+	pending, err := PendingMigrations()
 	if err != nil {
-		panic(fmt.Sprintf("error during DB migration: %s", err.Error()))
+		panic(fmt.Sprintf("error when checking DB migrations: %s", err.Error()))
+	}
+	if pending > 0 {
+		logrus.WithField("pending", pending).Warnf("DB schema is outdated. Run `migrate up` or start with -migrate.")
 	}
 }
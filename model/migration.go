@@ -0,0 +1,219 @@
+package model
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// Migration is a versioned schema change. Up and Down run in one
+// transaction together with the bookkeeping in `schema_migrations`.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(session *xorm.Session) error
+	Down    func(session *xorm.Session) error
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	Version   uint      `xorm:"'version' pk"`
+	Name      string    `xorm:"'name' notnull"`
+	AppliedAt time.Time `xorm:"'applied_at' notnull"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus tells if a known migration is applied.
+type MigrationStatus struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrations returns all known migrations in version order.
+func Migrations() []Migration {
+	result := make([]Migration, len(migrations))
+	copy(result, migrations)
+	if isSQLite() {
+		for i, migration := range result {
+			if replaced, ok := sqliteMigrations[migration.Version]; ok {
+				result[i] = replaced
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result
+}
+
+// execSQL makes a migration step running given statements in order.
+func execSQL(statements ...string) func(session *xorm.Session) error {
+	return func(session *xorm.Session) error {
+		for _, statement := range statements {
+			if _, err := session.Exec(statement); err != nil {
+				return xerrors.Errorf("error when executing %q: %w", statement, err)
+			}
+		}
+		return nil
+	}
+}
+
+func ensureSchemaMigrations() error {
+	_, err := Engine.Exec(
+		"CREATE TABLE IF NOT EXISTS schema_migrations (" +
+			"version BIGINT PRIMARY KEY NOT NULL, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)",
+	)
+	if err != nil {
+		return xerrors.Errorf("error when creating schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedMigrations(session *xorm.Session) (applied map[uint]SchemaMigration, err error) {
+	rows := make([]SchemaMigration, 0)
+	if err = session.Find(&rows); err != nil {
+		return nil, xerrors.Errorf("error when fetching applied migrations: %w", err)
+	}
+	applied = make(map[uint]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// MigrationStatuses lists all known migrations and whether they are
+// applied.
+func MigrationStatuses() (statuses []MigrationStatus, err error) {
+	if err = ensureSchemaMigrations(); err != nil {
+		return nil, err
+	}
+	session := Engine.NewSession()
+	defer session.Close()
+	applied, err := appliedMigrations(session)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range Migrations() {
+		row, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: row.AppliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// PendingMigrations counts migrations not applied yet.
+func PendingMigrations() (count int, err error) {
+	statuses, err := MigrationStatuses()
+	if err != nil {
+		return 0, err
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			count += 1
+		}
+	}
+	return count, nil
+}
+
+// MigrateUp applies pending migrations up to target version (all of
+// them if target is 0), in version order.
+func MigrateUp(target uint) (applied []Migration, err error) {
+	if err = ensureSchemaMigrations(); err != nil {
+		return nil, err
+	}
+	for _, migration := range Migrations() {
+		if target != 0 && migration.Version > target {
+			break
+		}
+		done, err := runMigration(migration, true)
+		if err != nil {
+			return applied, err
+		}
+		if done {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts latest `steps` applied migrations.
+func MigrateDown(steps int) (reverted []Migration, err error) {
+	if err = ensureSchemaMigrations(); err != nil {
+		return nil, err
+	}
+	all := Migrations()
+	for i := len(all) - 1; i >= 0 && len(reverted) < steps; i-- {
+		done, err := runMigration(all[i], false)
+		if err != nil {
+			return reverted, err
+		}
+		if done {
+			reverted = append(reverted, all[i])
+		}
+	}
+	return reverted, nil
+}
+
+// runMigration applies (or reverts) one migration in a transaction.
+// Instances migrating at the same time are serialized by an advisory
+// lock, and migrations already applied (or not applied) are skipped.
+func runMigration(migration Migration, up bool) (done bool, err error) {
+	l := logrus.WithFields(logrus.Fields{"model": "SchemaMigration", "version": migration.Version, "name": migration.Name})
+	session := Engine.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return false, xerrors.Errorf("%w", err)
+	}
+
+	if err = lockTransaction(session, "schema_migrations"); err != nil {
+		session.Rollback()
+		return false, xerrors.Errorf("error when locking schema_migrations: %w", err)
+	}
+	found, err := session.Where(builder.Eq{"version": migration.Version}).Exist(&SchemaMigration{})
+	if err != nil {
+		session.Rollback()
+		return false, xerrors.Errorf("error when checking migration %d: %w", migration.Version, err)
+	}
+	if found == up {
+		session.Rollback()
+		return false, nil
+	}
+
+	if up {
+		err = migration.Up(session)
+		if err == nil {
+			_, err = session.Insert(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()})
+		}
+	} else {
+		err = migration.Down(session)
+		if err == nil {
+			_, err = session.Where(builder.Eq{"version": migration.Version}).Delete(&SchemaMigration{})
+		}
+	}
+	if err != nil {
+		session.Rollback()
+		return false, xerrors.Errorf("error when migrating %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if err = session.Commit(); err != nil {
+		return false, xerrors.Errorf("%w", err)
+	}
+
+	if up {
+		l.Infof("Migration applied")
+	} else {
+		l.Infof("Migration reverted")
+	}
+	return true, nil
+}
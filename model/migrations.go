@@ -0,0 +1,180 @@
+package model
+
+import (
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+	"xorm.io/xorm"
+)
+
+// migrations are all schema changes, never edit one which has been
+// released: add a new version instead.
+//
+// Statements use IF (NOT) EXISTS, so that databases created by the old
+// Sync2-based startup can adopt these versions as they are.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_initial_tables",
+		Up: execSQL(
+			`CREATE TABLE IF NOT EXISTS "key" (`+
+				`"id" BIGSERIAL PRIMARY KEY NOT NULL, "key" VARCHAR(255) NOT NULL, "chain" VARCHAR(255) NOT NULL, `+
+				`"owner" VARCHAR(255) NULL, "n_f_t_id" BIGINT NULL, "created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_key_chain" ON "key" ("chain")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_key_owner" ON "key" ("owner")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_key_n_f_t_id" ON "key" ("n_f_t_id")`,
+
+			`CREATE TABLE IF NOT EXISTS "nft" (`+
+				`"id" BIGSERIAL PRIMARY KEY NOT NULL, "chain" VARCHAR(255) NOT NULL, "nft_id" BIGINT NOT NULL, "parent" BIGINT NULL, `+
+				`"shill_count" BIGINT DEFAULT 0 NULL, "max_shill_count" BIGINT NOT NULL, "owner" VARCHAR(255) NOT NULL, `+
+				`"token_addr" VARCHAR(255) DEFAULT '0x0' NOT NULL, "created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_nft_chain" ON "nft" ("chain")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_nft_nft_id" ON "nft" ("nft_id")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_nft_parent" ON "nft" ("parent")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_nft_owner" ON "nft" ("owner")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_nft_token_addr" ON "nft" ("token_addr")`,
+
+			`CREATE TABLE IF NOT EXISTS "block_logs" (`+
+				`"id" BIGSERIAL PRIMARY KEY NOT NULL, "chain" VARCHAR(255) NOT NULL, "block_height" BIGINT NOT NULL, `+
+				`"scanned" BOOL DEFAULT false NULL, "created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS "UQE_block_logs_chain_height" ON "block_logs" ("chain", "block_height")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_block_logs_scanned" ON "block_logs" ("scanned")`,
+
+			`CREATE TABLE IF NOT EXISTS "events" (`+
+				`"id" BIGSERIAL PRIMARY KEY NOT NULL, "chain" VARCHAR(255) NOT NULL, "block_height" BIGINT NOT NULL, `+
+				`"event_index" BIGINT NULL, "tx_hash" VARCHAR(255) NULL, "tx_index" BIGINT NULL, "type" VARCHAR(255) NOT NULL, `+
+				`"from" VARCHAR(255) NOT NULL, "to" VARCHAR(255) NOT NULL, "nft_id" BIGINT NOT NULL, "token_addr" VARCHAR(255) NULL, `+
+				`"created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_chain" ON "events" ("chain")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_block_height" ON "events" ("block_height")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_type" ON "events" ("type")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_from" ON "events" ("from")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_to" ON "events" ("to")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_nft_id" ON "events" ("nft_id")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_token_addr" ON "events" ("token_addr")`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS "events"`,
+			`DROP TABLE IF EXISTS "block_logs"`,
+			`DROP TABLE IF EXISTS "nft"`,
+			`DROP TABLE IF EXISTS "key"`,
+		),
+	},
+	{
+		Version: 2,
+		Name:    "create_telegram_tables",
+		Up: execSQL(
+			`CREATE TABLE IF NOT EXISTS "telegram_bind" (`+
+				`"id" BIGSERIAL PRIMARY KEY NOT NULL, "erc20_name" VARCHAR(255) NOT NULL, "erc20_symbol" VARCHAR(255) NOT NULL, `+
+				`"erc20_address" VARCHAR(255) NOT NULL, "admin_id" BIGINT NOT NULL, "created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_telegram_bind_admin_id" ON "telegram_bind" ("admin_id")`,
+
+			`CREATE TABLE IF NOT EXISTS "telegram_group" (`+
+				`"id" BIGSERIAL PRIMARY KEY NOT NULL, "tg_bind_id" BIGINT NULL, "chat_id" BIGINT NULL, "chat_title" VARCHAR(255) NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_telegram_group_tg_bind_id" ON "telegram_group" ("tg_bind_id")`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS "telegram_group"`,
+			`DROP TABLE IF EXISTS "telegram_bind"`,
+		),
+	},
+	{
+		Version: 3,
+		Name:    "create_rate_limit_buckets",
+		Up: execSQL(
+			`CREATE TABLE IF NOT EXISTS "rate_limit_buckets" (`+
+				`"bucket_key" VARCHAR(255) PRIMARY KEY NOT NULL, "tokens" DOUBLE PRECISION NOT NULL, "refilled_at" TIMESTAMP NOT NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_rate_limit_buckets_refilled_at" ON "rate_limit_buckets" ("refilled_at")`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS "rate_limit_buckets"`,
+		),
+	},
+	{
+		Version: 4,
+		Name:    "create_checkpoints",
+		Up: execSQL(
+			`CREATE TABLE IF NOT EXISTS "checkpoints" (` +
+				`"chain" VARCHAR(64) PRIMARY KEY NOT NULL, "block_height" BIGINT NOT NULL, "block_hash" VARCHAR(255) NOT NULL, ` +
+				`"created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS "checkpoints"`,
+		),
+	},
+	{
+		Version: 5,
+		Name:    "unique_events",
+		Up:      uniqueEvents,
+		Down: execSQL(
+			`DROP INDEX IF EXISTS "UQE_events_chain_tx_event"`,
+		),
+	},
+	{
+		Version: 6,
+		Name:    "numeric_token_ids",
+		Up: execSQL(
+			`ALTER TABLE "nft" ALTER COLUMN "nft_id" TYPE NUMERIC(78, 0)`,
+			`ALTER TABLE "nft" ALTER COLUMN "parent" TYPE NUMERIC(78, 0)`,
+			`ALTER TABLE "events" ALTER COLUMN "nft_id" TYPE NUMERIC(78, 0)`,
+			`ALTER TABLE "key" ALTER COLUMN "n_f_t_id" TYPE NUMERIC(78, 0)`,
+			`ALTER TABLE "events" ADD COLUMN IF NOT EXISTS "raw_token_id" VARCHAR(78) NULL`,
+			`ALTER TABLE "events" ADD COLUMN IF NOT EXISTS "token_id_overflow" BOOL DEFAULT false NOT NULL`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_token_id_overflow" ON "events" ("token_id_overflow")`,
+		),
+		// Fails if any id above 2^63 has been saved.
+		Down: execSQL(
+			`DROP INDEX IF EXISTS "IDX_events_token_id_overflow"`,
+			`ALTER TABLE "events" DROP COLUMN IF EXISTS "token_id_overflow"`,
+			`ALTER TABLE "events" DROP COLUMN IF EXISTS "raw_token_id"`,
+			`ALTER TABLE "key" ALTER COLUMN "n_f_t_id" TYPE BIGINT`,
+			`ALTER TABLE "events" ALTER COLUMN "nft_id" TYPE BIGINT`,
+			`ALTER TABLE "nft" ALTER COLUMN "parent" TYPE BIGINT`,
+			`ALTER TABLE "nft" ALTER COLUMN "nft_id" TYPE BIGINT`,
+		),
+	},
+	{
+		Version: 7,
+		Name:    "create_erc20_tokens",
+		Up: execSQL(
+			`CREATE TABLE IF NOT EXISTS "erc20_tokens" (` +
+				`"chain" VARCHAR(64) NOT NULL, "address" VARCHAR(42) NOT NULL, "name" VARCHAR(255) NOT NULL, ` +
+				`"symbol" VARCHAR(255) NOT NULL, "decimals" SMALLINT NOT NULL, "total_supply" VARCHAR(78) NOT NULL, ` +
+				`"created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL, PRIMARY KEY ("chain", "address"))`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS "erc20_tokens"`,
+		),
+	},
+	{
+		Version: 8,
+		Name:    "create_telegram_cursors",
+		Up: execSQL(
+			`CREATE TABLE IF NOT EXISTS "telegram_cursors" (` +
+				`"telegram_group_id" BIGINT NOT NULL, "block_height" BIGINT NOT NULL, "event_index" INTEGER NOT NULL, ` +
+				`"created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL, PRIMARY KEY ("telegram_group_id"))`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS "telegram_cursors"`,
+		),
+	},
+}
+
+// uniqueEvents deletes duplicated events (same chain, tx_hash and
+// event_index), keeping the earliest row, then creates unique index
+// `chain_tx_event`.
+func uniqueEvents(session *xorm.Session) error {
+	result, err := session.Exec(deleteUsing(
+		"events",
+		"a.id > b.id AND a.chain = b.chain AND a.tx_hash = b.tx_hash AND a.event_index = b.event_index",
+	))
+	if err != nil {
+		return xerrors.Errorf("error when deleting duplicated events: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		logrus.WithFields(logrus.Fields{"model": "Event", "affected": affected}).Warnf("Duplicated events deleted")
+	}
+
+	return execSQL(
+		`CREATE UNIQUE INDEX IF NOT EXISTS "UQE_events_chain_tx_event" ON "events" ("chain", "event_index", "tx_hash")`,
+	)(session)
+}
@@ -0,0 +1,88 @@
+package model
+
+// sqliteMigrations replace migrations whose SQL SQLite does not
+// understand. SQLite databases are always created from scratch, and
+// SQLite can't change column types: token id columns are text from the
+// beginning, since a NUMERIC or BIGINT column turns ids beyond int64 into
+// floats.
+var sqliteMigrations = map[uint]Migration{
+	1: {
+		Version: 1,
+		Name:    "create_initial_tables",
+		Up: execSQL(
+			`CREATE TABLE IF NOT EXISTS "key" (`+
+				`"id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, "key" VARCHAR(255) NOT NULL, "chain" VARCHAR(255) NOT NULL, `+
+				`"owner" VARCHAR(255) NULL, "n_f_t_id" VARCHAR(78) NULL, "created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_key_chain" ON "key" ("chain")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_key_owner" ON "key" ("owner")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_key_n_f_t_id" ON "key" ("n_f_t_id")`,
+
+			`CREATE TABLE IF NOT EXISTS "nft" (`+
+				`"id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, "chain" VARCHAR(255) NOT NULL, "nft_id" VARCHAR(78) NOT NULL, "parent" VARCHAR(78) NULL, `+
+				`"shill_count" BIGINT DEFAULT 0 NULL, "max_shill_count" BIGINT NOT NULL, "owner" VARCHAR(255) NOT NULL, `+
+				`"token_addr" VARCHAR(255) DEFAULT '0x0' NOT NULL, "created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_nft_chain" ON "nft" ("chain")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_nft_nft_id" ON "nft" ("nft_id")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_nft_parent" ON "nft" ("parent")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_nft_owner" ON "nft" ("owner")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_nft_token_addr" ON "nft" ("token_addr")`,
+
+			`CREATE TABLE IF NOT EXISTS "block_logs" (`+
+				`"id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, "chain" VARCHAR(255) NOT NULL, "block_height" BIGINT NOT NULL, `+
+				`"scanned" BOOL DEFAULT false NULL, "created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS "UQE_block_logs_chain_height" ON "block_logs" ("chain", "block_height")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_block_logs_scanned" ON "block_logs" ("scanned")`,
+
+			`CREATE TABLE IF NOT EXISTS "events" (`+
+				`"id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, "chain" VARCHAR(255) NOT NULL, "block_height" BIGINT NOT NULL, `+
+				`"event_index" BIGINT NULL, "tx_hash" VARCHAR(255) NULL, "tx_index" BIGINT NULL, "type" VARCHAR(255) NOT NULL, `+
+				`"from" VARCHAR(255) NOT NULL, "to" VARCHAR(255) NOT NULL, "nft_id" VARCHAR(78) NOT NULL, "token_addr" VARCHAR(255) NULL, `+
+				`"created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_chain" ON "events" ("chain")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_block_height" ON "events" ("block_height")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_type" ON "events" ("type")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_from" ON "events" ("from")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_to" ON "events" ("to")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_nft_id" ON "events" ("nft_id")`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_token_addr" ON "events" ("token_addr")`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS "events"`,
+			`DROP TABLE IF EXISTS "block_logs"`,
+			`DROP TABLE IF EXISTS "nft"`,
+			`DROP TABLE IF EXISTS "key"`,
+		),
+	},
+	2: {
+		Version: 2,
+		Name:    "create_telegram_tables",
+		Up: execSQL(
+			`CREATE TABLE IF NOT EXISTS "telegram_bind" (`+
+				`"id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, "erc20_name" VARCHAR(255) NOT NULL, "erc20_symbol" VARCHAR(255) NOT NULL, `+
+				`"erc20_address" VARCHAR(255) NOT NULL, "admin_id" BIGINT NOT NULL, "created_at" TIMESTAMP NULL, "updated_at" TIMESTAMP NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_telegram_bind_admin_id" ON "telegram_bind" ("admin_id")`,
+
+			`CREATE TABLE IF NOT EXISTS "telegram_group" (`+
+				`"id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, "tg_bind_id" BIGINT NULL, "chat_id" BIGINT NULL, "chat_title" VARCHAR(255) NULL)`,
+			`CREATE INDEX IF NOT EXISTS "IDX_telegram_group_tg_bind_id" ON "telegram_group" ("tg_bind_id")`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS "telegram_group"`,
+			`DROP TABLE IF EXISTS "telegram_bind"`,
+		),
+	},
+	6: {
+		Version: 6,
+		Name:    "numeric_token_ids",
+		Up: execSQL(
+			`ALTER TABLE "events" ADD COLUMN "raw_token_id" VARCHAR(78) NULL`,
+			`ALTER TABLE "events" ADD COLUMN "token_id_overflow" BOOL DEFAULT false NOT NULL`,
+			`CREATE INDEX IF NOT EXISTS "IDX_events_token_id_overflow" ON "events" ("token_id_overflow")`,
+		),
+		Down: execSQL(
+			`DROP INDEX IF EXISTS "IDX_events_token_id_overflow"`,
+			`ALTER TABLE "events" DROP COLUMN "token_id_overflow"`,
+			`ALTER TABLE "events" DROP COLUMN "raw_token_id"`,
+		),
+	},
+}
@@ -1,22 +1,22 @@
 package model
 
 import (
-	"strconv"
 	"time"
 
 	"golang.org/x/xerrors"
 	"xorm.io/builder"
+	"xorm.io/xorm"
 )
 
 type NFT struct {
-	Id            uint64 `xorm:"BIGINT pk autoincr"`
-	Chain         string `xorm:"'chain' index notnull"`
-	NFTID         uint64 `xorm:"'nft_id' index notnull"`
-	Parent        uint64 `xorm:"'parent' index"`
-	ShillCount    uint16 `xorm:"'shill_count' default(0)"`
-	MaxShillCount uint16 `xorm:"'max_shill_count' notnull"`
-	Owner         string `xorm:"'owner' index notnull"`
-	TokenAddr     string `xorm:"'token_addr' index notnull default('0x0')"`
+	Id            uint64  `xorm:"BIGINT pk autoincr"`
+	Chain         string  `xorm:"'chain' index notnull"`
+	NFTID         TokenId `xorm:"'nft_id' NUMERIC(78,0) index notnull"`
+	Parent        TokenId `xorm:"'parent' NUMERIC(78,0) index"`
+	ShillCount    uint16  `xorm:"'shill_count' default(0)"`
+	MaxShillCount uint16  `xorm:"'max_shill_count' notnull"`
+	Owner         string  `xorm:"'owner' index notnull"`
+	TokenAddr     string  `xorm:"'token_addr' index notnull default('0x0')"`
 
 	CreatedAt time.Time `xorm:"created 'created_at'"`
 	UpdatedAt time.Time `xorm:"updated 'updated_at'"`
@@ -33,147 +33,90 @@ func (NFT) TableName() string {
 
 // `NFTId = (IssueId(32bit) << 32) | EditionId(32bit)`
 func (nft NFT) IssueId() uint32 {
-	return uint32(nft.NFTID >> 32)
+	return nft.NFTID.IssueId()
 }
 
 // `NFTId = (IssueId(32bit) << 32) | EditionId(32bit)`
 func (nft NFT) EditionId() uint32 {
-	return uint32(nft.NFTID)
+	return nft.NFTID.EditionId()
 }
 
 func (nft NFT) IsRoot() bool {
 	return nft.EditionId() == uint32(1)
 }
 
-func (nft NFT) Children() (children []*NFT, err error) {
-	children = make([]*NFT, 0, 10)
-	err = Engine.Where(builder.Eq{"parent": nft.NFTID, "chain": nft.Chain}).Find(&children)
-	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
-	}
-	return children, nil
-}
-
-func (nft NFT) ChildrenCount() (count int64) {
-	count, _ = Engine.Where(builder.Eq{"chain": nft.Chain, "parent": nft.NFTID}).Count(&NFT{})
-	return count
-}
-
 // CanShill detects if a NFT can be shilled.
 func (nft NFT) CanShill() (result bool) {
 	return nft.MaxShillCount > nft.ShillCount
 }
 
 // FindNFT returns a NFT instance by nft_id.
-func FindNFT(chainName string, nft_id uint64) (nft *NFT, err error) {
+func FindNFT(db xorm.Interface, chainName string, nft_id TokenId) (nft *NFT, err error) {
 	nft = &NFT{Chain: chainName, NFTID: nft_id}
-	found, err := Engine.Get(nft)
+	found, err := db.Get(nft)
 	if err != nil {
 		return nil, xerrors.Errorf("%w", err)
 	}
 
 	if !found {
-		return nil, xerrors.Errorf("NFT not found for nft_id %d", nft_id)
+		return nil, xerrors.Errorf("%w for nft_id %s", ErrNFTNotFound, nft_id)
 	}
 
 	return nft, nil
 }
 
-func ChildrenCount(chainName string, nft_id uint64) (count int, err error) {
-	// children := make([]uint64, 0, 10)
-	children := []uint64{nft_id}
-	count = 0
-
-	for len(children) != 0 {
-		children_instances := make([]NFT, 0, 10)
-		err := Engine.Where(builder.In("parent", children).And(builder.Eq{"chain": chainName})).Select("nft_id").Find(&children_instances)
-		if err != nil {
-			return 0, xerrors.Errorf("%w", err)
-		}
-		children = make([]uint64, 0, 10)
-		for _, nft := range children_instances {
-			children = append(children, nft.NFTID)
-		}
-		count += len(children)
+// FindNFTsByOwner returns all NFTs of a chain owned by given address.
+func FindNFTsByOwner(db xorm.Interface, chainName string, owner string) (nfts []*NFT, err error) {
+	nfts = make([]*NFT, 0, 10)
+	err = db.Where(builder.Eq{"owner": owner, "chain": chainName}).Asc("id").Find(&nfts)
+	if err != nil {
+		return nil, xerrors.Errorf("error when finding NFTs of %s: %w", owner, err)
 	}
-
-	return count, nil
+	return nfts, nil
 }
 
-func ChildrenTree(chainName string, nftId uint64) (tree *NFTTree, err error) {
-	root := &NFTTree{
-		NFTID:    strconv.FormatUint(nftId, 10),
-		Children: []*NFTTree{},
-	}
-	traverseList := make([]*NFTTree, 0, 20)
-	traverseList = append(traverseList, root)
-
-	for i := 0; i < len(traverseList); i++ {
-		children := make([]NFT, 0, 10)
-		current := traverseList[i]
-		nft_id, err := strconv.ParseUint(current.NFTID, 10, 0)
-		if err != nil {
-			return nil, xerrors.Errorf("%w", err)
-		}
-		err = Engine.Where(builder.Eq{"parent": nft_id, "chain": chainName}).Select("nft_id").Find(&children)
-		if err != nil {
-			return nil, xerrors.Errorf("%w", err)
-		}
-		for _, nft := range children {
-			leaf := &NFTTree{
-				NFTID:    strconv.FormatUint(nft.NFTID, 10),
-				Children: []*NFTTree{},
-			}
-			current.Children = append(current.Children, leaf)
-			traverseList = append(traverseList, leaf)
-		}
+// FindNFTChildren returns direct children of given NFTs, in one query.
+func FindNFTChildren(db xorm.Interface, chainName string, parents []TokenId) (children []*NFT, err error) {
+	children = make([]*NFT, 0, 10)
+	if len(parents) == 0 {
+		return children, nil
 	}
-
-	return root, nil
-}
-
-// Suggest suggests next buyable NFT if current NFT is full-shilled (recursively), returns an NFT w/ same owner in children.
-func (nft *NFT) Suggest(base_nft *NFT) (next_nft *NFT, err error) {
-	// Beginning of recursive
-	if base_nft == nil {
-		base_nft = nft
+	values := make([]interface{}, 0, len(parents))
+	for _, parent := range parents {
+		values = append(values, parent)
 	}
-
-	// Current NFT has room to shill. Return itself.
-	if nft.CanShill() && nft.Owner == base_nft.Owner {
-		return nft, nil
+	err = db.Where(builder.In("parent", values...).And(builder.Eq{"chain": chainName})).Asc("id").Find(&children)
+	if err != nil {
+		return nil, xerrors.Errorf("error when finding children of %d NFTs: %w", len(parents), err)
 	}
+	return children, nil
+}
 
-	children, err := nft.Children()
+// CreateNFTs inserts NFTs.
+func CreateNFTs(db xorm.Interface, nfts []*NFT) (err error) {
+	affected, err := db.Insert(&nfts)
 	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
+		return xerrors.Errorf("error when inserting NFT record: %w", err)
 	}
-
-	// Breadth-first search. Strict check Owner for maximum owner profit.
-	for _, child := range children {
-		if child.CanShill() && child.Owner == base_nft.Owner {
-			return child, nil
-		}
+	if int(affected) != len(nfts) {
+		return xerrors.Errorf("error when Inserting NFT Record: records inserted mismatch total length: %d - %d", affected, len(nfts))
 	}
+	return nil
+}
 
-	// Search again, emit owner check.
-	for _, child := range children {
-		if child.CanShill() {
-			return child, nil
-		}
+// UpdateNFT saves given columns of a NFT.
+func UpdateNFT(db xorm.Interface, nft *NFT, cols ...string) (err error) {
+	_, err = db.ID(nft.Id).Cols(cols...).Update(nft)
+	if err != nil {
+		return xerrors.Errorf("error when updating NFT %s: %w", nft.NFTID, err)
 	}
+	return nil
+}
 
-	// Current depth not found. Start recursion.
-	for _, child := range children {
-		next_nft, err = child.Suggest(base_nft)
-		if err != nil {
-			return nil, err
-		}
-		if next_nft != nil {
-			return next_nft, nil
-		}
+// DeleteNFT deletes a NFT by nft_id.
+func DeleteNFT(db xorm.Interface, chainName string, nft_id TokenId) (err error) {
+	if _, err = db.Where(builder.Eq{"chain": chainName, "nft_id": nft_id}).Delete(&NFT{}); err != nil {
+		return xerrors.Errorf("error when deleting NFT %s: %w", nft_id, err)
 	}
-
-	// Not found.
-	return nil, nil
+	return nil
 }
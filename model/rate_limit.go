@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"golang.org/x/xerrors"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// RateLimitBucket is the persisted state of a token bucket. Shared by
+// all server instances using the Postgres rate limit store.
+type RateLimitBucket struct {
+	BucketKey  string    `xorm:"'bucket_key' pk varchar(255)"`
+	Tokens     float64   `xorm:"'tokens' notnull"`
+	RefilledAt time.Time `xorm:"'refilled_at' notnull index"`
+}
+
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// RateLimitBucketUpdate locks bucket of given key, calls fn to modify
+// it and saves the result, all in one transaction. A bucket is created
+// with initial value if not exists yet.
+func RateLimitBucketUpdate(engine *xorm.Engine, key string, initial RateLimitBucket, fn func(bucket *RateLimitBucket)) (err error) {
+	session := engine.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return xerrors.Errorf("%w", err)
+	}
+
+	_, err = session.Exec(
+		"INSERT INTO rate_limit_buckets (bucket_key, tokens, refilled_at) VALUES (?, ?, ?)"+onConflictDoNothing("bucket_key"),
+		key, initial.Tokens, initial.RefilledAt,
+	)
+	if err != nil {
+		session.Rollback()
+		return xerrors.Errorf("error when creating rate limit bucket: %w", err)
+	}
+
+	bucket := &RateLimitBucket{}
+	found, err := forUpdate(session).Where(builder.Eq{"bucket_key": key}).Get(bucket)
+	if err != nil {
+		session.Rollback()
+		return xerrors.Errorf("error when locking rate limit bucket: %w", err)
+	}
+	if !found {
+		session.Rollback()
+		return xerrors.Errorf("rate limit bucket %s vanished", key)
+	}
+
+	fn(bucket)
+	_, err = session.Where(builder.Eq{"bucket_key": key}).Cols("tokens", "refilled_at").Update(bucket)
+	if err != nil {
+		session.Rollback()
+		return xerrors.Errorf("error when saving rate limit bucket: %w", err)
+	}
+
+	return session.Commit()
+}
+
+// RateLimitBucketClean deletes buckets not touched since given time.
+func RateLimitBucketClean(db xorm.Interface, before time.Time) (affected int64, err error) {
+	affected, err = db.Where(builder.Lt{"refilled_at": before}).Delete(&RateLimitBucket{})
+	if err != nil {
+		return 0, xerrors.Errorf("error when cleaning rate limit buckets: %w", err)
+	}
+	return affected, nil
+}
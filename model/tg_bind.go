@@ -8,6 +8,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"golang.org/x/xerrors"
 	"xorm.io/builder"
+	"xorm.io/xorm"
 )
 
 type TelegramBind struct {
@@ -26,23 +27,23 @@ func (TelegramBind) TableName() string {
 }
 
 func (tb *TelegramBind) TokenScanURL() (result string, err error) {
-	url, err := url.Parse(config.C.Telegram.BlockViewerURLBase + "/address/" + tb.ERC20Address)
+	url, err := url.Parse(config.Get().Telegram.BlockViewerURLBase + "/address/" + tb.ERC20Address)
 	if err != nil {
 		return "", xerrors.Errorf("%w", err)
 	}
 	return url.String(), nil
 }
 
-func (tb *TelegramBind) TelegramGroups() (result []*TelegramGroup, err error) {
+func (tb *TelegramBind) TelegramGroups(db xorm.Interface) (result []*TelegramGroup, err error) {
 	result = make([]*TelegramGroup, 0)
-	err = Engine.Where(builder.Eq{"tg_bind_id": tb.Id}).Find(&result)
+	err = db.Where(builder.Eq{"tg_bind_id": tb.Id}).Find(&result)
 	if err != nil {
 		return nil, xerrors.Errorf("%w", err)
 	}
 	return result, nil
 }
 
-func CreateTGBind(adminID int64, erc20addr, erc20name, erc20symbol string) (instance *TelegramBind, err error) {
+func CreateTGBind(db xorm.Interface, adminID int64, erc20addr, erc20name, erc20symbol string) (instance *TelegramBind, err error) {
 	instance = &TelegramBind{
 		AdminID:      adminID,
 		ERC20Address: erc20addr,
@@ -50,9 +51,9 @@ func CreateTGBind(adminID int64, erc20addr, erc20name, erc20symbol string) (inst
 		ERC20Symbol:  erc20symbol,
 	}
 	if !common.IsHexAddress(erc20addr) {
-		return nil, xerrors.Errorf("invalid address: %s", erc20addr)
+		return nil, xerrors.Errorf("%w: %s", ErrInvalidAddress, erc20addr)
 	}
-	count, err := Engine.Insert(instance)
+	count, err := db.Insert(instance)
 	if err != nil {
 		return nil, xerrors.Errorf("%w", err)
 	}
@@ -64,21 +65,21 @@ func CreateTGBind(adminID int64, erc20addr, erc20name, erc20symbol string) (inst
 }
 
 // FindTGBindBy returns a slice of results for given condition struct
-func FindTGBindBy(condition *TelegramBind) (results []*TelegramBind, err error) {
+func FindTGBindBy(db xorm.Interface, condition *TelegramBind) (results []*TelegramBind, err error) {
 	results = make([]*TelegramBind, 0)
-	err = Engine.Find(&results, condition)
+	err = db.Find(&results, condition)
 	if err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
-func DeleteTGBind(binds []*TelegramBind) (affected int64, err error) {
+func DeleteTGBind(db xorm.Interface, binds []*TelegramBind) (affected int64, err error) {
 	ids := make([]uint64, 0)
 	for _, tb := range binds {
 		ids = append(ids, tb.Id)
-		DeleteTGGroupByBind(tb)
+		DeleteTGGroupByBind(db, tb)
 	}
 
-	return Engine.Where(builder.In("id", ids)).Delete(&TelegramBind{})
+	return db.Where(builder.In("id", ids)).Delete(&TelegramBind{})
 }
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"golang.org/x/xerrors"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// TelegramCursor is where announcing Publish events to a TelegramGroup
+// resumes, by position on chain. Events before it are announced
+// already.
+type TelegramCursor struct {
+	GroupID     uint64 `xorm:"'telegram_group_id' BIGINT pk"`
+	BlockHeight uint64 `xorm:"'block_height' notnull"`
+	EventIndex  uint   `xorm:"'event_index' notnull"`
+
+	CreatedAt time.Time `xorm:"'created_at' created"`
+	UpdatedAt time.Time `xorm:"'updated_at' updated"`
+}
+
+func (TelegramCursor) TableName() string {
+	return "telegram_cursors"
+}
+
+// Passed tells if event is before the cursor.
+func (cursor TelegramCursor) Passed(event *Event) bool {
+	return event.BlockHeight < cursor.BlockHeight ||
+		(event.BlockHeight == cursor.BlockHeight && event.Index < cursor.EventIndex)
+}
+
+// TGCursorSave creates or moves the cursor of a group.
+func TGCursorSave(db xorm.Interface, cursor *TelegramCursor) (err error) {
+	now := time.Now()
+	_, err = db.Exec(
+		"INSERT INTO telegram_cursors (telegram_group_id, block_height, event_index, created_at, updated_at) VALUES (?, ?, ?, ?, ?)"+
+			onConflictUpdate([]string{"telegram_group_id"}, "block_height", "event_index", "updated_at"),
+		cursor.GroupID, cursor.BlockHeight, cursor.EventIndex, now, now,
+	)
+	if err != nil {
+		return xerrors.Errorf("error when saving cursor of TelegramGroup %d: %w", cursor.GroupID, err)
+	}
+	cursor.UpdatedAt = now
+	return nil
+}
+
+// TGCursorFind returns cursor of a group.
+func TGCursorFind(db xorm.Interface, group_id uint64) (result *TelegramCursor, err error) {
+	result = &TelegramCursor{}
+	found, err := db.Where(builder.Eq{"telegram_group_id": group_id}).Get(result)
+	if err != nil {
+		return nil, xerrors.Errorf("error when finding cursor of TelegramGroup %d: %w", group_id, err)
+	}
+	if !found {
+		return nil, xerrors.Errorf("%w: %d", ErrTGCursorNotFound, group_id)
+	}
+	return result, nil
+}
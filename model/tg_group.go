@@ -3,6 +3,7 @@ package model
 import (
 	"golang.org/x/xerrors"
 	"xorm.io/builder"
+	"xorm.io/xorm"
 )
 
 type TelegramGroup struct {
@@ -16,9 +17,9 @@ func (TelegramGroup) TableName() string {
 	return "telegram_group"
 }
 
-func (tg *TelegramGroup) TelegramBind() (result *TelegramBind, err error) {
+func (tg *TelegramGroup) TelegramBind(db xorm.Interface) (result *TelegramBind, err error) {
 	result = &TelegramBind{}
-	found, err := Engine.Where(builder.Eq{"id": tg.Id}).Get(result)
+	found, err := db.Where(builder.Eq{"id": tg.TGBindID}).Get(result)
 	if err != nil {
 		return nil, xerrors.Errorf("%w", err)
 	}
@@ -28,14 +29,14 @@ func (tg *TelegramGroup) TelegramBind() (result *TelegramBind, err error) {
 	return result, nil
 }
 
-func CreateTGGroup(bind *TelegramBind, chatId int64, chatTitle string) (group *TelegramGroup, err error) {
+func CreateTGGroup(db xorm.Interface, bind *TelegramBind, chatId int64, chatTitle string) (group *TelegramGroup, err error) {
 	group = &TelegramGroup{
 		TGBindID: bind.Id,
 		ChatID: chatId,
 		ChatTitle: chatTitle,
 	}
 
-	affected, err := Engine.Insert(group)
+	affected, err := db.Insert(group)
 	if err != nil {
 		return nil, xerrors.Errorf("%w", err)
 	}
@@ -45,6 +46,10 @@ func CreateTGGroup(bind *TelegramBind, chatId int64, chatTitle string) (group *T
 	return group, nil
 }
 
-func DeleteTGGroupByBind(bind *TelegramBind) (affected int64, err error) {
-	return Engine.Where(builder.Eq{"tg_bind_id": bind.Id}).Delete(&TelegramGroup{})
+func DeleteTGGroupByBind(db xorm.Interface, bind *TelegramBind) (affected int64, err error) {
+	groups := builder.Select("id").From("telegram_group").Where(builder.Eq{"tg_bind_id": bind.Id})
+	if _, err := db.Where(builder.In("telegram_group_id", groups)).Delete(&TelegramCursor{}); err != nil {
+		return 0, xerrors.Errorf("error when deleting cursors of TelegramBind %d: %w", bind.Id, err)
+	}
+	return db.Where(builder.Eq{"tg_bind_id": bind.Id}).Delete(&TelegramGroup{})
 }
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"database/sql/driver"
+	"math/big"
+	"strconv"
+
+	"golang.org/x/xerrors"
+)
+
+// TokenId is an NFT id. SparkLink ids are uint64, while ERC-721
+// Transfer carries uint256: ids beyond uint64 are rejected by
+// ParseTokenId and TokenIdFromBig instead of being truncated.
+//
+// Stored as numeric(78, 0) in decimal, since lib/pq refuses uint64
+// parameters with the high bit set.
+type TokenId uint64
+
+// ParseTokenId parses a decimal token id.
+func ParseTokenId(s string) (TokenId, error) {
+	id, ok := new(big.Int).SetString(s, 10)
+	if !ok || id.Sign() < 0 {
+		return 0, xerrors.Errorf("%w: %q", ErrTokenIdInvalid, s)
+	}
+	return TokenIdFromBig(id)
+}
+
+// TokenIdFromBig converts a uint256 token id from chain.
+func TokenIdFromBig(id *big.Int) (TokenId, error) {
+	if id == nil || id.Sign() < 0 {
+		return 0, xerrors.Errorf("%w: %v", ErrTokenIdInvalid, id)
+	}
+	if !id.IsUint64() {
+		return 0, xerrors.Errorf("%w: %s", ErrTokenIdOutOfRange, id.String())
+	}
+	return TokenId(id.Uint64()), nil
+}
+
+func (id TokenId) Big() *big.Int {
+	return new(big.Int).SetUint64(uint64(id))
+}
+
+func (id TokenId) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// IssueId returns higher 32 bits.
+// `NFTId = (IssueId(32bit) << 32) | EditionId(32bit)`
+func (id TokenId) IssueId() uint32 {
+	return uint32(id >> 32)
+}
+
+// EditionId returns lower 32 bits.
+// `NFTId = (IssueId(32bit) << 32) | EditionId(32bit)`
+func (id TokenId) EditionId() uint32 {
+	return uint32(id)
+}
+
+// ToDB implements xorm convert.Conversion.
+func (id *TokenId) ToDB() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// FromDB implements xorm convert.Conversion.
+func (id *TokenId) FromDB(data []byte) error {
+	parsed, err := ParseTokenId(string(data))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, so that TokenId can be used as a
+// query parameter.
+func (id TokenId) Value() (driver.Value, error) {
+	return id.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (id *TokenId) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case []byte:
+		return id.FromDB(value)
+	case string:
+		return id.FromDB([]byte(value))
+	case int64:
+		if value < 0 {
+			return xerrors.Errorf("%w: %d", ErrTokenIdInvalid, value)
+		}
+		*id = TokenId(value)
+		return nil
+	default:
+		return xerrors.Errorf("%w: unsupported type %T", ErrTokenIdInvalid, src)
+	}
+}
@@ -2,6 +2,7 @@ package pinata
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/metrics"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/xerrors"
 )
@@ -35,10 +37,18 @@ var (
 	log = logrus.New().WithFields(logrus.Fields{
 		"worker": "pinata",
 	})
+
+	// ErrUnavailable is returned when Pinata API cannot be reached.
+	ErrUnavailable = xerrors.New("pinata unavailable")
+	// ErrUnauthorized is returned when Pinata rejects our credentials.
+	ErrUnauthorized = xerrors.New("pinata unauthorized")
+	// ErrRequestFailed is returned when Pinata responds with other non-2xx status.
+	ErrRequestFailed = xerrors.New("pinata request failed")
 )
 
 // GenerateAPIKey generates Pinata API key.
 func GenerateAPIKey(chain string, nft_id uint64) (result *GenerateAPIKeyResponse, err error) {
+	defer observe("generateApiKey", time.Now(), &err)
 	name := fmt.Sprintf(KEY_NAME_TEMPLATE, chain, nft_id)
 	request := h{
 		"keyName": name,
@@ -53,16 +63,17 @@ func GenerateAPIKey(chain string, nft_id uint64) (result *GenerateAPIKeyResponse
 		},
 	}
 
-	response, err := apiRequest("POST", "/users/generateApiKey", &request)
+	response, err := apiRequest(context.Background(), "POST", "/users/generateApiKey", &request)
 	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
+		return nil, xerrors.Errorf("%w: %s", ErrUnavailable, err.Error())
 	}
+	defer response.Body.Close()
 	body_bytes, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
+		return nil, xerrors.Errorf("%w: %s", ErrUnavailable, err.Error())
 	}
-	if (response.StatusCode != 200) && (response.StatusCode != 201) {
-		return nil, xerrors.Errorf(string(body_bytes))
+	if err = checkStatus(response.StatusCode, body_bytes); err != nil {
+		return nil, err
 	}
 
 	body := &GenerateAPIKeyResponse{}
@@ -76,16 +87,21 @@ func GenerateAPIKey(chain string, nft_id uint64) (result *GenerateAPIKeyResponse
 
 // RevokeAPIKey revokes given API Key from Pinata.
 func RevokeAPIKey(api_key string) bool {
+	var err error
+	defer observe("revokeApiKey", time.Now(), &err)
+
 	request := h{
 		"apiKey": api_key,
 	}
-	response, err := apiRequest("PUT", "/users/revokeApiKey", &request)
+	response, err := apiRequest(context.Background(), "PUT", "/users/revokeApiKey", &request)
 	if err != nil {
+		err = xerrors.Errorf("%w: %s", ErrUnavailable, err.Error())
 		return false
 	}
+	defer response.Body.Close()
 	body, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 201 {
-		log.WithField("func", "RevokeAPIKey").Warnf("%s", string(body))
+	if err = checkStatus(response.StatusCode, body); err != nil {
+		log.WithField("func", "RevokeAPIKey").Warnf("%s", err.Error())
 		return false
 	}
 
@@ -93,24 +109,78 @@ func RevokeAPIKey(api_key string) bool {
 
 }
 
-func apiRequest(method, endpoint string, body_struct *h) (response *http.Response, err error) {
+// TestAuthentication checks if configured credentials are accepted by
+// Pinata. The request is given up once ctx is done.
+func TestAuthentication(ctx context.Context) (err error) {
+	defer observe("testAuthentication", time.Now(), &err)
+
+	response, err := apiRequest(ctx, "GET", "/data/testAuthentication", nil)
+	if err != nil {
+		return xerrors.Errorf("%w: %s", ErrUnavailable, err.Error())
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return xerrors.Errorf("%w: %s", ErrUnavailable, err.Error())
+	}
+	return checkStatus(response.StatusCode, body)
+}
+
+// observe records latency and error reason of a Pinata API call.
+func observe(endpoint string, start time.Time, err *error) {
+	metrics.PinataRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	if *err == nil {
+		return
+	}
+
+	reason := "invalid_response"
+	switch {
+	case xerrors.Is(*err, ErrUnavailable):
+		reason = "unavailable"
+	case xerrors.Is(*err, ErrUnauthorized):
+		reason = "unauthorized"
+	case xerrors.Is(*err, ErrRequestFailed):
+		reason = "request_failed"
+	}
+	metrics.PinataErrors.WithLabelValues(endpoint, reason).Inc()
+}
+
+// checkStatus converts non-2xx Pinata response into sentinel errors.
+func checkStatus(status_code int, body []byte) error {
+	switch {
+	case status_code == http.StatusOK || status_code == http.StatusCreated:
+		return nil
+	case status_code == http.StatusUnauthorized || status_code == http.StatusForbidden:
+		return xerrors.Errorf("%w: %s", ErrUnauthorized, string(body))
+	default:
+		return xerrors.Errorf("%w: status %d: %s", ErrRequestFailed, status_code, string(body))
+	}
+}
+
+func apiRequest(ctx context.Context, method, endpoint string, body_struct *h) (response *http.Response, err error) {
 	client := http.Client{
 		Transport: &http.Transport{
 			IdleConnTimeout: 10 * time.Second,
 		},
 	}
 
-	body, err := json.Marshal(body_struct)
-	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
+	body := []byte{}
+	if body_struct != nil {
+		body, err = json.Marshal(body_struct)
+		if err != nil {
+			return nil, xerrors.Errorf("%w", err)
+		}
 	}
 
-	req, err := http.NewRequest(method, URL+endpoint, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, method, URL+endpoint, bytes.NewReader(body))
 	if err != nil {
 		return nil, xerrors.Errorf("%w", err)
 	}
-	req.Header.Add("pinata_api_key", config.C.Pinata.Key)
-	req.Header.Add("pinata_secret_api_key", config.C.Pinata.Secret)
+	// Credentials may be rotated by a config reload. Key and secret come
+	// from the same config.
+	credentials := config.Get().Pinata
+	req.Header.Add("pinata_api_key", credentials.Key)
+	req.Header.Add("pinata_secret_api_key", credentials.Secret)
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json")
 
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// How often full buckets are dropped from memory.
+	memoryStoreSweepInterval = 10 * time.Minute
+)
+
+type memoryBucket struct {
+	tokens     float64
+	refilledAt time.Time
+	rule       Rule
+}
+
+// MemoryStore keeps buckets in process memory. Limits are not shared
+// between instances.
+type MemoryStore struct {
+	lock      sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (store *MemoryStore) Take(key string, rule Rule, now time.Time) (decision Decision, err error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.sweep(now)
+	bucket, ok := store.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: rule.Burst, refilledAt: now, rule: rule}
+		store.buckets[key] = bucket
+	}
+	bucket.tokens, decision = refill(bucket.tokens, bucket.refilledAt, rule, now)
+	bucket.refilledAt = now
+	bucket.rule = rule
+
+	return decision, nil
+}
+
+// sweep drops buckets which have been refilled to full, since a new
+// bucket is full anyway. Must be called with lock held.
+func (store *MemoryStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < memoryStoreSweepInterval {
+		return
+	}
+	store.lastSweep = now
+	for key, bucket := range store.buckets {
+		if bucket.tokens+now.Sub(bucket.refilledAt).Seconds()*bucket.rule.Rate >= bucket.rule.Burst {
+			delete(store.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Buckets untouched longer than this will be deleted from DB.
+	postgresStoreIdleTimeout   = 24 * time.Hour
+	postgresStoreCleanInterval = 10 * time.Minute
+)
+
+// PostgresStore keeps buckets in `rate_limit_buckets` table of given
+// repository, so that limits are shared between all instances using
+// the same DB.
+type PostgresStore struct {
+	buckets   repository.RateLimitBuckets
+	lock      sync.Mutex
+	lastClean time.Time
+}
+
+func NewPostgresStore(buckets repository.RateLimitBuckets) *PostgresStore {
+	return &PostgresStore{buckets: buckets}
+}
+
+func (store *PostgresStore) Take(key string, rule Rule, now time.Time) (decision Decision, err error) {
+	store.clean(now)
+
+	initial := model.RateLimitBucket{Tokens: rule.Burst, RefilledAt: now}
+	err = store.buckets.Update(key, initial, func(bucket *model.RateLimitBucket) {
+		bucket.Tokens, decision = refill(bucket.Tokens, bucket.RefilledAt, rule, now)
+		bucket.RefilledAt = now
+	})
+	if err != nil {
+		return Decision{}, xerrors.Errorf("%w", err)
+	}
+	return decision, nil
+}
+
+// clean deletes idle buckets in background once in a while.
+func (store *PostgresStore) clean(now time.Time) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if now.Sub(store.lastClean) < postgresStoreCleanInterval {
+		return
+	}
+	store.lastClean = now
+
+	go func() {
+		affected, err := store.buckets.Clean(now.Add(-postgresStoreIdleTimeout))
+		l := logrus.WithFields(logrus.Fields{"module": "ratelimit", "store": "postgres"})
+		if err != nil {
+			l.Warnf("error when cleaning idle buckets: %s", err.Error())
+			return
+		}
+		l.WithField("affected", affected).Debugf("Idle buckets cleaned")
+	}()
+}
@@ -0,0 +1,79 @@
+// Package ratelimit implements token bucket rate limiting with
+// pluggable bucket stores.
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/repository"
+	"golang.org/x/xerrors"
+)
+
+// Rule describes a token bucket: refills `Rate` tokens per second, and
+// holds at most `Burst` tokens.
+type Rule struct {
+	Rate  float64
+	Burst float64
+}
+
+// Decision is the result of taking a token from a bucket.
+type Decision struct {
+	Allowed    bool
+	Remaining  float64       // Tokens left in bucket after this decision
+	RetryAfter time.Duration // Zero if allowed
+}
+
+// Store keeps token buckets.
+type Store interface {
+	// Take consumes one token from bucket `key`.
+	Take(key string, rule Rule, now time.Time) (Decision, error)
+}
+
+// RuleFromConfig converts config into Rule. Returns nil if rule_config
+// is nil or allows nothing.
+func RuleFromConfig(rule_config *config.RateLimitRule) *Rule {
+	if rule_config == nil || rule_config.Requests == 0 || rule_config.PeriodSeconds == 0 {
+		return nil
+	}
+	burst := rule_config.Burst
+	if burst == 0 {
+		burst = rule_config.Requests
+	}
+	return &Rule{
+		Rate:  float64(rule_config.Requests) / float64(rule_config.PeriodSeconds),
+		Burst: float64(burst),
+	}
+}
+
+// NewStore creates a Store by name given in config. The "postgres"
+// store keeps buckets in db.
+func NewStore(name string, db repository.Store) (Store, error) {
+	switch name {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "postgres":
+		return NewPostgresStore(db.RateLimitBuckets()), nil
+	default:
+		return nil, xerrors.Errorf("unknown rate limit store: %s", name)
+	}
+}
+
+// refill returns bucket state after refilling tokens since
+// refilled_at and taking one token if possible.
+func refill(tokens float64, refilled_at time.Time, rule Rule, now time.Time) (new_tokens float64, decision Decision) {
+	elapsed := now.Sub(refilled_at).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	new_tokens = math.Min(rule.Burst, tokens+elapsed*rule.Rate)
+
+	if new_tokens >= 1 {
+		new_tokens -= 1
+		return new_tokens, Decision{Allowed: true, Remaining: new_tokens}
+	}
+
+	retry_after := time.Duration((1 - new_tokens) / rule.Rate * float64(time.Second))
+	return new_tokens, Decision{Allowed: false, Remaining: new_tokens, RetryAfter: retry_after}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RuleFromConfig(t *testing.T) {
+	t.Run("burst defaults to requests", func(t *testing.T) {
+		rule := RuleFromConfig(&config.RateLimitRule{Requests: 10, PeriodSeconds: 60})
+		assert.InDelta(t, 10.0/60.0, rule.Rate, 0.0001)
+		assert.Equal(t, float64(10), rule.Burst)
+	})
+
+	t.Run("nil if not configured", func(t *testing.T) {
+		assert.Nil(t, RuleFromConfig(nil))
+		assert.Nil(t, RuleFromConfig(&config.RateLimitRule{Requests: 10}))
+	})
+}
+
+func Test_MemoryStore(t *testing.T) {
+	rule := Rule{Rate: 1, Burst: 2}
+	now := time.Unix(1600000000, 0)
+
+	t.Run("burst then deny", func(t *testing.T) {
+		store := NewMemoryStore()
+		for i := 0; i < 2; i++ {
+			decision, err := store.Take("key", rule, now)
+			assert.Nil(t, err)
+			assert.True(t, decision.Allowed)
+		}
+		decision, err := store.Take("key", rule, now)
+		assert.Nil(t, err)
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, time.Second, decision.RetryAfter)
+	})
+
+	t.Run("refill", func(t *testing.T) {
+		store := NewMemoryStore()
+		store.Take("key", rule, now)
+		store.Take("key", rule, now)
+		decision, _ := store.Take("key", rule, now.Add(500*time.Millisecond))
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, 500*time.Millisecond, decision.RetryAfter)
+
+		decision, _ = store.Take("key", rule, now.Add(time.Second))
+		assert.True(t, decision.Allowed)
+	})
+
+	t.Run("keys are separated", func(t *testing.T) {
+		store := NewMemoryStore()
+		store.Take("a", rule, now)
+		store.Take("a", rule, now)
+		decision, _ := store.Take("b", rule, now)
+		assert.True(t, decision.Allowed)
+	})
+
+	t.Run("sweep full buckets", func(t *testing.T) {
+		store := NewMemoryStore()
+		store.Take("a", rule, now)
+		store.Take("b", Rule{Rate: 0.0001, Burst: 2}, now)
+		store.Take("c", rule, now.Add(memoryStoreSweepInterval))
+		assert.Equal(t, 2, len(store.buckets))
+		_, ok := store.buckets["a"]
+		assert.False(t, ok)
+	})
+}
+
+func Test_PostgresStore(t *testing.T) {
+	rule := Rule{Rate: 1, Burst: 2}
+	now := time.Unix(1600000000, 0)
+	db := repository.NewMemory()
+
+	// Two stores on the same DB share their buckets.
+	store, other := NewPostgresStore(db.RateLimitBuckets()), NewPostgresStore(db.RateLimitBuckets())
+	decision, err := store.Take("key", rule, now)
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+	decision, err = other.Take("key", rule, now)
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+
+	decision, err = store.Take("key", rule, now)
+	assert.Nil(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, time.Second, decision.RetryAfter)
+}
@@ -0,0 +1,705 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/util"
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/xerrors"
+)
+
+// Memory keeps everything in process memory. Meant for unit tests:
+// transactions are serialized and rolled back by restoring a snapshot,
+// but changes inside a transaction are visible to others before commit.
+type Memory struct {
+	lock    *sync.Mutex // Guards state
+	txLock  *sync.Mutex // Serializes transactions
+	state   *memoryState
+	inTx    bool
+	lastIds *uint64
+	locks   map[string]bool // Names locked by TryLock, guarded by lock
+}
+
+type memoryState struct {
+	nfts        []model.NFT
+	events      []model.Event
+	keys        []model.Key
+	blockLogs   []model.BlockLog
+	checkpoints map[string]model.Checkpoint
+	binds       []model.TelegramBind
+	groups      []model.TelegramGroup
+	cursors     map[uint64]model.TelegramCursor // By TelegramGroup id
+	erc20Tokens map[string]model.ERC20Token     // By chain and lowercased address
+	buckets     map[string]model.RateLimitBucket
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		lock:   new(sync.Mutex),
+		txLock: new(sync.Mutex),
+		state: &memoryState{
+			checkpoints: make(map[string]model.Checkpoint),
+			cursors:     make(map[uint64]model.TelegramCursor),
+			erc20Tokens: make(map[string]model.ERC20Token),
+			buckets:     make(map[string]model.RateLimitBucket),
+		},
+		lastIds: new(uint64),
+		locks:   make(map[string]bool),
+	}
+}
+
+func (state *memoryState) clone() *memoryState {
+	cloned := &memoryState{
+		nfts:        append([]model.NFT(nil), state.nfts...),
+		events:      append([]model.Event(nil), state.events...),
+		keys:        append([]model.Key(nil), state.keys...),
+		blockLogs:   append([]model.BlockLog(nil), state.blockLogs...),
+		checkpoints: make(map[string]model.Checkpoint, len(state.checkpoints)),
+		binds:       append([]model.TelegramBind(nil), state.binds...),
+		groups:      append([]model.TelegramGroup(nil), state.groups...),
+		cursors:     make(map[uint64]model.TelegramCursor, len(state.cursors)),
+		erc20Tokens: make(map[string]model.ERC20Token, len(state.erc20Tokens)),
+		buckets:     make(map[string]model.RateLimitBucket, len(state.buckets)),
+	}
+	for chainName, checkpoint := range state.checkpoints {
+		cloned.checkpoints[chainName] = checkpoint
+	}
+	for group_id, cursor := range state.cursors {
+		cloned.cursors[group_id] = cursor
+	}
+	for key, token := range state.erc20Tokens {
+		cloned.erc20Tokens[key] = token
+	}
+	for key, bucket := range state.buckets {
+		cloned.buckets[key] = bucket
+	}
+	return cloned
+}
+
+// nextId returns a new auto-increment id. Caller must hold lock.
+func (store *Memory) nextId() uint64 {
+	*store.lastIds += 1
+	return *store.lastIds
+}
+
+func (store *Memory) NFTs() NFTs                         { return memoryNFTs{store} }
+func (store *Memory) Events() Events                     { return memoryEvents{store} }
+func (store *Memory) Keys() Keys                         { return memoryKeys{store} }
+func (store *Memory) BlockLogs() BlockLogs               { return memoryBlockLogs{store} }
+func (store *Memory) TelegramBinds() TelegramBinds       { return memoryTelegramBinds{store} }
+func (store *Memory) ERC20Tokens() ERC20Tokens           { return memoryERC20Tokens{store} }
+func (store *Memory) RateLimitBuckets() RateLimitBuckets { return memoryRateLimitBuckets{store} }
+
+func (store *Memory) Transaction(fn func(tx Store) error) error {
+	if store.inTx {
+		return fn(store)
+	}
+
+	store.txLock.Lock()
+	defer store.txLock.Unlock()
+	store.lock.Lock()
+	snapshot := store.state.clone()
+	store.lock.Unlock()
+
+	tx := *store
+	tx.inTx = true
+	if err := fn(&tx); err != nil {
+		store.lock.Lock()
+		*store.state = *snapshot
+		store.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (store *Memory) Ping(_ context.Context) error {
+	return nil
+}
+
+// TryLock locks name in this store only.
+func (store *Memory) TryLock(_ context.Context, name string) (Lock, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.locks[name] {
+		return nil, nil
+	}
+	store.locks[name] = true
+	return memoryLock{store, name}, nil
+}
+
+type memoryLock struct {
+	store *Memory
+	name  string
+}
+
+func (lock memoryLock) Alive(_ context.Context) error {
+	return nil
+}
+
+func (lock memoryLock) Release() error {
+	lock.store.lock.Lock()
+	defer lock.store.lock.Unlock()
+	delete(lock.store.locks, lock.name)
+	return nil
+}
+
+type memoryNFTs struct{ store *Memory }
+
+func (repo memoryNFTs) Find(chainName string, nft_id model.TokenId) (*model.NFT, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	for _, nft := range repo.store.state.nfts {
+		if nft.Chain == chainName && nft.NFTID == nft_id {
+			return &nft, nil
+		}
+	}
+	return nil, xerrors.Errorf("%w for nft_id %s", model.ErrNFTNotFound, nft_id)
+}
+
+func (repo memoryNFTs) FindByOwner(chainName string, owner string) ([]*model.NFT, error) {
+	return repo.filter(func(nft *model.NFT) bool {
+		return nft.Chain == chainName && nft.Owner == owner
+	}), nil
+}
+
+func (repo memoryNFTs) ChildrenOf(chainName string, parents []model.TokenId) ([]*model.NFT, error) {
+	wanted := make(map[model.TokenId]bool, len(parents))
+	for _, parent := range parents {
+		wanted[parent] = true
+	}
+	return repo.filter(func(nft *model.NFT) bool {
+		return nft.Chain == chainName && wanted[nft.Parent]
+	}), nil
+}
+
+func (repo memoryNFTs) filter(match func(nft *model.NFT) bool) (result []*model.NFT) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	result = make([]*model.NFT, 0)
+	for _, nft := range repo.store.state.nfts {
+		nft := nft
+		if match(&nft) {
+			result = append(result, &nft)
+		}
+	}
+	return result
+}
+
+func (repo memoryNFTs) Create(nfts []*model.NFT) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	now := time.Now()
+	for _, nft := range nfts {
+		nft.Id = repo.store.nextId()
+		nft.CreatedAt, nft.UpdatedAt = now, now
+		repo.store.state.nfts = append(repo.store.state.nfts, *nft)
+	}
+	return nil
+}
+
+func (repo memoryNFTs) Update(nft *model.NFT, cols ...string) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	for i := range repo.store.state.nfts {
+		saved := &repo.store.state.nfts[i]
+		if saved.Id != nft.Id {
+			continue
+		}
+		for _, col := range cols {
+			switch col {
+			case "owner":
+				saved.Owner = nft.Owner
+			case "shill_count":
+				saved.ShillCount = nft.ShillCount
+			case "max_shill_count":
+				saved.MaxShillCount = nft.MaxShillCount
+			case "token_addr":
+				saved.TokenAddr = nft.TokenAddr
+			case "parent":
+				saved.Parent = nft.Parent
+			default:
+				return xerrors.Errorf("column %s of NFT not supported", col)
+			}
+		}
+		saved.UpdatedAt = time.Now()
+		return nil
+	}
+	return nil
+}
+
+func (repo memoryNFTs) Delete(chainName string, nft_id model.TokenId) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	nfts := make([]model.NFT, 0, len(repo.store.state.nfts))
+	for _, nft := range repo.store.state.nfts {
+		if nft.Chain != chainName || nft.NFTID != nft_id {
+			nfts = append(nfts, nft)
+		}
+	}
+	repo.store.state.nfts = nfts
+	return nil
+}
+
+type memoryEvents struct{ store *Memory }
+
+func (repo memoryEvents) CreateIgnoreExisting(events []*model.Event) (affected int64, err error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	now := time.Now()
+NextEvent:
+	for _, event := range events {
+		for _, saved := range repo.store.state.events {
+			if saved.Chain == event.Chain && saved.TxHash == event.TxHash && saved.Index == event.Index {
+				continue NextEvent
+			}
+		}
+		saved := *event
+		saved.Id = repo.store.nextId()
+		saved.CreatedAt, saved.UpdatedAt = now, now
+		repo.store.state.events = append(repo.store.state.events, saved)
+		affected += 1
+	}
+	return affected, nil
+}
+
+func (repo memoryEvents) FindInBlock(chainName string, height uint64) ([]*model.Event, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	events := make([]*model.Event, 0)
+	for _, event := range repo.store.state.events {
+		event := event
+		if event.Chain == chainName && event.BlockHeight == height {
+			events = append(events, &event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Index != events[j].Index {
+			return events[i].Index < events[j].Index
+		}
+		return events[i].Id < events[j].Id
+	})
+	return events, nil
+}
+
+func (repo memoryEvents) FindPublishBetween(chainName string, from, to uint64) ([]*model.Event, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	events := make([]*model.Event, 0)
+	for _, event := range repo.store.state.events {
+		event := event
+		if event.Chain == chainName && event.IsPublish() && event.BlockHeight >= from && event.BlockHeight <= to {
+			events = append(events, &event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].BlockHeight != events[j].BlockHeight {
+			return events[i].BlockHeight < events[j].BlockHeight
+		}
+		if events[i].Index != events[j].Index {
+			return events[i].Index < events[j].Index
+		}
+		return events[i].Id < events[j].Id
+	})
+	return events, nil
+}
+
+func (repo memoryEvents) HasLaterTransfer(event *model.Event) (bool, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	for _, saved := range repo.store.state.events {
+		if saved.Chain != event.Chain || saved.NFTId != event.NFTId || saved.Type != model.EventTypeTransfer {
+			continue
+		}
+		if saved.BlockHeight > event.BlockHeight ||
+			(saved.BlockHeight == event.BlockHeight && saved.Index > event.Index) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (repo memoryEvents) FindLatestTransfer(chainName string, nft_id model.TokenId) (*model.Event, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	var latest *model.Event
+	for _, saved := range repo.store.state.events {
+		saved := saved
+		if saved.Chain != chainName || saved.NFTId != nft_id || saved.Type != model.EventTypeTransfer || saved.TokenIdOverflow {
+			continue
+		}
+		if latest == nil || saved.BlockHeight > latest.BlockHeight ||
+			(saved.BlockHeight == latest.BlockHeight && saved.Index > latest.Index) {
+			latest = &saved
+		}
+	}
+	if latest == nil {
+		return nil, xerrors.Errorf("%w: transfer of %s", model.ErrEventNotFound, nft_id)
+	}
+	return latest, nil
+}
+
+func (repo memoryEvents) Update(event *model.Event) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	for i := range repo.store.state.events {
+		saved := &repo.store.state.events[i]
+		if saved.Id == event.Id {
+			created_at := saved.CreatedAt
+			*saved = *event
+			saved.CreatedAt, saved.UpdatedAt = created_at, time.Now()
+			return nil
+		}
+	}
+	return nil
+}
+
+func (repo memoryEvents) Delete(id uint64) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	events := repo.store.state.events[:0]
+	for _, event := range repo.store.state.events {
+		if event.Id != id {
+			events = append(events, event)
+		}
+	}
+	repo.store.state.events = events
+	return nil
+}
+
+type memoryKeys struct{ store *Memory }
+
+func (repo memoryKeys) Create(chainName string, owner string, nft_id model.TokenId) (*model.Key, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	for _, key := range repo.store.state.keys {
+		if key.Chain == chainName && key.Owner == owner && key.NFTId == nft_id {
+			return nil, xerrors.Errorf("%w: %s", model.ErrKeyExists, nft_id)
+		}
+	}
+	now := time.Now()
+	key := model.Key{
+		Id:        repo.store.nextId(),
+		Key:       util.RandomStringGenerator(model.KEY_LENGTH),
+		Chain:     chainName,
+		Owner:     owner,
+		NFTId:     nft_id,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	repo.store.state.keys = append(repo.store.state.keys, key)
+	return &key, nil
+}
+
+func (repo memoryKeys) Get(chainName string, nft_id model.TokenId) (string, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	for _, key := range repo.store.state.keys {
+		if key.Chain == chainName && key.NFTId == nft_id {
+			return key.Key, nil
+		}
+	}
+	return "", xerrors.Errorf("%w: %s", model.ErrKeyNotFound, nft_id)
+}
+
+type memoryBlockLogs struct{ store *Memory }
+
+func (repo memoryBlockLogs) Start(chainName string, height uint64) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	for _, block_log := range repo.store.state.blockLogs {
+		if block_log.Chain == chainName && block_log.BlockHeight == height {
+			return xerrors.Errorf("%w: height %d", model.ErrBlockLogExists, height)
+		}
+	}
+	now := time.Now()
+	repo.store.state.blockLogs = append(repo.store.state.blockLogs, model.BlockLog{
+		Id:          repo.store.nextId(),
+		Chain:       chainName,
+		BlockHeight: height,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	return nil
+}
+
+func (repo memoryBlockLogs) Finish(chainName string, height uint64) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	for i := range repo.store.state.blockLogs {
+		block_log := &repo.store.state.blockLogs[i]
+		if block_log.Chain == chainName && block_log.BlockHeight == height {
+			block_log.Scanned = true
+			block_log.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return xerrors.Errorf("%w: height %d", model.ErrBlockLogNotFound, height)
+}
+
+func (repo memoryBlockLogs) FindLatestScanned(chainName string) (*model.BlockLog, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	return repo.latestScanned(chainName)
+}
+
+// latestScanned requires lock being held.
+func (repo memoryBlockLogs) latestScanned(chainName string) (*model.BlockLog, error) {
+	var latest *model.BlockLog
+	for _, block_log := range repo.store.state.blockLogs {
+		block_log := block_log
+		if block_log.Chain == chainName && block_log.Scanned && (latest == nil || block_log.BlockHeight > latest.BlockHeight) {
+			latest = &block_log
+		}
+	}
+	if latest == nil {
+		return nil, xerrors.Errorf("found height failed: %w", model.ErrBlockLogNotFound)
+	}
+	return latest, nil
+}
+
+// Clean follows the same rules as model.BlockLogClean.
+func (repo memoryBlockLogs) Clean(chainName string, keep_count uint64, keep_age time.Duration) (affected int64, err error) {
+	if keep_count == 0 && keep_age == 0 {
+		return 0, nil
+	}
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	latest, err := repo.latestScanned(chainName)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := func(block_log model.BlockLog) bool {
+		if block_log.Chain != chainName || block_log.BlockHeight >= latest.BlockHeight {
+			return false
+		}
+		if keep_count > 0 && latest.BlockHeight >= keep_count && block_log.BlockHeight <= latest.BlockHeight-keep_count {
+			return true
+		}
+		return keep_age > 0 && block_log.CreatedAt.Before(time.Now().Add(-keep_age))
+	}
+	block_logs := repo.store.state.blockLogs[:0]
+	for _, block_log := range repo.store.state.blockLogs {
+		if expired(block_log) {
+			affected += 1
+			continue
+		}
+		block_logs = append(block_logs, block_log)
+	}
+	repo.store.state.blockLogs = block_logs
+	return affected, nil
+}
+
+func (repo memoryBlockLogs) FindCheckpoint(chainName string) (*model.Checkpoint, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	checkpoint, ok := repo.store.state.checkpoints[chainName]
+	if !ok {
+		return nil, xerrors.Errorf("%w: %s", model.ErrCheckpointNotFound, chainName)
+	}
+	return &checkpoint, nil
+}
+
+func (repo memoryBlockLogs) SaveCheckpoint(chainName string, height uint64, hash string) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	now := time.Now()
+	checkpoint, ok := repo.store.state.checkpoints[chainName]
+	if !ok {
+		checkpoint = model.Checkpoint{Chain: chainName, CreatedAt: now}
+	}
+	checkpoint.BlockHeight, checkpoint.BlockHash, checkpoint.UpdatedAt = height, hash, now
+	repo.store.state.checkpoints[chainName] = checkpoint
+	return nil
+}
+
+type memoryTelegramBinds struct{ store *Memory }
+
+func (repo memoryTelegramBinds) Create(admin_id int64, erc20_address, erc20_name, erc20_symbol string) (*model.TelegramBind, error) {
+	if !common.IsHexAddress(erc20_address) {
+		return nil, xerrors.Errorf("%w: %s", model.ErrInvalidAddress, erc20_address)
+	}
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	now := time.Now()
+	bind := model.TelegramBind{
+		Id:           repo.store.nextId(),
+		ERC20Name:    erc20_name,
+		ERC20Symbol:  erc20_symbol,
+		ERC20Address: erc20_address,
+		AdminID:      admin_id,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	repo.store.state.binds = append(repo.store.state.binds, bind)
+	return &bind, nil
+}
+
+// matches mimics xorm conditions built from non-zero fields.
+func (repo memoryTelegramBinds) matches(bind model.TelegramBind, condition *model.TelegramBind) bool {
+	return (condition.Id == 0 || bind.Id == condition.Id) &&
+		(condition.ERC20Name == "" || bind.ERC20Name == condition.ERC20Name) &&
+		(condition.ERC20Symbol == "" || bind.ERC20Symbol == condition.ERC20Symbol) &&
+		(condition.ERC20Address == "" || bind.ERC20Address == condition.ERC20Address) &&
+		(condition.AdminID == 0 || bind.AdminID == condition.AdminID)
+}
+
+func (repo memoryTelegramBinds) FindBy(condition *model.TelegramBind) ([]*model.TelegramBind, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	results := make([]*model.TelegramBind, 0)
+	for _, bind := range repo.store.state.binds {
+		bind := bind
+		if repo.matches(bind, condition) {
+			results = append(results, &bind)
+		}
+	}
+	return results, nil
+}
+
+func (repo memoryTelegramBinds) Exist(condition *model.TelegramBind) (bool, error) {
+	results, err := repo.FindBy(condition)
+	return len(results) > 0, err
+}
+
+func (repo memoryTelegramBinds) Delete(binds []*model.TelegramBind) (affected int64, err error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	deleted := make(map[uint64]bool, len(binds))
+	for _, bind := range binds {
+		deleted[bind.Id] = true
+	}
+
+	kept_binds := repo.store.state.binds[:0]
+	for _, bind := range repo.store.state.binds {
+		if deleted[bind.Id] {
+			affected += 1
+			continue
+		}
+		kept_binds = append(kept_binds, bind)
+	}
+	repo.store.state.binds = kept_binds
+
+	kept_groups := repo.store.state.groups[:0]
+	for _, group := range repo.store.state.groups {
+		if deleted[group.TGBindID] {
+			delete(repo.store.state.cursors, group.Id)
+			continue
+		}
+		kept_groups = append(kept_groups, group)
+	}
+	repo.store.state.groups = kept_groups
+	return affected, nil
+}
+
+func (repo memoryTelegramBinds) CreateGroup(bind *model.TelegramBind, chat_id int64, chat_title string) (*model.TelegramGroup, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	group := model.TelegramGroup{
+		Id:        repo.store.nextId(),
+		TGBindID:  bind.Id,
+		ChatID:    chat_id,
+		ChatTitle: chat_title,
+	}
+	repo.store.state.groups = append(repo.store.state.groups, group)
+	return &group, nil
+}
+
+func (repo memoryTelegramBinds) Groups(bind *model.TelegramBind) ([]*model.TelegramGroup, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	results := make([]*model.TelegramGroup, 0)
+	for _, group := range repo.store.state.groups {
+		group := group
+		if group.TGBindID == bind.Id {
+			results = append(results, &group)
+		}
+	}
+	return results, nil
+}
+
+func (repo memoryTelegramBinds) FindCursor(group *model.TelegramGroup) (*model.TelegramCursor, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	cursor, ok := repo.store.state.cursors[group.Id]
+	if !ok {
+		return nil, xerrors.Errorf("%w: %d", model.ErrTGCursorNotFound, group.Id)
+	}
+	return &cursor, nil
+}
+
+func (repo memoryTelegramBinds) SaveCursor(cursor *model.TelegramCursor) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	now := time.Now()
+	saved, ok := repo.store.state.cursors[cursor.GroupID]
+	if !ok {
+		saved = model.TelegramCursor{GroupID: cursor.GroupID, CreatedAt: now}
+	}
+	saved.BlockHeight, saved.EventIndex, saved.UpdatedAt = cursor.BlockHeight, cursor.EventIndex, now
+	repo.store.state.cursors[cursor.GroupID] = saved
+	cursor.UpdatedAt = now
+	return nil
+}
+
+type memoryERC20Tokens struct{ store *Memory }
+
+func (repo memoryERC20Tokens) key(chainName string, address string) string {
+	return chainName + "/" + strings.ToLower(address)
+}
+
+func (repo memoryERC20Tokens) Find(chainName string, address string) (*model.ERC20Token, error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	token, ok := repo.store.state.erc20Tokens[repo.key(chainName, address)]
+	if !ok {
+		return nil, xerrors.Errorf("%w: %s %s", model.ErrERC20TokenNotFound, chainName, address)
+	}
+	return &token, nil
+}
+
+func (repo memoryERC20Tokens) Save(token *model.ERC20Token) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	now := time.Now()
+	key := repo.key(token.Chain, token.Address)
+	token.Address = strings.ToLower(token.Address)
+	token.CreatedAt, token.UpdatedAt = now, now
+	if saved, ok := repo.store.state.erc20Tokens[key]; ok {
+		token.CreatedAt = saved.CreatedAt
+	}
+	repo.store.state.erc20Tokens[key] = *token
+	return nil
+}
+
+type memoryRateLimitBuckets struct{ store *Memory }
+
+func (repo memoryRateLimitBuckets) Update(key string, initial model.RateLimitBucket, fn func(bucket *model.RateLimitBucket)) error {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	bucket, ok := repo.store.state.buckets[key]
+	if !ok {
+		bucket = initial
+		bucket.BucketKey = key
+	}
+	fn(&bucket)
+	repo.store.state.buckets[key] = bucket
+	return nil
+}
+
+func (repo memoryRateLimitBuckets) Clean(before time.Time) (affected int64, err error) {
+	repo.store.lock.Lock()
+	defer repo.store.lock.Unlock()
+	for key, bucket := range repo.store.state.buckets {
+		if bucket.RefilledAt.Before(before) {
+			delete(repo.store.state.buckets, key)
+			affected += 1
+		}
+	}
+	return affected, nil
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func Test_Memory_Transaction(t *testing.T) {
+	t.Run("commits", func(t *testing.T) {
+		store := NewMemory()
+		err := store.Transaction(func(tx Store) error {
+			return tx.BlockLogs().SaveCheckpoint(chainName, 1000, "0xaaaa")
+		})
+		assert.Nil(t, err)
+
+		checkpoint, err := store.BlockLogs().FindCheckpoint(chainName)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(1000), checkpoint.BlockHeight)
+	})
+
+	t.Run("rolls back", func(t *testing.T) {
+		store := NewMemory()
+		err := store.Transaction(func(tx Store) error {
+			require.Nil(t, tx.BlockLogs().SaveCheckpoint(chainName, 1000, "0xaaaa"))
+			return xerrors.New("failed")
+		})
+		assert.EqualError(t, err, "failed")
+
+		_, err = store.BlockLogs().FindCheckpoint(chainName)
+		assert.True(t, xerrors.Is(err, model.ErrCheckpointNotFound))
+	})
+}
+
+func Test_Memory_Events(t *testing.T) {
+	store := NewMemory()
+	events := []*model.Event{
+		{Chain: chainName, BlockHeight: 100, Index: 2, TxHash: "0x01", Type: model.EventTypeTransfer, NFTId: 1},
+		{Chain: chainName, BlockHeight: 100, Index: 1, TxHash: "0x01", Type: model.EventTypePublish, NFTId: 1},
+	}
+
+	affected, err := store.Events().CreateIgnoreExisting(events)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), affected)
+	affected, err = store.Events().CreateIgnoreExisting(events)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), affected)
+
+	found, err := store.Events().FindInBlock(chainName, 100)
+	assert.Nil(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, uint(1), found[0].Index)
+
+	later, err := store.Events().HasLaterTransfer(found[0])
+	assert.Nil(t, err)
+	assert.True(t, later)
+	later, err = store.Events().HasLaterTransfer(found[1])
+	assert.Nil(t, err)
+	assert.False(t, later)
+
+	assert.Nil(t, store.Events().Delete(found[1].Id))
+	found, err = store.Events().FindInBlock(chainName, 100)
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+}
+
+func Test_Memory_Keys(t *testing.T) {
+	store := NewMemory()
+	_, err := store.Keys().Get(chainName, 0x100000001)
+	assert.True(t, xerrors.Is(err, model.ErrKeyNotFound))
+
+	key, err := store.Keys().Create(chainName, "0xA", 0x100000001)
+	assert.Nil(t, err)
+	assert.Len(t, key.Key, model.KEY_LENGTH)
+
+	_, err = store.Keys().Create(chainName, "0xA", 0x100000001)
+	assert.True(t, xerrors.Is(err, model.ErrKeyExists))
+
+	found, err := store.Keys().Get(chainName, 0x100000001)
+	assert.Nil(t, err)
+	assert.Equal(t, key.Key, found)
+}
+
+func Test_Memory_BlockLogs(t *testing.T) {
+	store := NewMemory()
+	block_logs := store.BlockLogs()
+	_, err := block_logs.FindLatestScanned(chainName)
+	assert.True(t, xerrors.Is(err, model.ErrBlockLogNotFound))
+
+	for height := uint64(1); height <= 10; height++ {
+		require.Nil(t, block_logs.Start(chainName, height))
+		require.Nil(t, block_logs.Finish(chainName, height))
+	}
+	assert.True(t, xerrors.Is(block_logs.Start(chainName, 10), model.ErrBlockLogExists))
+	assert.True(t, xerrors.Is(block_logs.Finish(chainName, 11), model.ErrBlockLogNotFound))
+
+	latest, err := block_logs.FindLatestScanned(chainName)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), latest.BlockHeight)
+
+	affected, err := block_logs.Clean(chainName, 3, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), affected)
+
+	affected, err = block_logs.Clean(chainName, 0, time.Nanosecond)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), affected)
+	latest, err = block_logs.FindLatestScanned(chainName)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), latest.BlockHeight)
+}
+
+func Test_Memory_TelegramBinds(t *testing.T) {
+	store := NewMemory()
+	binds := store.TelegramBinds()
+
+	_, err := binds.Create(1337, "0xabc123", "Test", "")
+	assert.True(t, xerrors.Is(err, model.ErrInvalidAddress))
+
+	bind, err := binds.Create(1337, "0x0000000000000000000000000000000000000000", "Ethereum", "ETH")
+	require.Nil(t, err)
+	_, err = binds.CreateGroup(bind, -100, "Group")
+	require.Nil(t, err)
+
+	exist, err := binds.Exist(&model.TelegramBind{AdminID: 1337, ERC20Address: bind.ERC20Address})
+	assert.Nil(t, err)
+	assert.True(t, exist)
+	found, err := binds.FindBy(&model.TelegramBind{AdminID: 1})
+	assert.Nil(t, err)
+	assert.Empty(t, found)
+
+	affected, err := binds.Delete([]*model.TelegramBind{bind})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), affected)
+	groups, err := binds.Groups(bind)
+	assert.Nil(t, err)
+	assert.Empty(t, groups)
+}
+
+func Test_Memory_TelegramCursors(t *testing.T) {
+	store := NewMemory()
+	binds := store.TelegramBinds()
+	bind, err := binds.Create(1337, "0x0000000000000000000000000000000000000000", "Ethereum", "ETH")
+	require.Nil(t, err)
+	group, err := binds.CreateGroup(bind, -100, "Group")
+	require.Nil(t, err)
+
+	_, err = binds.FindCursor(group)
+	assert.True(t, xerrors.Is(err, model.ErrTGCursorNotFound))
+	require.Nil(t, binds.SaveCursor(&model.TelegramCursor{GroupID: group.Id, BlockHeight: 100, EventIndex: 1}))
+	cursor, err := binds.FindCursor(group)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(100), cursor.BlockHeight)
+
+	_, err = binds.Delete([]*model.TelegramBind{bind})
+	require.Nil(t, err)
+	_, err = binds.FindCursor(group)
+	assert.True(t, xerrors.Is(err, model.ErrTGCursorNotFound))
+}
+
+func Test_Memory_ERC20Tokens(t *testing.T) {
+	store := NewMemory()
+	tokens := store.ERC20Tokens()
+
+	_, err := tokens.Find(chainName, "0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F")
+	assert.True(t, xerrors.Is(err, model.ErrERC20TokenNotFound))
+
+	require.Nil(t, tokens.Save(&model.ERC20Token{
+		Chain: chainName, Address: "0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F", Symbol: "TEST", Decimals: 6, TotalSupply: "100",
+	}))
+	found, err := tokens.Find(chainName, "0x7b5b92b0ed1dfeafdbd724b177a7733bda67497f")
+	require.Nil(t, err)
+	assert.Equal(t, "TEST", found.Symbol)
+	assert.Equal(t, "0x7b5b92b0ed1dfeafdbd724b177a7733bda67497f", found.Address)
+}
+
+func Test_Memory_TryLock(t *testing.T) {
+	store := NewMemory()
+	ctx := context.Background()
+
+	lock, err := store.TryLock(ctx, "test:exclusive")
+	require.Nil(t, err)
+	require.NotNil(t, lock)
+	assert.Nil(t, lock.Alive(ctx))
+
+	other, err := store.TryLock(ctx, "test:exclusive")
+	assert.Nil(t, err)
+	assert.Nil(t, other)
+
+	require.Nil(t, lock.Release())
+	lock, err = store.TryLock(ctx, "test:exclusive")
+	assert.Nil(t, err)
+	assert.NotNil(t, lock)
+}
+
+func Test_Memory_RateLimitBuckets(t *testing.T) {
+	store := NewMemory()
+	buckets := store.RateLimitBuckets()
+	now := time.Unix(1600000000, 0)
+
+	take := func(bucket *model.RateLimitBucket) { bucket.Tokens -= 1 }
+	require.Nil(t, buckets.Update("a", model.RateLimitBucket{Tokens: 2, RefilledAt: now}, take))
+	require.Nil(t, buckets.Update("a", model.RateLimitBucket{Tokens: 2, RefilledAt: now}, func(bucket *model.RateLimitBucket) {
+		assert.Equal(t, "a", bucket.BucketKey)
+		assert.Equal(t, float64(1), bucket.Tokens)
+	}))
+	require.Nil(t, buckets.Update("b", model.RateLimitBucket{Tokens: 2, RefilledAt: now.Add(time.Hour)}, take))
+
+	affected, err := buckets.Clean(now.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), affected)
+}
+
+func Test_Memory_FindLatestTransfer(t *testing.T) {
+	store := NewMemory()
+	_, err := store.Events().FindLatestTransfer(chainName, 1)
+	assert.True(t, xerrors.Is(err, model.ErrEventNotFound))
+
+	_, err = store.Events().CreateIgnoreExisting([]*model.Event{
+		{Chain: chainName, BlockHeight: 100, Index: 1, TxHash: "0x01", Type: model.EventTypeTransfer, NFTId: 1, To: "0xB"},
+		{Chain: chainName, BlockHeight: 100, Index: 0, TxHash: "0x01", Type: model.EventTypeTransfer, NFTId: 1, To: "0xA"},
+		{Chain: chainName, BlockHeight: 99, Index: 5, TxHash: "0x02", Type: model.EventTypeTransfer, NFTId: 1, To: "0xC"},
+	})
+	require.Nil(t, err)
+	latest, err := store.Events().FindLatestTransfer(chainName, 1)
+	require.Nil(t, err)
+	assert.Equal(t, "0xB", latest.To)
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"github.com/SparkNFT/key_server/model"
+	"golang.org/x/xerrors"
+)
+
+// ChildrenCount counts all descendants of a NFT, one query per tree
+// level.
+func ChildrenCount(nfts NFTs, chainName string, nft_id model.TokenId) (count int, err error) {
+	parents := []model.TokenId{nft_id}
+	count = 0
+
+	for len(parents) != 0 {
+		children, err := nfts.ChildrenOf(chainName, parents)
+		if err != nil {
+			return 0, xerrors.Errorf("%w", err)
+		}
+		parents = make([]model.TokenId, 0, len(children))
+		for _, nft := range children {
+			parents = append(parents, nft.NFTID)
+		}
+		count += len(parents)
+	}
+
+	return count, nil
+}
+
+// ChildrenTree builds the tree of all descendants of a NFT, one query
+// per tree level.
+func ChildrenTree(nfts NFTs, chainName string, nft_id model.TokenId) (tree *model.NFTTree, err error) {
+	root := &model.NFTTree{
+		NFTID:    nft_id.String(),
+		Children: []*model.NFTTree{},
+	}
+	level := map[model.TokenId]*model.NFTTree{nft_id: root}
+
+	for len(level) != 0 {
+		parents := make([]model.TokenId, 0, len(level))
+		for parent := range level {
+			parents = append(parents, parent)
+		}
+		children, err := nfts.ChildrenOf(chainName, parents)
+		if err != nil {
+			return nil, xerrors.Errorf("%w", err)
+		}
+		next_level := make(map[model.TokenId]*model.NFTTree, len(children))
+		for _, nft := range children {
+			leaf := &model.NFTTree{
+				NFTID:    nft.NFTID.String(),
+				Children: []*model.NFTTree{},
+			}
+			current := level[nft.Parent]
+			current.Children = append(current.Children, leaf)
+			next_level[nft.NFTID] = leaf
+		}
+		level = next_level
+	}
+
+	return root, nil
+}
+
+// childrenLoader serves children of NFTs in a tree, loading a whole
+// level of the tree in one query when a NFT of it is first asked for.
+type childrenLoader struct {
+	nfts      NFTs
+	chainName string
+	loaded    map[model.TokenId][]*model.NFT
+	// Parents whose children are not loaded yet.
+	next []model.TokenId
+}
+
+func newChildrenLoader(nfts NFTs, root *model.NFT) *childrenLoader {
+	return &childrenLoader{
+		nfts:      nfts,
+		chainName: root.Chain,
+		loaded:    make(map[model.TokenId][]*model.NFT),
+		next:      []model.TokenId{root.NFTID},
+	}
+}
+
+func (loader *childrenLoader) of(parent model.TokenId) ([]*model.NFT, error) {
+	for {
+		if children, ok := loader.loaded[parent]; ok {
+			return children, nil
+		}
+		if len(loader.next) == 0 {
+			return []*model.NFT{}, nil
+		}
+
+		children, err := loader.nfts.ChildrenOf(loader.chainName, loader.next)
+		if err != nil {
+			return nil, xerrors.Errorf("%w", err)
+		}
+		for _, id := range loader.next {
+			loader.loaded[id] = []*model.NFT{}
+		}
+		loader.next = make([]model.TokenId, 0, len(children))
+		for _, nft := range children {
+			loader.loaded[nft.Parent] = append(loader.loaded[nft.Parent], nft)
+			loader.next = append(loader.next, nft.NFTID)
+		}
+	}
+}
+
+// Suggest suggests next buyable NFT if current NFT is full-shilled (recursively), returns an NFT w/ same owner in children.
+// Children are loaded one tree level at a time.
+func Suggest(nfts NFTs, nft *model.NFT, base_nft *model.NFT) (next_nft *model.NFT, err error) {
+	return suggest(newChildrenLoader(nfts, nft), nft, base_nft)
+}
+
+func suggest(children_of *childrenLoader, nft *model.NFT, base_nft *model.NFT) (next_nft *model.NFT, err error) {
+	// Beginning of recursive
+	if base_nft == nil {
+		base_nft = nft
+	}
+
+	// Current NFT has room to shill. Return itself.
+	if nft.CanShill() && nft.Owner == base_nft.Owner {
+		return nft, nil
+	}
+
+	children, err := children_of.of(nft.NFTID)
+	if err != nil {
+		return nil, xerrors.Errorf("%w", err)
+	}
+
+	// Breadth-first search. Strict check Owner for maximum owner profit.
+	for _, child := range children {
+		if child.CanShill() && child.Owner == base_nft.Owner {
+			return child, nil
+		}
+	}
+
+	// Search again, emit owner check.
+	for _, child := range children {
+		if child.CanShill() {
+			return child, nil
+		}
+	}
+
+	// Current depth not found. Start recursion.
+	for _, child := range children {
+		next_nft, err = suggest(children_of, child, base_nft)
+		if err != nil {
+			return nil, err
+		}
+		if next_nft != nil {
+			return next_nft, nil
+		}
+	}
+
+	// Not found.
+	return nil, nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	chainName = "ethereum"
+)
+
+func insert_nfts(t *testing.T, store Store, nfts []*model.NFT) NFTs {
+	for _, nft := range nfts {
+		nft.Chain = chainName
+	}
+	require.Nil(t, store.NFTs().Create(nfts))
+	return store.NFTs()
+}
+
+func insert_nft_testdata(t *testing.T, store Store) NFTs {
+	return insert_nfts(t, store, []*model.NFT{
+		{NFTID: 0x300000001, Parent: 0x0, ShillCount: 3, MaxShillCount: 10, Owner: "0xA"}, // 3 children
+		{NFTID: 0x300000002, Parent: 0x300000001, ShillCount: 0, MaxShillCount: 10, Owner: "0xB"},
+		{NFTID: 0x300000003, Parent: 0x300000001, ShillCount: 3, MaxShillCount: 10, Owner: "0xC"}, // 3 children
+		{NFTID: 0x300000004, Parent: 0x300000001, ShillCount: 0, MaxShillCount: 10, Owner: "0xD"},
+		{NFTID: 0x300000005, Parent: 0x300000003, ShillCount: 0, MaxShillCount: 10, Owner: "0xE"},
+		{NFTID: 0x300000006, Parent: 0x300000003, ShillCount: 0, MaxShillCount: 10, Owner: "0xF"},
+		{NFTID: 0x300000007, Parent: 0x300000003, ShillCount: 0, MaxShillCount: 10, Owner: "0x10"},
+	})
+}
+
+func insert_suggest_nft_data(t *testing.T, store Store) NFTs {
+	return insert_nfts(t, store, []*model.NFT{
+		{NFTID: 0x300000001, Parent: 0x0, ShillCount: 10, MaxShillCount: 10, Owner: "0xA"},
+		{NFTID: 0x300000002, Parent: 0x300000001, ShillCount: 10, MaxShillCount: 10, Owner: "0xB"},
+		{NFTID: 0x300000003, Parent: 0x300000001, ShillCount: 5, MaxShillCount: 10, Owner: "0xC"},
+		{NFTID: 0x300000004, Parent: 0x300000001, ShillCount: 5, MaxShillCount: 10, Owner: "0xA"},
+		{NFTID: 0x300000005, Parent: 0x300000002, ShillCount: 5, MaxShillCount: 10, Owner: "0xA"},
+		{NFTID: 0x300000006, Parent: 0x300000001, ShillCount: 10, MaxShillCount: 10, Owner: "0xE"},
+	})
+}
+
+func Test_ChildrenCount(t *testing.T) {
+	each_store(t, func(t *testing.T, store Store) {
+		nfts := insert_nft_testdata(t, store)
+
+		// Root NFT
+		result, err := ChildrenCount(nfts, chainName, model.TokenId(0x300000001))
+		assert.Nil(t, err)
+		assert.Equal(t, 6, result)
+
+		// NFT with less children
+		result, err = ChildrenCount(nfts, chainName, model.TokenId(0x300000003))
+		assert.Nil(t, err)
+		assert.Equal(t, 3, result)
+
+		// NFT found, but no children
+		result, err = ChildrenCount(nfts, chainName, model.TokenId(0x300000002))
+		assert.Nil(t, err)
+		assert.Equal(t, 0, result)
+
+		// No NFT found
+		result, err = ChildrenCount(nfts, chainName, model.TokenId(0x300000010))
+		assert.Nil(t, err)
+		assert.Equal(t, 0, result)
+	})
+}
+
+func Test_ChildrenOf(t *testing.T) {
+	each_store(t, func(t *testing.T, store Store) {
+		nfts := insert_nft_testdata(t, store)
+
+		children, err := nfts.ChildrenOf(chainName, []model.TokenId{0x300000003, 0x300000002})
+		assert.Nil(t, err)
+		require.Len(t, children, 3)
+		assert.Equal(t, model.TokenId(0x300000005), children[0].NFTID)
+
+		children, err = nfts.ChildrenOf(chainName, []model.TokenId{})
+		assert.Nil(t, err)
+		assert.Empty(t, children)
+	})
+}
+
+func Test_ChildrenTree(t *testing.T) {
+	each_store(t, func(t *testing.T, store Store) {
+		nfts := insert_nft_testdata(t, store)
+
+		tree, err := ChildrenTree(nfts, chainName, model.TokenId(0x300000001))
+		assert.Nil(t, err)
+		assert.Equal(t, model.TokenId(0x300000001).String(), tree.NFTID)
+		assert.Equal(t, 3, len(tree.Children))
+		found := false
+		for _, n := range tree.Children {
+			if n.NFTID == model.TokenId(0x300000003).String() {
+				assert.Equal(t, 3, len(n.Children))
+				found = true
+			}
+		}
+		assert.True(t, found)
+	})
+}
+
+func Test_Suggest(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		each_store(t, func(t *testing.T, store Store) {
+			nfts := insert_suggest_nft_data(t, store)
+
+			nft, _ := nfts.Find(chainName, model.TokenId(0x300000001))
+			next, err := Suggest(nfts, nft, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, model.TokenId(0x300000004), next.NFTID)
+		})
+	})
+
+	t.Run("returns self", func(t *testing.T) {
+		each_store(t, func(t *testing.T, store Store) {
+			nfts := insert_suggest_nft_data(t, store)
+
+			nft, _ := nfts.Find(chainName, model.TokenId(0x300000004))
+			next, err := Suggest(nfts, nft, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, model.TokenId(0x300000004), next.NFTID)
+		})
+	})
+
+	t.Run("returns different owner", func(t *testing.T) {
+		each_store(t, func(t *testing.T, store Store) {
+			nfts := insert_suggest_nft_data(t, store)
+
+			nft, _ := nfts.Find(chainName, model.TokenId(0x300000002))
+			next, err := Suggest(nfts, nft, nil)
+			assert.Nil(t, err)
+			assert.NotNil(t, next)
+			assert.Equal(t, model.TokenId(0x300000005), next.NFTID)
+		})
+	})
+
+	t.Run("returns nil", func(t *testing.T) {
+		each_store(t, func(t *testing.T, store Store) {
+			nfts := insert_suggest_nft_data(t, store)
+
+			nft, _ := nfts.Find(chainName, model.TokenId(0x300000006))
+			next, err := Suggest(nfts, nft, nil)
+			assert.Nil(t, err)
+			assert.Nil(t, next)
+		})
+	})
+}
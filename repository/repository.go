@@ -0,0 +1,131 @@
+// Package repository hides storage behind interfaces, so that handlers
+// and workers can be given a SQL store in production and an
+// in-memory one in unit tests.
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SparkNFT/key_server/model"
+)
+
+// NFTs stores NFT relationships. Find returns model.ErrNFTNotFound if
+// NFT is missing.
+type NFTs interface {
+	Find(chainName string, nft_id model.TokenId) (*model.NFT, error)
+	FindByOwner(chainName string, owner string) ([]*model.NFT, error)
+	// ChildrenOf returns direct children of all given parents, ordered
+	// by creation.
+	ChildrenOf(chainName string, parents []model.TokenId) ([]*model.NFT, error)
+	Create(nfts []*model.NFT) error
+	// Update saves given columns of a NFT.
+	Update(nft *model.NFT, cols ...string) error
+	Delete(chainName string, nft_id model.TokenId) error
+}
+
+// Events stores parsed contract events. Events are identified by
+// (chain, tx_hash, event_index).
+type Events interface {
+	// CreateIgnoreExisting saves events not saved yet.
+	CreateIgnoreExisting(events []*model.Event) (affected int64, err error)
+	FindInBlock(chainName string, height uint64) ([]*model.Event, error)
+	// FindPublishBetween returns Publish events of blocks from..to
+	// (inclusive), in the order they happened.
+	FindPublishBetween(chainName string, from, to uint64) ([]*model.Event, error)
+	// HasLaterTransfer tells if a Transfer of the same NFT after given
+	// event is saved.
+	HasLaterTransfer(event *model.Event) (bool, error)
+	// FindLatestTransfer returns model.ErrEventNotFound if no Transfer
+	// of the NFT is saved.
+	FindLatestTransfer(chainName string, nft_id model.TokenId) (*model.Event, error)
+	Update(event *model.Event) error
+	Delete(id uint64) error
+}
+
+// Keys stores encryption keys of issues. Create returns
+// model.ErrKeyExists and Get returns model.ErrKeyNotFound.
+type Keys interface {
+	Create(chainName string, owner string, nft_id model.TokenId) (*model.Key, error)
+	Get(chainName string, nft_id model.TokenId) (string, error)
+}
+
+// BlockLogs stores scanner progress: BlockLogs of blocks being scanned
+// and the checkpoint of every chain. Start returns
+// model.ErrBlockLogExists if height was started before.
+type BlockLogs interface {
+	Start(chainName string, height uint64) error
+	Finish(chainName string, height uint64) error
+	// FindLatestScanned returns model.ErrBlockLogNotFound if no block
+	// is scanned yet.
+	FindLatestScanned(chainName string) (*model.BlockLog, error)
+	Clean(chainName string, keep_count uint64, keep_age time.Duration) (affected int64, err error)
+
+	// FindCheckpoint returns model.ErrCheckpointNotFound if chain is
+	// never scanned.
+	FindCheckpoint(chainName string) (*model.Checkpoint, error)
+	SaveCheckpoint(chainName string, height uint64, hash string) error
+}
+
+// TelegramBinds stores ERC20 addresses bound by Telegram admins and
+// groups following them.
+type TelegramBinds interface {
+	Create(admin_id int64, erc20_address, erc20_name, erc20_symbol string) (*model.TelegramBind, error)
+	// FindBy returns binds matching non-zero fields of condition.
+	FindBy(condition *model.TelegramBind) ([]*model.TelegramBind, error)
+	Exist(condition *model.TelegramBind) (bool, error)
+	// Delete deletes binds with their groups.
+	Delete(binds []*model.TelegramBind) (affected int64, err error)
+
+	CreateGroup(bind *model.TelegramBind, chat_id int64, chat_title string) (*model.TelegramGroup, error)
+	Groups(bind *model.TelegramBind) ([]*model.TelegramGroup, error)
+
+	// FindCursor returns model.ErrTGCursorNotFound if nothing is
+	// announced to group yet.
+	FindCursor(group *model.TelegramGroup) (*model.TelegramCursor, error)
+	SaveCursor(cursor *model.TelegramCursor) error
+}
+
+// ERC20Tokens caches metadata of ERC20 contracts. Find returns
+// model.ErrERC20TokenNotFound if token is not cached yet.
+type ERC20Tokens interface {
+	Find(chainName string, address string) (*model.ERC20Token, error)
+	Save(token *model.ERC20Token) error
+}
+
+// RateLimitBuckets stores token buckets shared by all instances using
+// the same store.
+type RateLimitBuckets interface {
+	// Update locks bucket of key, calls fn to modify it and saves the
+	// result. A bucket is created with initial value if not exists yet.
+	Update(key string, initial model.RateLimitBucket, fn func(bucket *model.RateLimitBucket)) error
+	// Clean deletes buckets not touched since given time.
+	Clean(before time.Time) (affected int64, err error)
+}
+
+// Lock is an exclusive lock of a name, held until released or lost.
+type Lock interface {
+	// Alive returns error if lock is lost.
+	Alive(ctx context.Context) error
+	Release() error
+}
+
+// Store gives access to all repositories.
+type Store interface {
+	NFTs() NFTs
+	Events() Events
+	Keys() Keys
+	BlockLogs() BlockLogs
+	TelegramBinds() TelegramBinds
+	ERC20Tokens() ERC20Tokens
+	RateLimitBuckets() RateLimitBuckets
+
+	// TryLock acquires lock of given name without waiting. Returns nil
+	// lock if it is held by others.
+	TryLock(ctx context.Context, name string) (Lock, error)
+
+	// Transaction runs fn with a store whose changes are committed
+	// only if fn returns nil.
+	Transaction(fn func(tx Store) error) error
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SparkNFT/key_server/model"
+	"golang.org/x/xerrors"
+	"xorm.io/xorm"
+)
+
+// SQL keeps everything in a Postgres or SQLite DB through model
+// functions.
+type SQL struct {
+	engine *xorm.Engine
+	db     xorm.Interface // engine, or session inside a transaction
+}
+
+func NewSQL(engine *xorm.Engine) *SQL {
+	return &SQL{engine: engine, db: engine}
+}
+
+func (store *SQL) NFTs() NFTs                   { return sqlNFTs{store.db} }
+func (store *SQL) Events() Events               { return sqlEvents{store.db} }
+func (store *SQL) Keys() Keys                   { return sqlKeys{store.db} }
+func (store *SQL) BlockLogs() BlockLogs         { return sqlBlockLogs{store.db} }
+func (store *SQL) TelegramBinds() TelegramBinds { return sqlTelegramBinds{store.db} }
+func (store *SQL) ERC20Tokens() ERC20Tokens     { return sqlERC20Tokens{store.db} }
+
+// RateLimitBuckets runs in transactions of its own, even inside
+// Transaction.
+func (store *SQL) RateLimitBuckets() RateLimitBuckets { return sqlRateLimitBuckets{store.engine} }
+
+// TryLock takes a Postgres advisory lock, held by a dedicated
+// connection.
+func (store *SQL) TryLock(ctx context.Context, name string) (Lock, error) {
+	lock, err := model.TryAdvisoryLock(ctx, store.engine, name)
+	if err != nil || lock == nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// Transaction runs fn in a DB transaction. Nested calls join the outer
+// transaction.
+func (store *SQL) Transaction(fn func(tx Store) error) (err error) {
+	if _, in_tx := store.db.(*xorm.Session); in_tx {
+		return fn(store)
+	}
+
+	session := store.engine.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return xerrors.Errorf("error when starting transaction: %w", err)
+	}
+	if err = fn(&SQL{engine: store.engine, db: session}); err != nil {
+		session.Rollback()
+		return err
+	}
+	if err = session.Commit(); err != nil {
+		return xerrors.Errorf("error when commiting changes: %w", err)
+	}
+	return nil
+}
+
+func (store *SQL) Ping(ctx context.Context) error {
+	if err := store.engine.PingContext(ctx); err != nil {
+		return xerrors.Errorf("error when pinging DB: %w", err)
+	}
+	return nil
+}
+
+type sqlNFTs struct{ db xorm.Interface }
+
+func (repo sqlNFTs) Find(chainName string, nft_id model.TokenId) (*model.NFT, error) {
+	return model.FindNFT(repo.db, chainName, nft_id)
+}
+
+func (repo sqlNFTs) FindByOwner(chainName string, owner string) ([]*model.NFT, error) {
+	return model.FindNFTsByOwner(repo.db, chainName, owner)
+}
+
+func (repo sqlNFTs) ChildrenOf(chainName string, parents []model.TokenId) ([]*model.NFT, error) {
+	return model.FindNFTChildren(repo.db, chainName, parents)
+}
+
+func (repo sqlNFTs) Create(nfts []*model.NFT) error {
+	return model.CreateNFTs(repo.db, nfts)
+}
+
+func (repo sqlNFTs) Update(nft *model.NFT, cols ...string) error {
+	return model.UpdateNFT(repo.db, nft, cols...)
+}
+
+func (repo sqlNFTs) Delete(chainName string, nft_id model.TokenId) error {
+	return model.DeleteNFT(repo.db, chainName, nft_id)
+}
+
+type sqlEvents struct{ db xorm.Interface }
+
+func (repo sqlEvents) CreateIgnoreExisting(events []*model.Event) (int64, error) {
+	return model.InsertEventsIgnoreExisting(repo.db, events)
+}
+
+func (repo sqlEvents) FindInBlock(chainName string, height uint64) ([]*model.Event, error) {
+	return model.FindEventsInBlock(repo.db, chainName, height)
+}
+
+func (repo sqlEvents) FindPublishBetween(chainName string, from, to uint64) ([]*model.Event, error) {
+	return model.FindPublishEventsBetween(repo.db, chainName, from, to)
+}
+
+func (repo sqlEvents) HasLaterTransfer(event *model.Event) (bool, error) {
+	return model.HasLaterTransfer(repo.db, event)
+}
+
+func (repo sqlEvents) FindLatestTransfer(chainName string, nft_id model.TokenId) (*model.Event, error) {
+	return model.FindLatestTransfer(repo.db, chainName, nft_id)
+}
+
+func (repo sqlEvents) Update(event *model.Event) error {
+	return model.UpdateEvent(repo.db, event)
+}
+
+func (repo sqlEvents) Delete(id uint64) error {
+	return model.DeleteEvent(repo.db, id)
+}
+
+type sqlKeys struct{ db xorm.Interface }
+
+func (repo sqlKeys) Create(chainName string, owner string, nft_id model.TokenId) (*model.Key, error) {
+	return model.CreateKey(repo.db, chainName, owner, nft_id)
+}
+
+func (repo sqlKeys) Get(chainName string, nft_id model.TokenId) (string, error) {
+	return model.GetKey(repo.db, chainName, nft_id)
+}
+
+type sqlBlockLogs struct{ db xorm.Interface }
+
+func (repo sqlBlockLogs) Start(chainName string, height uint64) error {
+	return model.BlockLogStart(repo.db, chainName, height)
+}
+
+func (repo sqlBlockLogs) Finish(chainName string, height uint64) error {
+	return model.BlockLogFinish(repo.db, chainName, height)
+}
+
+func (repo sqlBlockLogs) FindLatestScanned(chainName string) (*model.BlockLog, error) {
+	return model.BlockLogFindFirst(repo.db, chainName)
+}
+
+func (repo sqlBlockLogs) Clean(chainName string, keep_count uint64, keep_age time.Duration) (int64, error) {
+	return model.BlockLogClean(repo.db, chainName, keep_count, keep_age)
+}
+
+func (repo sqlBlockLogs) FindCheckpoint(chainName string) (*model.Checkpoint, error) {
+	return model.CheckpointFind(repo.db, chainName)
+}
+
+func (repo sqlBlockLogs) SaveCheckpoint(chainName string, height uint64, hash string) error {
+	return model.CheckpointSave(repo.db, chainName, height, hash)
+}
+
+type sqlTelegramBinds struct{ db xorm.Interface }
+
+func (repo sqlTelegramBinds) Create(admin_id int64, erc20_address, erc20_name, erc20_symbol string) (*model.TelegramBind, error) {
+	return model.CreateTGBind(repo.db, admin_id, erc20_address, erc20_name, erc20_symbol)
+}
+
+func (repo sqlTelegramBinds) FindBy(condition *model.TelegramBind) ([]*model.TelegramBind, error) {
+	return model.FindTGBindBy(repo.db, condition)
+}
+
+func (repo sqlTelegramBinds) Exist(condition *model.TelegramBind) (bool, error) {
+	found, err := repo.db.Exist(condition)
+	if err != nil {
+		return false, xerrors.Errorf("error when finding TelegramBind: %w", err)
+	}
+	return found, nil
+}
+
+func (repo sqlTelegramBinds) Delete(binds []*model.TelegramBind) (int64, error) {
+	return model.DeleteTGBind(repo.db, binds)
+}
+
+func (repo sqlTelegramBinds) CreateGroup(bind *model.TelegramBind, chat_id int64, chat_title string) (*model.TelegramGroup, error) {
+	return model.CreateTGGroup(repo.db, bind, chat_id, chat_title)
+}
+
+func (repo sqlTelegramBinds) Groups(bind *model.TelegramBind) ([]*model.TelegramGroup, error) {
+	return bind.TelegramGroups(repo.db)
+}
+
+func (repo sqlTelegramBinds) FindCursor(group *model.TelegramGroup) (*model.TelegramCursor, error) {
+	return model.TGCursorFind(repo.db, group.Id)
+}
+
+func (repo sqlTelegramBinds) SaveCursor(cursor *model.TelegramCursor) error {
+	return model.TGCursorSave(repo.db, cursor)
+}
+
+type sqlERC20Tokens struct{ db xorm.Interface }
+
+func (repo sqlERC20Tokens) Find(chainName string, address string) (*model.ERC20Token, error) {
+	return model.ERC20TokenFind(repo.db, chainName, address)
+}
+
+func (repo sqlERC20Tokens) Save(token *model.ERC20Token) error {
+	return model.ERC20TokenSave(repo.db, token)
+}
+
+type sqlRateLimitBuckets struct{ engine *xorm.Engine }
+
+func (repo sqlRateLimitBuckets) Update(key string, initial model.RateLimitBucket, fn func(bucket *model.RateLimitBucket)) error {
+	return model.RateLimitBucketUpdate(repo.engine, key, initial, fn)
+}
+
+func (repo sqlRateLimitBuckets) Clean(before time.Time) (int64, error) {
+	return model.RateLimitBucketClean(repo.engine, before)
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/model"
+)
+
+func TestMain(m *testing.M) {
+	// SQL store runs against a fresh SQLite database, no external
+	// service needed.
+	dir, err := os.MkdirTemp("", "spark_repository_test")
+	if err != nil {
+		panic(err)
+	}
+	config.C.DB = config.DBConfig{
+		Driver:      config.DBDriverSQLite,
+		Path:        filepath.Join(dir, "test.db"),
+		AutoMigrate: true,
+	}
+	model.Init()
+	result := m.Run()
+	model.Engine.Close()
+	os.RemoveAll(dir)
+	os.Exit(result)
+}
+
+// each_store runs fn as a subtest with every Store implementation, each
+// one empty.
+func each_store(t *testing.T, fn func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemory())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		model.Engine.Where("1 = 1").Delete(new(model.NFT))
+		fn(t, NewSQL(model.Engine))
+	})
+}
@@ -1,10 +1,12 @@
 package telegram
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/SparkNFT/key_server/chain"
+	"github.com/SparkNFT/key_server/config"
 	"github.com/SparkNFT/key_server/model"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/looplab/fsm"
@@ -127,7 +129,7 @@ func GetConversation(m tele.Context) (stateMachine *Conversation) {
 
 // EmitEvent triggers a status change event
 func (conv *Conversation) EmitEvent(event ConversationEvent) (err error) {
-	conv.Error = conv.FSM.Event(string(event))
+	conv.Error = conv.FSM.Event(context.Background(), string(event))
 	return conv.Error
 }
 
@@ -139,7 +141,7 @@ func (conv *Conversation) Reply() (err error) {
 			Menus["main"],
 		)
 		// Ignore BackToTop event error anyway
-		conv.FSM.Event(string(EBackToTop))
+		conv.FSM.Event(context.Background(), string(EBackToTop))
 		return nil
 	}
 
@@ -174,13 +176,13 @@ func (conv *Conversation) SelfDestroy() {
 	delete(Conversations, conv.User)
 }
 
-func (conv *Conversation) top(_ *fsm.Event) {
+func (conv *Conversation) top(_ context.Context, _ *fsm.Event) {
 	conv.Erc20Address = ""
 	conv.Error = nil
 	log.WithFields(logrus.Fields{"user": conv.User}).Debugf("Top")
 }
 
-func (conv *Conversation) before_bind_input_erc20(event *fsm.Event) {
+func (conv *Conversation) before_bind_input_erc20(_ context.Context, event *fsm.Event) {
 	if !common.IsHexAddress(conv.Tele.Text()) {
 		conv.Error = xerrors.Errorf("invalid ERC20 address")
 		event.Cancel(conv.Error)
@@ -188,19 +190,21 @@ func (conv *Conversation) before_bind_input_erc20(event *fsm.Event) {
 	}
 	conv.Erc20Address = conv.Tele.Text()
 	conv.Tele.Reply(fmt.Sprintf("Now querying ERC20 info of %s", conv.Erc20Address))
-	name, symbol, err := chain.GetERC20Info(conv.Erc20Address)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	token, err := erc20TokenOf(ctx, tokens, config.Get().Telegram.ChainName(), conv.Erc20Address)
 	if err != nil {
 		conv.Error = xerrors.Errorf("get ERC20 info failed: %w", err)
 		event.Cancel(conv.Error)
 		return
 	}
-	conv.Erc20Name = name
-	conv.Erc20Symbol = symbol
+	conv.Erc20Name = token.Name
+	conv.Erc20Symbol = token.Symbol
 	conv.LogDebug("Inputed ERC20")
 }
 
-func (conv *Conversation) before_bind_confirm_erc20(event *fsm.Event) {
-	_, err := model.CreateTGBind(conv.User, conv.Erc20Address, conv.Erc20Name, conv.Erc20Symbol)
+func (conv *Conversation) before_bind_confirm_erc20(_ context.Context, event *fsm.Event) {
+	_, err := binds.Create(conv.User, conv.Erc20Address, conv.Erc20Name, conv.Erc20Symbol)
 	if err != nil {
 		conv.Error = err
 		event.Cancel(conv.Error)
@@ -208,8 +212,8 @@ func (conv *Conversation) before_bind_confirm_erc20(event *fsm.Event) {
 	conv.LogDebug("Saved ERC20 to DB. Error: %+v", err)
 }
 
-func (conv *Conversation) list_erc20_binding(_ *fsm.Event) {
-	tg_binds, err := model.FindTGBindBy(&model.TelegramBind{AdminID: conv.AdminID()})
+func (conv *Conversation) list_erc20_binding(_ context.Context, _ *fsm.Event) {
+	tg_binds, err := binds.FindBy(&model.TelegramBind{AdminID: conv.AdminID()})
 	if err != nil {
 		conv.Error = err
 		conv.Reply()
@@ -232,7 +236,7 @@ func (conv *Conversation) list_erc20_binding(_ *fsm.Event) {
 	})
 }
 
-func (conv *Conversation) before_unbind_input_erc20(event *fsm.Event) {
+func (conv *Conversation) before_unbind_input_erc20(_ context.Context, event *fsm.Event) {
 	erc20 := conv.Tele.Text()
 	if !common.IsHexAddress(erc20) {
 		conv.Error = xerrors.Errorf("invalid ERC20 address")
@@ -240,7 +244,7 @@ func (conv *Conversation) before_unbind_input_erc20(event *fsm.Event) {
 		return
 	}
 
-	found, err := model.Engine.Exist(&model.TelegramBind{ERC20Address: erc20, AdminID: conv.AdminID()})
+	found, err := binds.Exist(&model.TelegramBind{ERC20Address: erc20, AdminID: conv.AdminID()})
 	if err != nil {
 		conv.Error = xerrors.Errorf("error when fetching ERC20 record: %w", err)
 		event.Cancel(conv.Error)
@@ -255,8 +259,8 @@ func (conv *Conversation) before_unbind_input_erc20(event *fsm.Event) {
 	conv.Erc20Address = erc20
 }
 
-func (conv *Conversation) before_unbind_confirm_erc20(event *fsm.Event) {
-	results, err := model.FindTGBindBy(&model.TelegramBind{
+func (conv *Conversation) before_unbind_confirm_erc20(_ context.Context, event *fsm.Event) {
+	results, err := binds.FindBy(&model.TelegramBind{
 		ERC20Address: conv.Erc20Address,
 		AdminID:      conv.AdminID(),
 	})
@@ -266,7 +270,7 @@ func (conv *Conversation) before_unbind_confirm_erc20(event *fsm.Event) {
 		return
 	}
 
-	count, err := model.DeleteTGBind(results)
+	count, err := binds.Delete(results)
 	if err != nil {
 		conv.Error = xerrors.Errorf("error when deleting binding: %w", err)
 		event.Cancel(conv.Error)
@@ -0,0 +1,83 @@
+package telegram
+
+import (
+	"context"
+	"time"
+
+	"github.com/SparkNFT/key_server/chain"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+// erc20RefreshAfter is how long cached ERC20 metadata is used before
+// being queried again. Only total supply is expected to change.
+const erc20RefreshAfter = 24 * time.Hour
+
+// erc20InfoOf queries ERC20 metadata on chain. Replaced in tests.
+var erc20InfoOf = func(ctx context.Context, chainName string, address common.Address) (*chain.ERC20Info, error) {
+	pool, err := chain.PoolOf(chainName)
+	if err != nil {
+		return nil, err
+	}
+	return chain.ERC20InfoOf(ctx, pool, address)
+}
+
+// erc20TokenOf returns ERC20 metadata at address on given chain, or
+// native currency of chain for 0x0. Results are cached in tokens, and
+// an outdated one is used if chain is unreachable.
+func erc20TokenOf(ctx context.Context, tokens repository.ERC20Tokens, chainName, address string) (*model.ERC20Token, error) {
+	if chain.IsNativeToken(address) {
+		native, err := chain.NativeTokenOf(chainName)
+		if err != nil {
+			return nil, err
+		}
+		return erc20TokenFrom(chainName, chain.NativeTokenAddress, native), nil
+	}
+	if !common.IsHexAddress(address) {
+		return nil, xerrors.Errorf("%w: %s", model.ErrInvalidAddress, address)
+	}
+
+	var cached *model.ERC20Token
+	found, err := tokens.Find(chainName, address)
+	switch {
+	case err == nil && time.Since(found.UpdatedAt) < erc20RefreshAfter:
+		return found, nil
+	case err == nil:
+		cached = found
+	case !xerrors.Is(err, model.ErrERC20TokenNotFound):
+		return nil, err
+	}
+
+	l := logrus.WithFields(logrus.Fields{"module": "telegram", "chain": chainName, "address": address})
+	info, err := erc20InfoOf(ctx, chainName, common.HexToAddress(address))
+	if err != nil {
+		if cached != nil {
+			l.Warnf("Using outdated ERC20 info: %s", err.Error())
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	token := erc20TokenFrom(chainName, common.HexToAddress(address), info)
+	if err := tokens.Save(token); err != nil {
+		l.Warnf("Failed to cache ERC20 info: %s", err.Error())
+	}
+	return token, nil
+}
+
+func erc20TokenFrom(chainName string, address common.Address, info *chain.ERC20Info) *model.ERC20Token {
+	token := &model.ERC20Token{
+		Chain:    chainName,
+		Address:  address.Hex(),
+		Name:     info.Name,
+		Symbol:   info.Symbol,
+		Decimals: info.Decimals,
+	}
+	if info.TotalSupply != nil {
+		token.TotalSupply = info.TotalSupply.String()
+	}
+	return token
+}
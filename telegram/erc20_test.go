@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/chain"
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+// fakeERC20 answers ERC20 queries of any address, counting them.
+type fakeERC20 struct {
+	calls       int
+	fail        bool
+	totalSupply int64
+}
+
+func with_fake_erc20(t *testing.T) *fakeERC20 {
+	erc20 := &fakeERC20{totalSupply: 1000000}
+	original := erc20InfoOf
+	erc20InfoOf = func(ctx context.Context, chainName string, address common.Address) (*chain.ERC20Info, error) {
+		erc20.calls += 1
+		if erc20.fail {
+			return nil, xerrors.New("connection refused")
+		}
+		return &chain.ERC20Info{Name: "Test Token", Symbol: "TEST", Decimals: 6, TotalSupply: big.NewInt(erc20.totalSupply)}, nil
+	}
+	config.Set(&config.Config{Chain: map[string]*config.ChainConfig{
+		"ethereum": {},
+		"polygon":  {NativeCurrency: config.NativeCurrency{Name: "Matic", Symbol: "MATIC", Decimals: 18}},
+	}})
+	t.Cleanup(func() {
+		erc20InfoOf = original
+		config.Set(nil)
+	})
+	return erc20
+}
+
+// outdatedTokens finds every cached token as saved long ago.
+type outdatedTokens struct {
+	repository.ERC20Tokens
+}
+
+func (tokens outdatedTokens) Find(chainName string, address string) (*model.ERC20Token, error) {
+	token, err := tokens.ERC20Tokens.Find(chainName, address)
+	if err == nil {
+		token.UpdatedAt = time.Now().Add(-2 * erc20RefreshAfter)
+	}
+	return token, err
+}
+
+func Test_erc20TokenOf(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("queries and caches", func(t *testing.T) {
+		erc20 := with_fake_erc20(t)
+		tokens := repository.NewMemory().ERC20Tokens()
+
+		token, err := erc20TokenOf(ctx, tokens, "ethereum", tokenAddress)
+		require.Nil(t, err)
+		assert.Equal(t, "Test Token", token.Name)
+		assert.Equal(t, "TEST", token.Symbol)
+		assert.Equal(t, uint8(6), token.Decimals)
+		assert.Equal(t, "1000000", token.TotalSupply)
+		assert.Equal(t, 1, erc20.calls)
+
+		cached, err := erc20TokenOf(ctx, tokens, "ethereum", tokenAddress)
+		require.Nil(t, err)
+		assert.Equal(t, "TEST", cached.Symbol)
+		assert.Equal(t, 1, erc20.calls)
+	})
+
+	t.Run("refreshes outdated cache", func(t *testing.T) {
+		erc20 := with_fake_erc20(t)
+		tokens := outdatedTokens{repository.NewMemory().ERC20Tokens()}
+		_, err := erc20TokenOf(ctx, tokens, "ethereum", tokenAddress)
+		require.Nil(t, err)
+
+		erc20.totalSupply = 2000000
+		token, err := erc20TokenOf(ctx, tokens, "ethereum", tokenAddress)
+		require.Nil(t, err)
+		assert.Equal(t, "2000000", token.TotalSupply)
+
+		erc20.fail = true
+		token, err = erc20TokenOf(ctx, tokens, "ethereum", tokenAddress)
+		require.Nil(t, err, "outdated cache is better than nothing")
+		assert.Equal(t, "2000000", token.TotalSupply)
+	})
+
+	t.Run("not cached and chain unreachable", func(t *testing.T) {
+		erc20 := with_fake_erc20(t)
+		erc20.fail = true
+		_, err := erc20TokenOf(ctx, repository.NewMemory().ERC20Tokens(), "ethereum", tokenAddress)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("native currency", func(t *testing.T) {
+		erc20 := with_fake_erc20(t)
+		tokens := repository.NewMemory().ERC20Tokens()
+		token, err := erc20TokenOf(ctx, tokens, "polygon", "0x0")
+		require.Nil(t, err)
+		assert.Equal(t, "MATIC", token.Symbol)
+		assert.Equal(t, 0, erc20.calls)
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		with_fake_erc20(t)
+		_, err := erc20TokenOf(ctx, repository.NewMemory().ERC20Tokens(), "ethereum", "0xabc123")
+		assert.True(t, xerrors.Is(err, model.ErrInvalidAddress))
+	})
+}
@@ -17,9 +17,10 @@ func sendGreetingMessage(m tele.Context) {
 	}
 	if !is_admin {
 		sendErrorMessage(m, "Only Creator or Administrator of this group can invite me", xerrors.Errorf("wrong role when inviting bot in group"))
+		return
 	}
 
-	binds, err := model.FindTGBindBy(&model.TelegramBind{AdminID: m.Message().Sender.ID})
+	binds, err := binds.FindBy(&model.TelegramBind{AdminID: m.Message().Sender.ID})
 	if err != nil {
 		sendErrorMessage(m, "Error when finding existed ERC20 binding", err)
 		return
@@ -32,6 +33,10 @@ func sendGreetingMessage(m tele.Context) {
 		)
 		return
 	}
+	if err := followBinds(m.Chat(), binds); err != nil {
+		sendErrorMessage(m, "Error when saving this group", err)
+		return
+	}
 
 	message := strings.Builder{}
 	defer message.Reset()
@@ -58,6 +63,28 @@ func sendGreetingMessage(m tele.Context) {
 	}
 }
 
+// followBinds makes chat a group of every bind, so that new issues of
+// their tokens are announced there. Inviting bot again is harmless.
+func followBinds(chat *tele.Chat, bound []*model.TelegramBind) error {
+	for _, bind := range bound {
+		groups, err := binds.Groups(bind)
+		if err != nil {
+			return err
+		}
+		followed := false
+		for _, group := range groups {
+			followed = followed || group.ChatID == chat.ID
+		}
+		if followed {
+			continue
+		}
+		if _, err := binds.CreateGroup(bind, chat.ID, chat.Title); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func sendErrorMessage(m tele.Context, message string, err error) {
 	chatTitle, _ := getChatTitle(m.Chat().ID)
 	m.EditOrReply(
@@ -75,7 +102,7 @@ func sendErrorMessage(m tele.Context, message string, err error) {
 func escapeHtml(text string) (result string) {
 	result = strings.Replace(text, "&", "&amp;", -1)
 	result = strings.Replace(result, "<", "&lt;", -1)
-	result = strings.Replace(result, ">", "&rt;", -1)
+	result = strings.Replace(result, ">", "&gt;", -1)
 	log.Debugf("Before: %s, After: %s", text, result)
 
 	return result
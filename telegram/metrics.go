@@ -0,0 +1,39 @@
+package telegram
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/SparkNFT/key_server/metrics"
+)
+
+// metricsTransport counts failed Bot API calls which send something
+// to chats (sendMessage, editMessageText, ...).
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	response, err := t.next.RoundTrip(req)
+	if !isSendMethod(method) {
+		return response, err
+	}
+
+	if err != nil {
+		metrics.TelegramSendFailures.WithLabelValues(method, "network").Inc()
+	} else if response.StatusCode >= 300 {
+		metrics.TelegramSendFailures.WithLabelValues(method, "status_"+strconv.Itoa(response.StatusCode)).Inc()
+	}
+	return response, err
+}
+
+func isSendMethod(method string) bool {
+	switch method {
+	case "sendMessage", "editMessageText", "editMessageReplyMarkup", "sendPhoto", "forwardMessage", "copyMessage":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,239 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/metrics"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	l "github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+	tele "gopkg.in/tucnak/telebot.v3"
+)
+
+const (
+	// notifierInterval is the pause between two rounds of announcing.
+	notifierInterval = 10 * time.Second
+	// notifierMaxBackoff bounds how long a failing group is skipped.
+	notifierMaxBackoff = time.Hour
+	// notifierBatchBlocks bounds blocks read for a group in one round,
+	// so that a group far behind catches up gradually.
+	notifierBatchBlocks = 1000
+)
+
+// sendNotification posts an HTML message to a chat. Replaced in tests.
+var sendNotification = func(chat_id int64, message string) error {
+	_, err := B.Send(&tele.Chat{ID: chat_id}, message, &tele.SendOptions{
+		DisableWebPagePreview: true,
+		ParseMode:             tele.ModeHTML,
+	})
+	return err
+}
+
+// NotifierWorker announces new issues to groups following their ERC20
+// token until ctx is cancelled. Only Publish events of fully scanned
+// blocks (up to checkpoint) of `telegram.chain` are announced. Every
+// group has a cursor moved right after each message, so an issue is
+// announced once per group across restarts; only a crash between
+// sending and saving the cursor can repeat that one message.
+//
+// Run one instance only, like under a leader lock.
+func NotifierWorker(store repository.Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		notifier := newNotifier(store)
+		for {
+			if err := notifier.round(ctx); err != nil {
+				notifier.log.Warnf("Announcing new issues failed: %s", err.Error())
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(notifierInterval):
+			}
+		}
+	}
+}
+
+// notifier remembers groups failing to be announced to, so that one
+// group never holds back the others.
+type notifier struct {
+	store   repository.Store
+	log     *l.Entry
+	now     func() time.Time
+	backoff map[uint64]*groupBackoff // By TelegramGroup id
+}
+
+type groupBackoff struct {
+	failures uint
+	until    time.Time
+}
+
+func newNotifier(store repository.Store) *notifier {
+	return &notifier{
+		store:   store,
+		log:     l.WithFields(l.Fields{"module": "telegram", "worker": "notifier"}),
+		now:     time.Now,
+		backoff: make(map[uint64]*groupBackoff),
+	}
+}
+
+// round announces new issues to every group not backing off.
+func (n *notifier) round(ctx context.Context) error {
+	chainName := config.Get().Telegram.ChainName()
+	checkpoint, err := n.store.BlockLogs().FindCheckpoint(chainName)
+	if xerrors.Is(err, model.ErrCheckpointNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	binds, err := n.store.TelegramBinds().FindBy(&model.TelegramBind{})
+	if err != nil {
+		return xerrors.Errorf("error when finding TelegramBinds: %w", err)
+	}
+	for _, bind := range binds {
+		groups, err := n.store.TelegramBinds().Groups(bind)
+		if err != nil {
+			return xerrors.Errorf("error when finding groups of TelegramBind %d: %w", bind.Id, err)
+		}
+		for _, group := range groups {
+			if ctx.Err() != nil {
+				return nil
+			}
+			backoff, failing := n.backoff[group.Id]
+			if failing && n.now().Before(backoff.until) {
+				continue
+			}
+			if err := notifyGroup(n.store, chainName, bind, group, checkpoint.BlockHeight); err != nil {
+				n.failed(group, err)
+				continue
+			}
+			delete(n.backoff, group.Id)
+		}
+	}
+	return nil
+}
+
+// failed skips group for a while, doubling with every failure in a
+// row, or as long as Telegram asks to.
+func (n *notifier) failed(group *model.TelegramGroup, err error) {
+	backoff, ok := n.backoff[group.Id]
+	if !ok {
+		backoff = &groupBackoff{}
+		n.backoff[group.Id] = backoff
+	}
+	backoff.failures += 1
+	wait := notifierMaxBackoff
+	if backoff.failures <= 8 {
+		wait = notifierInterval << (backoff.failures - 1)
+	}
+	var flood tele.FloodError
+	if xerrors.As(err, &flood) && flood.RetryAfter > 0 {
+		wait = time.Duration(flood.RetryAfter) * time.Second
+	}
+	if wait > notifierMaxBackoff {
+		wait = notifierMaxBackoff
+	}
+	backoff.until = n.now().Add(wait)
+	n.log.WithFields(l.Fields{"chat_id": group.ChatID, "failures": backoff.failures}).
+		Warnf("Announcing to group failed, retrying in %s: %s", wait, err.Error())
+}
+
+// notifyGroup announces Publish events of bound token after cursor of
+// group, up to scanned height. Groups seen for the first time start
+// after it, without announcing issues of the past.
+func notifyGroup(store repository.Store, chainName string, bind *model.TelegramBind, group *model.TelegramGroup, scanned uint64) error {
+	logger := l.WithFields(l.Fields{"module": "telegram", "worker": "notifier", "chat_id": group.ChatID, "token": bind.ERC20Address})
+	cursor, err := store.TelegramBinds().FindCursor(group)
+	if xerrors.Is(err, model.ErrTGCursorNotFound) {
+		logger.Infof("Announcing issues to group after block %d.", scanned)
+		return store.TelegramBinds().SaveCursor(&model.TelegramCursor{GroupID: group.Id, BlockHeight: scanned + 1})
+	} else if err != nil {
+		return err
+	}
+	if cursor.BlockHeight > scanned {
+		return nil
+	}
+
+	to := scanned
+	if to-cursor.BlockHeight >= notifierBatchBlocks {
+		to = cursor.BlockHeight + notifierBatchBlocks - 1
+	}
+	events, err := store.Events().FindPublishBetween(chainName, cursor.BlockHeight, to)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if cursor.Passed(event) || !strings.EqualFold(event.TokenAddr, bind.ERC20Address) {
+			continue
+		}
+		err := sendNotification(group.ChatID, newIssueMessage(bind, event))
+		switch {
+		case err == nil:
+			metrics.TelegramNotifications.WithLabelValues("sent").Inc()
+			logger.WithField("nft_id", event.NFTId).Infof("New issue announced.")
+		case isUndeliverable(err):
+			metrics.TelegramNotifications.WithLabelValues("skipped").Inc()
+			logger.WithField("nft_id", event.NFTId).Warnf("Cannot announce new issue, skipped: %s", err.Error())
+		default:
+			return xerrors.Errorf("error when sending issue %s: %w", event.NFTId, err)
+		}
+		cursor.BlockHeight, cursor.EventIndex = event.BlockHeight, event.Index+1
+		if err := store.TelegramBinds().SaveCursor(cursor); err != nil {
+			return err
+		}
+	}
+
+	cursor.BlockHeight, cursor.EventIndex = to+1, 0
+	return store.TelegramBinds().SaveCursor(cursor)
+}
+
+// isUndeliverable tells if the message will never be delivered to the
+// chat, however many times it is sent.
+func isUndeliverable(err error) bool {
+	for _, undeliverable := range []error{
+		tele.ErrBotKickedFromGroup,
+		tele.ErrBotKickedFromSuperGroup,
+		tele.ErrChatNotFound,
+		tele.ErrGroupMigrated,
+		tele.ErrBlockedByUser,
+		tele.ErrUserIsDeactivated,
+		tele.ErrNotStartedByUser,
+		tele.ErrNoRightsToSend,
+		tele.ErrMessageTooLong,
+	} {
+		if xerrors.Is(err, undeliverable) {
+			return true
+		}
+	}
+	// Any other "Forbidden": bot is not allowed to post there.
+	var api_err *tele.APIError
+	return xerrors.As(err, &api_err) && api_err.Code == 403
+}
+
+func newIssueMessage(bind *model.TelegramBind, event *model.Event) string {
+	message := strings.Builder{}
+	message.WriteString("New issue on <b>SparkLink</b>!\n\n")
+	message.WriteString(fmt.Sprintf(
+		"<b>%s</b> ($%s) issue #%d by <code>%s</code>\n\n",
+		escapeHtml(bind.ERC20Name),
+		escapeHtml(bind.ERC20Symbol),
+		event.IssueId(),
+		event.To,
+	))
+	message.WriteString(fmt.Sprintf("<a href=\"%s\">View on SparkLink</a>", issueURL(event)))
+	return message.String()
+}
+
+// issueURL links to root NFT of an issue on SparkLink.
+func issueURL(event *model.Event) string {
+	base := config.Get().Telegram.SparkLinkURLBase
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	return base + "NFT/" + event.NFTId.String()
+}
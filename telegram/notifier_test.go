@@ -0,0 +1,196 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	l "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+	tele "gopkg.in/tucnak/telebot.v3"
+)
+
+const (
+	chainName    = "ethereum"
+	tokenAddress = "0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F"
+)
+
+type sentMessage struct {
+	chat_id int64
+	message string
+}
+
+// with_fake_sender records messages instead of sending them. Sending
+// to a chat in failing gives its error.
+func with_fake_sender(t *testing.T, failing map[int64]error) *[]sentMessage {
+	sent := make([]sentMessage, 0)
+	original := sendNotification
+	sendNotification = func(chat_id int64, message string) error {
+		if err := failing[chat_id]; err != nil {
+			return err
+		}
+		sent = append(sent, sentMessage{chat_id, message})
+		return nil
+	}
+	log = l.WithField("module", "telegram")
+	config.Set(&config.Config{Telegram: config.TelegramConfig{SparkLinkURLBase: "https://sparklink.io/#"}})
+	t.Cleanup(func() {
+		sendNotification = original
+		config.Set(nil)
+	})
+	return &sent
+}
+
+func publish(height uint64, index uint, token_addr string, issue_id uint32) *model.Event {
+	return &model.Event{
+		Chain:       chainName,
+		BlockHeight: height,
+		Index:       index,
+		TxHash:      fmt.Sprintf("0x%x", height),
+		Type:        model.EventTypePublish,
+		To:          "0x0000004215285644116b17436372d569a4ed3a1d",
+		NFTId:       model.TokenId(uint64(issue_id) << 32),
+		TokenAddr:   token_addr,
+	}
+}
+
+func new_notifier_store(t *testing.T) (*repository.Memory, *model.TelegramGroup) {
+	store := repository.NewMemory()
+	bind, err := store.TelegramBinds().Create(1337, tokenAddress, "Test <Token>", "TEST")
+	require.Nil(t, err)
+	group, err := store.TelegramBinds().CreateGroup(bind, -100, "Group")
+	require.Nil(t, err)
+	require.Nil(t, store.BlockLogs().SaveCheckpoint(chainName, 100, "0x1"))
+	return store, group
+}
+
+func Test_notifier(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("announces once per group", func(t *testing.T) {
+		sent := with_fake_sender(t, nil)
+		store, group := new_notifier_store(t)
+		notifier := newNotifier(store)
+		_, err := store.Events().CreateIgnoreExisting([]*model.Event{publish(99, 0, tokenAddress, 1)})
+		require.Nil(t, err)
+
+		require.Nil(t, notifier.round(ctx))
+		assert.Empty(t, *sent, "issues before the group joined are not announced")
+		cursor, err := store.TelegramBinds().FindCursor(group)
+		require.Nil(t, err)
+		assert.Equal(t, uint64(101), cursor.BlockHeight)
+
+		_, err = store.Events().CreateIgnoreExisting([]*model.Event{
+			publish(101, 3, "0x7b5b92b0ed1dfeafdbd724b177a7733bda67497f", 2),
+			publish(101, 4, "0x0000000000000000000000000000000000000001", 3),
+			publish(102, 0, tokenAddress, 4),
+			publish(103, 0, tokenAddress, 5),
+		})
+		require.Nil(t, err)
+		require.Nil(t, store.BlockLogs().SaveCheckpoint(chainName, 102, "0x2"))
+
+		require.Nil(t, notifier.round(ctx))
+		require.Len(t, *sent, 2)
+		assert.Equal(t, int64(-100), (*sent)[0].chat_id)
+		assert.Contains(t, (*sent)[0].message, "<b>Test &lt;Token&gt;</b> ($TEST) issue #2")
+		assert.Contains(t, (*sent)[0].message, `<a href="https://sparklink.io/#/NFT/8589934592">`)
+		assert.Contains(t, (*sent)[1].message, "issue #4")
+
+		require.Nil(t, newNotifier(store).round(ctx))
+		assert.Len(t, *sent, 2, "nothing announced twice, even after restart")
+
+		require.Nil(t, store.BlockLogs().SaveCheckpoint(chainName, 103, "0x3"))
+		require.Nil(t, notifier.round(ctx))
+		require.Len(t, *sent, 3)
+		assert.Contains(t, (*sent)[2].message, "issue #5")
+	})
+
+	t.Run("retries a failed message after backoff", func(t *testing.T) {
+		failing := map[int64]error{-100: xerrors.New("connection reset")}
+		sent := with_fake_sender(t, failing)
+		store, group := new_notifier_store(t)
+		notifier := newNotifier(store)
+		now := time.Now()
+		notifier.now = func() time.Time { return now }
+		require.Nil(t, notifier.round(ctx))
+		_, err := store.Events().CreateIgnoreExisting([]*model.Event{publish(101, 0, tokenAddress, 2), publish(101, 1, tokenAddress, 3)})
+		require.Nil(t, err)
+		require.Nil(t, store.BlockLogs().SaveCheckpoint(chainName, 101, "0x2"))
+
+		require.Nil(t, notifier.round(ctx))
+		cursor, err := store.TelegramBinds().FindCursor(group)
+		require.Nil(t, err)
+		assert.Equal(t, uint64(101), cursor.BlockHeight)
+		assert.Equal(t, uint(0), cursor.EventIndex)
+
+		delete(failing, -100)
+		require.Nil(t, notifier.round(ctx))
+		assert.Empty(t, *sent, "backing off")
+
+		now = now.Add(notifierInterval)
+		require.Nil(t, notifier.round(ctx))
+		require.Len(t, *sent, 2)
+		assert.Contains(t, (*sent)[0].message, "issue #2")
+	})
+
+	t.Run("failing group does not hold back others", func(t *testing.T) {
+		sent := with_fake_sender(t, map[int64]error{
+			-100: tele.FloodError{APIError: tele.NewAPIError(429, "Too Many Requests: retry after 600"), RetryAfter: 600},
+		})
+		store, group := new_notifier_store(t)
+		other_bind, err := store.TelegramBinds().Create(1338, tokenAddress, "Test", "TEST")
+		require.Nil(t, err)
+		_, err = store.TelegramBinds().CreateGroup(other_bind, -200, "Other group")
+		require.Nil(t, err)
+		notifier := newNotifier(store)
+		now := time.Now()
+		notifier.now = func() time.Time { return now }
+		require.Nil(t, notifier.round(ctx))
+
+		for i, height := range []uint64{101, 102} {
+			_, err = store.Events().CreateIgnoreExisting([]*model.Event{publish(height, 0, tokenAddress, uint32(height))})
+			require.Nil(t, err)
+			require.Nil(t, store.BlockLogs().SaveCheckpoint(chainName, height, "0x2"))
+			require.Nil(t, notifier.round(ctx))
+			require.Len(t, *sent, i+1)
+			assert.Equal(t, int64(-200), (*sent)[i].chat_id)
+			now = now.Add(time.Minute)
+		}
+		assert.Equal(t, now.Add(-2*time.Minute).Add(600*time.Second), notifier.backoff[group.Id].until, "as long as Telegram asks")
+	})
+
+	t.Run("skips groups bot cannot post to", func(t *testing.T) {
+		for _, gone := range []error{
+			tele.ErrBotKickedFromGroup,
+			tele.ErrBlockedByUser,
+			tele.ErrNoRightsToSend,
+			tele.NewAPIError(403, "Forbidden: bot is not a member of the channel chat"),
+		} {
+			sent := with_fake_sender(t, map[int64]error{-100: gone})
+			store, group := new_notifier_store(t)
+			notifier := newNotifier(store)
+			require.Nil(t, notifier.round(ctx))
+			_, err := store.Events().CreateIgnoreExisting([]*model.Event{publish(101, 0, tokenAddress, 2)})
+			require.Nil(t, err)
+			require.Nil(t, store.BlockLogs().SaveCheckpoint(chainName, 101, "0x2"))
+
+			require.Nil(t, notifier.round(ctx))
+			assert.Empty(t, *sent)
+			assert.Empty(t, notifier.backoff)
+			cursor, err := store.TelegramBinds().FindCursor(group)
+			require.Nil(t, err)
+			assert.Equal(t, uint64(102), cursor.BlockHeight, gone.Error())
+		}
+	})
+
+	t.Run("nothing scanned yet", func(t *testing.T) {
+		with_fake_sender(t, nil)
+		assert.Nil(t, newNotifier(repository.NewMemory()).round(ctx))
+	})
+}
@@ -2,9 +2,11 @@ package telegram
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/repository"
 	l "github.com/sirupsen/logrus"
 	"golang.org/x/xerrors"
 	tele "gopkg.in/tucnak/telebot.v3"
@@ -14,9 +16,15 @@ var (
 	B     *tele.Bot
 	log   *l.Entry
 	Menus = make(map[string]*tele.ReplyMarkup, 0)
+	// binds stores ERC20 bindings of admins and their groups.
+	binds repository.TelegramBinds
+	// tokens caches ERC20 info of bound addresses.
+	tokens repository.ERC20Tokens
 )
 
-func Init(offline bool) {
+func Init(offline bool, store repository.Store) {
+	binds = store.TelegramBinds()
+	tokens = store.ERC20Tokens()
 	if log == nil {
 		log = l.WithFields(l.Fields{
 			"module": "telegram",
@@ -46,11 +54,15 @@ func Init(offline bool) {
 func initBot(offline bool) {
 	var err error
 	B, err = tele.NewBot(tele.Settings{
-		Token: config.C.Telegram.Token,
+		Token: config.Get().Telegram.Token,
 		Poller: &tele.LongPoller{
 			Timeout: 10 * time.Second,
 		},
 		Offline: offline,
+		Client: &http.Client{
+			Timeout:   time.Minute,
+			Transport: &metricsTransport{next: http.DefaultTransport},
+		},
 	})
 	if err != nil {
 		panic(err)
@@ -8,7 +8,6 @@ import (
 	"github.com/SparkNFT/key_server/chain"
 	"github.com/SparkNFT/key_server/config"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,7 +19,7 @@ var (
 	// This address created issue_id: 1
 	Issue1_creator = common.HexToAddress("0x53278E84029130805e2b659A35d8cBc35c7a6f81")
 	Contract *abi.SparkLink
-	Client *ethclient.Client
+	Client chain.Client
 )
 
 func TestMain(m *testing.M) {
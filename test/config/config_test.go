@@ -33,3 +33,33 @@ func Test_Chain(t *testing.T) {
 		assert.False(t, ok)
 	})
 }
+
+func Test_EnableChains(t *testing.T) {
+	configJSON := "{\"chain\": {\"ethereum\": {\"rpc_url\": \"test_ethereum\"}, \"bsc\": {\"rpc_url\": \"test_bsc\"}}}"
+
+	t.Run("enable all", func(t *testing.T) {
+		before_each(t)
+		json.Unmarshal([]byte(configJSON), &config.C)
+		err := config.EnableChains()
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"bsc", "ethereum"}, config.EnabledChains())
+	})
+
+	t.Run("enable given", func(t *testing.T) {
+		before_each(t)
+		json.Unmarshal([]byte(configJSON), &config.C)
+		err := config.EnableChains("bsc")
+		assert.Nil(t, err)
+		assert.True(t, config.IsChainEnabled("bsc"))
+		assert.False(t, config.IsChainEnabled("ethereum"))
+		assert.False(t, config.IsChainEnabled("matic"))
+	})
+
+	t.Run("unknown chain", func(t *testing.T) {
+		before_each(t)
+		json.Unmarshal([]byte(configJSON), &config.C)
+		err := config.EnableChains("matic")
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "matic")
+	})
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+const (
+	samplePath = "../../config/config.sample.json"
+)
+
+func write_config(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.Nil(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func Test_Duration(t *testing.T) {
+	t.Run("strings and seconds", func(t *testing.T) {
+		for input, expected := range map[string]time.Duration{
+			"\"1m30s\"": 90 * time.Second,
+			"\"500ms\"": 500 * time.Millisecond,
+			"\"2\"":     2 * time.Second,
+			"1.5":       1500 * time.Millisecond,
+		} {
+			var duration config.Duration
+			assert.Nil(t, json.Unmarshal([]byte(input), &duration), input)
+			assert.Equal(t, expected, duration.Duration(), input)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var duration config.Duration
+		assert.NotNil(t, json.Unmarshal([]byte("\"soon\""), &duration))
+		assert.NotNil(t, json.Unmarshal([]byte("true"), &duration))
+	})
+
+	t.Run("legacy seconds keys", func(t *testing.T) {
+		chain := config.ChainConfig{}
+		err := json.Unmarshal([]byte("{\"sleep_seconds\": 1, \"fail_sleep_seconds\": 5}"), &chain)
+		assert.Nil(t, err)
+		assert.Equal(t, time.Second, chain.Sleep.Duration())
+		assert.Equal(t, 5*time.Second, chain.FailSleep.Duration())
+	})
+}
+
+func Test_Load(t *testing.T) {
+	t.Run("sample is valid", func(t *testing.T) {
+		loaded, err := config.Load(samplePath, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, time.Second, loaded.Chain["ethereum"].Sleep.Duration())
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		path := write_config(t, "config.yaml", `
+db:
+  driver: sqlite
+  path: dev.db
+chain:
+  ethereum:
+    rpc_url: wss://example.com/ws
+    contract_address: "0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F"
+    block_height: 100
+    sleep: 2s
+    fail_sleep: 1m
+`)
+		loaded, err := config.Load(path, nil)
+		require.Nil(t, err)
+		assert.Equal(t, config.DBDriverSQLite, loaded.DB.Driver)
+		assert.Equal(t, 2*time.Second, loaded.Chain["ethereum"].Sleep.Duration())
+		assert.Equal(t, time.Minute, loaded.Chain["ethereum"].FailSleep.Duration())
+	})
+
+	t.Run("env and overrides win over file", func(t *testing.T) {
+		t.Setenv("SPARK_DB_PASSWORD", "from_env")
+		t.Setenv("SPARK_DB_PORT", "5432")
+		t.Setenv("SPARK_CHAIN_ETHEREUM_RPC_URL", "https://env.example.com")
+		t.Setenv("SPARK_CHAIN_ETHEREUM_SLEEP", "3s")
+		t.Setenv("SPARK_CORS_ALLOW_ORIGINS", "https://a.example.com, https://b.example.com")
+
+		loaded, err := config.Load(samplePath, []string{
+			"chain.ethereum.sleep=4s",
+			"rate_limit.routes.GET /api/v1/nft/info.per_ip.requests=1",
+			"rate_limit.routes.GET /api/v1/nft/info.per_ip.period_seconds=1",
+		})
+		require.Nil(t, err)
+		assert.Equal(t, "from_env", loaded.DB.Password)
+		assert.Equal(t, 5432, loaded.DB.Port)
+		assert.Equal(t, "https://env.example.com", loaded.Chain["ethereum"].RPCUrl)
+		assert.Equal(t, 4*time.Second, loaded.Chain["ethereum"].Sleep.Duration())
+		assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, loaded.Cors.AllowOrigins)
+		assert.Equal(t, uint(1), loaded.RateLimit.Routes["GET /api/v1/nft/info"].PerIP.Requests)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		t.Setenv("SPARK_DB_PORT", "not_a_port")
+		_, err := config.Load(samplePath, []string{"chain.ethereum.nonexist=1", "no_equal_sign"})
+		validation_err := &config.ValidationError{}
+		require.True(t, xerrors.As(err, &validation_err))
+		assert.Len(t, validation_err.Problems, 1)
+		assert.Contains(t, validation_err.Problems[0], "SPARK_DB_PORT")
+
+		t.Setenv("SPARK_DB_PORT", "5432")
+		_, err = config.Load(samplePath, []string{"chain.ethereum.nonexist=1", "no_equal_sign"})
+		require.True(t, xerrors.As(err, &validation_err))
+		assert.Len(t, validation_err.Problems, 2)
+	})
+}
+
+func Test_Validate(t *testing.T) {
+	t.Run("reports every problem", func(t *testing.T) {
+		c := config.Config{
+			DB: config.DBConfig{Driver: config.DBDriverSQLite},
+			Chain: map[string]*config.ChainConfig{
+				"ethereum": {ContractAddress: "0x1234", Sleep: config.Duration(time.Second)},
+			},
+			RateLimit: config.RateLimitConfig{Store: "redis"},
+		}
+		err := c.Validate()
+		validation_err := &config.ValidationError{}
+		require.True(t, xerrors.As(err, &validation_err))
+		assert.Equal(t, []string{
+			"chain.ethereum.block_height: must be the height the contract was deployed at",
+			"chain.ethereum.contract_address: \"0x1234\" is not a 0x-prefixed hex address",
+			"chain.ethereum.fail_sleep: must be positive, like \"5s\"",
+			"chain.ethereum.rpc_url: required",
+			"db.path: required for sqlite",
+			"rate_limit.store: \"redis\" is not one of \"memory\", \"postgres\"",
+		}, validation_err.Problems)
+	})
+
+	t.Run("operator signer", func(t *testing.T) {
+		_, err := config.Load(samplePath, []string{"chain.ethereum.operator.signer=external"})
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), "chain.ethereum.operator.external_url: required")
+
+		_, err = config.Load(samplePath, []string{"chain.ethereum.operator.signer=hsm"})
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), "chain.ethereum.operator.signer")
+	})
+
+	t.Run("telegram chain and native currency", func(t *testing.T) {
+		loaded, err := config.Load(samplePath, nil)
+		require.Nil(t, err)
+		assert.Equal(t, "ETH", loaded.Chain["ethereum"].Native().Symbol)
+		assert.Equal(t, "ethereum", loaded.Telegram.ChainName())
+
+		_, err = config.Load(samplePath, []string{"telegram.chain=etereum", "chain.ethereum.native_currency.symbol="})
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), "telegram.chain: \"etereum\" is not a configured chain")
+		assert.Contains(t, err.Error(), "chain.ethereum.native_currency.symbol: required")
+	})
+
+	t.Run("rpc endpoints", func(t *testing.T) {
+		loaded, err := config.Load(samplePath, nil)
+		require.Nil(t, err)
+		endpoints := loaded.Chain["ethereum"].RPCEndpoints()
+		require.Len(t, endpoints, 2)
+		assert.Equal(t, loaded.Chain["ethereum"].RPCUrl, endpoints[0].URL)
+		assert.Equal(t, 10*time.Second, endpoints[0].Timeout.Duration())
+		assert.Nil(t, endpoints[0].RateLimit)
+		assert.Equal(t, uint(25), endpoints[1].RateLimit.Requests)
+
+		t.Setenv("SPARK_CHAIN_ETHEREUM_RPC_URLS", "https://a.example.com, wss://b.example.com")
+		loaded, err = config.Load(samplePath, []string{"chain.ethereum.rpc_url="})
+		require.Nil(t, err)
+		endpoints = loaded.Chain["ethereum"].RPCEndpoints()
+		require.Len(t, endpoints, 2)
+		assert.Equal(t, "wss://b.example.com", endpoints[1].URL)
+
+		_, err = config.Load(samplePath, []string{"chain.ethereum.rpc_urls=ftp://a.example.com"})
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), "chain.ethereum.rpc_urls[0]")
+	})
+
+	t.Run("scan mode", func(t *testing.T) {
+		loaded, err := config.Load(samplePath, nil)
+		require.Nil(t, err)
+		assert.Equal(t, config.ScanModePoll, loaded.Chain["ethereum"].ScanMode)
+
+		_, err = config.Load(samplePath, []string{"chain.ethereum.scan_mode=subscribe"})
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), "chain.ethereum.scan_mode")
+
+		_, err = config.Load(samplePath, []string{"chain.ethereum.scan_mode=subscribe", "chain.ethereum.rpc_urls=wss://a.example.com"})
+		assert.Nil(t, err)
+
+		_, err = config.Load(samplePath, []string{"chain.ethereum.scan_mode=push"})
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), `"push" is not one of`)
+	})
+
+	t.Run("no chain", func(t *testing.T) {
+		c := config.Config{DB: config.DBConfig{Driver: config.DBDriverSQLite, Path: "dev.db"}}
+		err := c.Validate()
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "at least one chain")
+	})
+}
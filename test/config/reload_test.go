@@ -0,0 +1,107 @@
+package config
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write_sample(t *testing.T, path string, replacer *strings.Replacer) {
+	content, err := os.ReadFile(samplePath)
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(path, []byte(replacer.Replace(string(content))), 0600))
+}
+
+func before_each_reload(t *testing.T) string {
+	before_each(t)
+	path := write_config(t, "config.json", "")
+	write_sample(t, path, strings.NewReplacer())
+	config.ConfigPath = path
+	config.Init()
+	require.Nil(t, config.EnableChains())
+	t.Cleanup(func() { config.Set(nil) })
+	return path
+}
+
+func Test_Reload(t *testing.T) {
+	t.Run("swaps config and calls hooks", func(t *testing.T) {
+		path := before_each_reload(t)
+		assert.Equal(t, time.Second, config.Get().Chain["ethereum"].Sleep.Duration())
+
+		var old_sleep, new_sleep time.Duration
+		config.OnReload(func(old, new *config.Config) {
+			old_sleep = old.Chain["ethereum"].Sleep.Duration()
+			new_sleep = new.Chain["ethereum"].Sleep.Duration()
+		})
+		write_sample(t, path, strings.NewReplacer(`"sleep": "1s"`, `"sleep": "3s"`, `"key": "ffffffffffffffffffff"`, `"key": "rotated"`))
+		require.Nil(t, config.Reload())
+
+		assert.Equal(t, 3*time.Second, config.Get().Chain["ethereum"].Sleep.Duration())
+		assert.Equal(t, "rotated", config.Get().Pinata.Key)
+		assert.True(t, config.IsChainEnabled("ethereum"))
+		assert.Equal(t, time.Second, old_sleep)
+		assert.Equal(t, 3*time.Second, new_sleep)
+		// Startup config is untouched
+		assert.Equal(t, time.Second, config.C.Chain["ethereum"].Sleep.Duration())
+	})
+
+	t.Run("keeps current config if invalid", func(t *testing.T) {
+		path := before_each_reload(t)
+		write_sample(t, path, strings.NewReplacer(`"sleep": "1s"`, `"sleep": "soon"`))
+		assert.NotNil(t, config.Reload())
+		assert.Equal(t, time.Second, config.Get().Chain["ethereum"].Sleep.Duration())
+	})
+
+	t.Run("keeps overrides", func(t *testing.T) {
+		config.Overrides = config.OverrideFlags{"db.auto_migrate=true", "chain.ethereum.sleep=2s"}
+		t.Cleanup(func() { config.Overrides = nil })
+		path := before_each_reload(t)
+		startup := config.Get().DB
+		require.True(t, startup.AutoMigrate)
+
+		write_sample(t, path, strings.NewReplacer(`"key": "ffffffffffffffffffff"`, `"key": "rotated"`))
+		require.Nil(t, config.Reload())
+		assert.Equal(t, startup, config.Get().DB, "db config unchanged")
+		assert.Equal(t, 2*time.Second, config.Get().Chain["ethereum"].Sleep.Duration())
+	})
+
+	t.Run("keeps enabled chains", func(t *testing.T) {
+		path := before_each_reload(t)
+		require.Nil(t, config.EnableChains("ethereum"))
+		write_sample(t, path, strings.NewReplacer(`"chain": {`, `"chain": {
+        "bsc": {
+            "rpc_url": "https://bsc.example.com",
+            "contract_address": "0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F",
+            "block_height": 1,
+            "sleep": "1s",
+            "fail_sleep": "5s"
+        },`))
+		require.Nil(t, config.Reload())
+		assert.Equal(t, []string{"ethereum"}, config.EnabledChains())
+
+		require.Nil(t, config.EnableChains())
+		require.Nil(t, config.Reload())
+		assert.Equal(t, []string{"bsc", "ethereum"}, config.EnabledChains())
+	})
+}
+
+func Test_Watch(t *testing.T) {
+	path := before_each_reload(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go config.Watch(ctx, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	write_sample(t, path, strings.NewReplacer(`"sleep": "1s"`, `"sleep": "2s"`))
+	later := time.Now().Add(time.Minute)
+	require.Nil(t, os.Chtimes(path, later, later))
+	assert.Eventually(t, func() bool {
+		return config.Get().Chain["ethereum"].Sleep.Duration() == 2*time.Second
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func Test_ResolveSecrets(t *testing.T) {
+	secrets := config.FakeSecrets{
+		"spark/db": `{"password": "db_pass", "port": 5432}`,
+		"tg_token": "123:telegram",
+		"not_json": "plain",
+		"privkey":  "0x0123456789012345678901234567890123456789012345678901234567890123",
+	}
+
+	t.Run("success", func(t *testing.T) {
+		c := config.Config{
+			DB: config.DBConfig{Password: "secret://spark/db#password", User: "secret://spark/db#port"},
+			Chain: map[string]*config.ChainConfig{
+				"ethereum": {OperatorAccountPrivateKey: "secret://privkey"},
+			},
+			Telegram: config.TelegramConfig{Token: "secret://tg_token"},
+			Pinata:   config.PinataConfig{Key: "not a secret"},
+		}
+		err := config.ResolveSecrets(context.Background(), &c, secrets)
+		require.Nil(t, err)
+		assert.Equal(t, "db_pass", c.DB.Password)
+		assert.Equal(t, "5432", c.DB.User)
+		assert.Equal(t, secrets["privkey"], c.Chain["ethereum"].OperatorAccountPrivateKey)
+		assert.Equal(t, "123:telegram", c.Telegram.Token)
+		assert.Equal(t, "not a secret", c.Pinata.Key)
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		c := config.Config{
+			DB:       config.DBConfig{Password: "secret://spark/db#nonexist"},
+			Telegram: config.TelegramConfig{Token: "secret://nonexist"},
+			Pinata:   config.PinataConfig{Secret: "secret://not_json#field"},
+		}
+		err := config.ResolveSecrets(context.Background(), &c, secrets)
+		validation_err := &config.ValidationError{}
+		require.True(t, xerrors.As(err, &validation_err))
+		require.Len(t, validation_err.Problems, 3)
+		assert.Contains(t, validation_err.Problems[0], "db.password")
+		assert.Contains(t, validation_err.Problems[1], "pinata.secret")
+		assert.Contains(t, validation_err.Problems[2], "telegram.token")
+	})
+
+	t.Run("provider not configured", func(t *testing.T) {
+		c := config.Config{DB: config.DBConfig{Password: "secret://db"}}
+		err := config.ResolveSecrets(context.Background(), &c, nil)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "secrets.provider")
+	})
+
+	t.Run("in Load", func(t *testing.T) {
+		config.Secrets = secrets
+		defer func() { config.Secrets = nil }()
+		t.Setenv("SPARK_DB_PASSWORD", "secret://spark/db#password")
+
+		loaded, err := config.Load(samplePath, nil)
+		require.Nil(t, err)
+		assert.Equal(t, "db_pass", loaded.DB.Password)
+	})
+}
+
+func Test_SecretProviders(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.Nil(t, os.WriteFile(filepath.Join(dir, "db_password"), []byte("from_file\n"), 0600))
+		provider, err := config.NewSecretProvider(context.Background(), config.SecretsConfig{Provider: config.SecretProviderFile, Dir: dir})
+		require.Nil(t, err)
+
+		value, err := provider.GetSecret(context.Background(), "db_password")
+		assert.Nil(t, err)
+		assert.Equal(t, "from_file", value)
+
+		_, err = provider.GetSecret(context.Background(), "nonexist")
+		assert.True(t, xerrors.Is(err, config.ErrSecretNotFound))
+		_, err = provider.GetSecret(context.Background(), "../db_password")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("TEST_SECRET_TOKEN", "from_env")
+		provider, err := config.NewSecretProvider(context.Background(), config.SecretsConfig{Provider: config.SecretProviderEnv})
+		require.Nil(t, err)
+
+		value, err := provider.GetSecret(context.Background(), "TEST_SECRET_TOKEN")
+		assert.Nil(t, err)
+		assert.Equal(t, "from_env", value)
+		_, err = provider.GetSecret(context.Background(), "TEST_SECRET_NONEXIST")
+		assert.True(t, xerrors.Is(err, config.ErrSecretNotFound))
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := config.NewSecretProvider(context.Background(), config.SecretsConfig{Provider: "vault"})
+		assert.NotNil(t, err)
+	})
+}
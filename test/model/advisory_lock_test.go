@@ -0,0 +1,36 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TryAdvisoryLock(t *testing.T) {
+	t.Run("exclusive", func(t *testing.T) {
+		before_each(t)
+		ctx := context.Background()
+
+		lock, err := model.TryAdvisoryLock(ctx, model.Engine, "test:exclusive")
+		assert.Nil(t, err)
+		assert.NotNil(t, lock)
+		assert.Nil(t, lock.Alive(ctx))
+
+		other, err := model.TryAdvisoryLock(ctx, model.Engine, "test:exclusive")
+		assert.Nil(t, err)
+		assert.Nil(t, other)
+
+		another, err := model.TryAdvisoryLock(ctx, model.Engine, "test:another")
+		assert.Nil(t, err)
+		assert.NotNil(t, another)
+		assert.Nil(t, another.Release())
+
+		assert.Nil(t, lock.Release())
+		lock, err = model.TryAdvisoryLock(ctx, model.Engine, "test:exclusive")
+		assert.Nil(t, err)
+		assert.NotNil(t, lock)
+		assert.Nil(t, lock.Release())
+	})
+}
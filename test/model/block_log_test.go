@@ -3,6 +3,7 @@ package model
 import (
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/SparkNFT/key_server/model"
 	"github.com/stretchr/testify/assert"
@@ -22,8 +23,7 @@ func Test_BlockLogStart(t *testing.T) {
 
 		err = model.BlockLogStart(session, chainName, height)
 		session.Commit()
-		assert.NotNil(t, err)
-		assert.Contains(t, err.Error(), "duplicate key value violates unique constraint")
+		assert.ErrorIs(t, err, model.ErrBlockLogExists)
 	})
 
 	t.Run("success", func(t *testing.T) {
@@ -95,7 +95,7 @@ func Test_BlockLogFindFirst(t *testing.T) {
 		model.BlockLogFinish(session, chainName, height)
 		session.Commit()
 
-		found, err := model.BlockLogFindFirst(chainName)
+		found, err := model.BlockLogFindFirst(model.Engine, chainName)
 		assert.Nil(t, err)
 		assert.Equal(t, height, found.BlockHeight)
 	})
@@ -112,7 +112,7 @@ func Test_BlockLogFindFirst(t *testing.T) {
 		model.BlockLogFinish(session, chainName, height)
 		session.Commit()
 
-		found, err := model.BlockLogFindFirst(chainName)
+		found, err := model.BlockLogFindFirst(model.Engine, chainName)
 		assert.Nil(t, err)
 		assert.Equal(t, height, found.BlockHeight)
 	})
@@ -127,7 +127,7 @@ func Test_BlockLogFindFirst(t *testing.T) {
 		model.BlockLogStart(session, chainName, height)
 		session.Commit()
 
-		found, err := model.BlockLogFindFirst(chainName)
+		found, err := model.BlockLogFindFirst(model.Engine, chainName)
 		assert.NotNil(t, err)
 		assert.Nil(t, found)
 		assert.Contains(t, err.Error(), "found height failed")
@@ -138,6 +138,7 @@ func Test_BlockLogClean(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		before_each(t)
 		log := model.BlockLog{
+			Chain:       chainName,
 			BlockHeight: 1000,
 			Scanned:     true,
 		}
@@ -146,28 +147,70 @@ func Test_BlockLogClean(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, int64(1), affected)
 
-		err = model.BlockLogClean(chainName)
+		_, err = model.BlockLogClean(model.Engine, chainName, 100, 0)
 		assert.Nil(t, err)
 
 		model.Engine.ID(log.Id).Get(&fetched)
 		assert.Equal(t, log.Id, fetched.Id)
 	})
 
-	t.Run("success with log cleaned", func(t *testing.T) {
+	t.Run("by count", func(t *testing.T) {
 		before_each(t)
 
 		for i := uint64(0); i < 150; i++ {
 			model.Engine.Insert(model.BlockLog{
+				Chain:       chainName,
+				BlockHeight: i + 1000,
+				Scanned:     true,
+			})
+			model.Engine.Insert(model.BlockLog{
+				Chain:       "other",
 				BlockHeight: i + 1000,
-				Scanned: true,
+				Scanned:     true,
 			})
 		}
 
-		err := model.BlockLogClean(chainName)
+		affected, err := model.BlockLogClean(model.Engine, chainName, 100, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(50), affected)
+
+		count, err := model.Engine.Count(&model.BlockLog{Chain: chainName})
 		assert.Nil(t, err)
+		assert.Equal(t, int64(100), count)
+		oldest := model.BlockLog{}
+		model.Engine.Where("chain = ?", chainName).Asc("block_height").Get(&oldest)
+		assert.Equal(t, uint64(1050), oldest.BlockHeight)
+
+		// Other chains are untouched
+		count, err = model.Engine.Count(&model.BlockLog{Chain: "other"})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(150), count)
+	})
+
+	t.Run("by age", func(t *testing.T) {
+		before_each(t)
+
+		for i := uint64(0); i < 10; i++ {
+			model.Engine.Insert(model.BlockLog{
+				Chain:       chainName,
+				BlockHeight: i + 1000,
+				Scanned:     true,
+			})
+		}
+		model.Engine.Exec("UPDATE block_logs SET created_at = ? WHERE block_height < ?", time.Now().Add(-2*time.Hour), 1005)
+
+		affected, err := model.BlockLogClean(model.Engine, chainName, 0, time.Hour)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(5), affected)
+	})
+
+	t.Run("latest is kept", func(t *testing.T) {
+		before_each(t)
+		model.Engine.Insert(model.BlockLog{Chain: chainName, BlockHeight: 1000, Scanned: true})
+		model.Engine.Exec("UPDATE block_logs SET created_at = ?", time.Now().Add(-2*time.Hour))
 
-		count, err := model.Engine.Count(&model.BlockLog{})
+		affected, err := model.BlockLogClean(model.Engine, chainName, 0, time.Hour)
 		assert.Nil(t, err)
-		assert.Equal(t, int64(101), count)
+		assert.Equal(t, int64(0), affected)
 	})
 }
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func Test_Checkpoint(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		before_each(t)
+		found, err := model.CheckpointFind(model.Engine, chainName)
+		assert.Nil(t, found)
+		assert.True(t, xerrors.Is(err, model.ErrCheckpointNotFound))
+	})
+
+	t.Run("save and move", func(t *testing.T) {
+		before_each(t)
+		session := model.Engine.NewSession()
+		defer session.Close()
+
+		assert.Nil(t, model.CheckpointSave(session, chainName, 1000, "0xaaaa"))
+		assert.Nil(t, model.CheckpointSave(session, chainName, 1001, "0xbbbb"))
+
+		found, err := model.CheckpointFind(model.Engine, chainName)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(1001), found.BlockHeight)
+		assert.Equal(t, "0xbbbb", found.BlockHash)
+
+		count, err := model.Engine.Count(&model.Checkpoint{})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
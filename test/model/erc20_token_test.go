@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func Test_ERC20Token(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		before_each(t)
+		found, err := model.ERC20TokenFind(model.Engine, chainName, "0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F")
+		assert.Nil(t, found)
+		assert.True(t, xerrors.Is(err, model.ErrERC20TokenNotFound))
+	})
+
+	t.Run("save and refresh", func(t *testing.T) {
+		before_each(t)
+		token := &model.ERC20Token{
+			Chain:       chainName,
+			Address:     "0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F",
+			Name:        "Test Token",
+			Symbol:      "TEST",
+			Decimals:    18,
+			TotalSupply: "1000000000000000000000000000000",
+		}
+		require.Nil(t, model.ERC20TokenSave(model.Engine, token))
+		token.TotalSupply = "2000000000000000000000000000000"
+		require.Nil(t, model.ERC20TokenSave(model.Engine, token))
+
+		found, err := model.ERC20TokenFind(model.Engine, chainName, "0x7b5b92b0ed1dfeafdbd724b177a7733bda67497f")
+		require.Nil(t, err)
+		assert.Equal(t, "0x7b5b92b0ed1dfeafdbd724b177a7733bda67497f", found.Address)
+		assert.Equal(t, "TEST", found.Symbol)
+		assert.Equal(t, uint8(18), found.Decimals)
+		assert.Equal(t, "2000000000000000000000000000000", found.TotalSupply)
+
+		_, err = model.ERC20TokenFind(model.Engine, "other", token.Address)
+		assert.True(t, xerrors.Is(err, model.ErrERC20TokenNotFound))
+	})
+}
@@ -1,11 +1,14 @@
 package model
 
 import (
+	"math/big"
 	"testing"
 
+	"github.com/SparkNFT/key_server/abi"
 	"github.com/SparkNFT/key_server/config"
 	"github.com/SparkNFT/key_server/model"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -83,3 +86,69 @@ func Test_IsMint(t *testing.T) {
 		assert.False(t, event.IsMint())
 	})
 }
+
+func Test_InsertEventsIgnoreExisting(t *testing.T) {
+	t.Run("ignores existing events", func(t *testing.T) {
+		before_each(t)
+		logs := []abi.SparkLinkTransfer{{
+			From:    common.HexToAddress("0x0"),
+			To:      common.HexToAddress("0x0000004215285644116b17436372d569a4ed3a1d"),
+			TokenId: big.NewInt(0x100000001),
+			Raw: types.Log{
+				BlockNumber: 1000,
+				TxHash:      common.HexToHash("0xc7a6953f78c0610518888a8d071a87c16f1df210bf8aeee70053da0305f09e81"),
+				Index:       3,
+			},
+		}}
+
+		events := model.EventsFromTransfer(chainName, logs)
+		affected, err := model.InsertEventsIgnoreExisting(model.Engine, events)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), affected)
+
+		// Processing the same block again
+		affected, err = model.InsertEventsIgnoreExisting(model.Engine, events)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), affected)
+
+		count, err := model.Engine.Count(&model.Event{Chain: chainName})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func Test_EventsFromTransfer(t *testing.T) {
+	t.Run("flags token id out of range", func(t *testing.T) {
+		token_id := new(big.Int).Lsh(big.NewInt(1), 64)
+		logs := []abi.SparkLinkTransfer{{
+			From:    common.HexToAddress("0x0"),
+			To:      common.HexToAddress("0x0000004215285644116b17436372d569a4ed3a1d"),
+			TokenId: token_id,
+			Raw: types.Log{
+				BlockNumber: 1000,
+				TxHash:      common.HexToHash("0xc7a6953f78c0610518888a8d071a87c16f1df210bf8aeee70053da0305f09e81"),
+				Index:       4,
+			},
+		}}
+
+		events := model.EventsFromTransfer(chainName, logs)
+		assert.Len(t, events, 1)
+		assert.True(t, events[0].TokenIdOverflow)
+		assert.Equal(t, model.TokenId(0), events[0].NFTId)
+		assert.Equal(t, token_id.String(), events[0].RawTokenId)
+	})
+}
+
+func Test_EventUniqueIndex(t *testing.T) {
+	t.Run("rejects duplicated event", func(t *testing.T) {
+		before_each(t)
+		event := model.Event{Chain: chainName, BlockHeight: 1000, TxHash: "0xaa", Index: 1, Type: model.EventTypeTransfer, From: "0x0", To: "0x1"}
+		_, err := model.Engine.Insert(&event)
+		assert.Nil(t, err)
+
+		duplicated := event
+		duplicated.Id = 0
+		_, err = model.Engine.Insert(&duplicated)
+		assert.True(t, model.IsUniqueViolation(err), err)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -24,6 +25,9 @@ func db_clean_data() {
 	model.Engine.Where("1 = 1").Delete(new(model.NFT))
 	model.Engine.Where("1 = 1").Delete(new(model.Event))
 	model.Engine.Where("1 = 1").Delete(new(model.TelegramBind))
+	model.Engine.Where("1 = 1").Delete(new(model.Checkpoint))
+	model.Engine.Where("1 = 1").Delete(new(model.ERC20Token))
+	model.Engine.Where("1 = 1").Delete(new(model.TelegramCursor))
 }
 
 func TestMain(m *testing.M) {
@@ -31,10 +35,23 @@ func TestMain(m *testing.M) {
 	rand.Seed(rand_seed)
 	fmt.Printf("Seed: %d\n", rand_seed)
 
-	config.ConfigPath = "../../config/config.test.json"
-	config.Init()
+	// Runs against a fresh SQLite database, no external service needed.
+	dir, err := os.MkdirTemp("", "spark_server_test")
+	if err != nil {
+		panic(err)
+	}
+	config.C.DB = config.DBConfig{
+		Driver:      config.DBDriverSQLite,
+		Path:        filepath.Join(dir, "test.db"),
+		AutoMigrate: true,
+	}
+	config.C.Chain = map[string]*config.ChainConfig{
+		chainName: {Enabled: true, ContractAddress: "0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F"},
+	}
 	model.Init()
 	db_clean_data()
 	result := m.Run()
+	model.Engine.Close()
+	os.RemoveAll(dir)
 	os.Exit(result)
 }
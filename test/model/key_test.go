@@ -28,8 +28,8 @@ func Test_CreateKey(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		before_each(t)
 		author_address, _ := generate_new_wallet()
-		issue_id := uint64(rand.Int())
-		key, err := model.CreateKey(chainName, author_address, issue_id)
+		issue_id := model.TokenId(rand.Int())
+		key, err := model.CreateKey(model.Engine, chainName, author_address, issue_id)
 
 		assert.Nil(t, err)
 		assert.NotNil(t, key)
@@ -39,11 +39,11 @@ func Test_CreateKey(t *testing.T) {
 	t.Run("Duplicated", func(t *testing.T) {
 		before_each(t)
 		author_address, _ := generate_new_wallet()
-		issue_id := uint64(rand.Int())
-		_, err := model.CreateKey(chainName, author_address, issue_id)
+		issue_id := model.TokenId(rand.Int())
+		_, err := model.CreateKey(model.Engine, chainName, author_address, issue_id)
 		assert.Nil(t, err)
 
-		key, err := model.CreateKey(chainName, author_address, issue_id)
+		key, err := model.CreateKey(model.Engine, chainName, author_address, issue_id)
 		assert.Nil(t, key)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "exists")
@@ -56,19 +56,19 @@ func Test_GetKey(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		before_each(t)
 		author_address, _ := generate_new_wallet()
-		issue_id := uint64(rand.Int())
-		key, err := model.CreateKey(chainName, author_address, issue_id)
+		issue_id := model.TokenId(rand.Int())
+		key, err := model.CreateKey(model.Engine, chainName, author_address, issue_id)
 		assert.Nil(t, err)
 
-		key_string, err := model.GetKey(chainName, issue_id)
+		key_string, err := model.GetKey(model.Engine, chainName, issue_id)
 		assert.Nil(t, err)
 		assert.Equal(t, key.Key, key_string)
 		t.Logf("Key generated: %s", key.Key)
 	})
 	t.Run("not found", func(t *testing.T) {
 		before_each(t)
-		issue_id := uint64(rand.Int())
-		key_string, err := model.GetKey(chainName, issue_id)
+		issue_id := model.TokenId(rand.Int())
+		key_string, err := model.GetKey(model.Engine, chainName, issue_id)
 		assert.NotNil(t, err)
 		assert.Equal(t, "", key_string)
 		assert.Contains(t, err.Error(), "not found")
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Migrations(t *testing.T) {
+	t.Run("versions are unique and increasing", func(t *testing.T) {
+		migrations := model.Migrations()
+		assert.NotEmpty(t, migrations)
+		for i, migration := range migrations {
+			assert.NotNil(t, migration.Up, migration.Name)
+			assert.NotNil(t, migration.Down, migration.Name)
+			if i > 0 {
+				assert.Greater(t, migration.Version, migrations[i-1].Version)
+			}
+		}
+	})
+}
+
+func Test_MigrateDownUp(t *testing.T) {
+	t.Run("reverts and re-applies latest migration", func(t *testing.T) {
+		before_each(t)
+		migrations := model.Migrations()
+		latest := migrations[len(migrations)-1]
+
+		reverted, err := model.MigrateDown(1)
+		assert.Nil(t, err)
+		if assert.Len(t, reverted, 1) {
+			assert.Equal(t, latest.Version, reverted[0].Version)
+		}
+		pending, err := model.PendingMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, pending)
+
+		applied, err := model.MigrateUp(0)
+		assert.Nil(t, err)
+		assert.Len(t, applied, 1)
+
+		// Nothing left
+		applied, err = model.MigrateUp(0)
+		assert.Nil(t, err)
+		assert.Len(t, applied, 0)
+
+		statuses, err := model.MigrationStatuses()
+		assert.Nil(t, err)
+		for _, status := range statuses {
+			assert.True(t, status.Applied, status.Name)
+		}
+	})
+}
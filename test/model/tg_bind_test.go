@@ -27,14 +27,14 @@ func Test_CreateTGBind(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		before_each(t)
 
-		tb, err := model.CreateTGBind(int64(1337), "0x0000000000000000000000000000000000000000", "Ethereum", "ETH")
+		tb, err := model.CreateTGBind(model.Engine, int64(1337), "0x0000000000000000000000000000000000000000", "Ethereum", "ETH")
 		assert.Nil(t, err)
 		assert.Greater(t, tb.Id, uint64(0))
 	})
 
 	t.Run("fail if ETH address invalid", func(t *testing.T) {
 		before_each(t)
-		tb, err := model.CreateTGBind(int64(1337), "0xabc123", "Test", "")
+		tb, err := model.CreateTGBind(model.Engine, int64(1337), "0xabc123", "Test", "")
 		assert.Nil(t, tb)
 		assert.Contains(t, err.Error(), "invalid address")
 	})
@@ -45,8 +45,8 @@ func Test_FindTGBindBy(t *testing.T) {
 		t.Run("success", func(t *testing.T) {
 			before_each(t)
 			adminID := int64(1337)
-			tb, _ := model.CreateTGBind(adminID, "0x0000000000000000000000000000000000000000", "", "")
-			foundTBs, err := model.FindTGBindBy(&model.TelegramBind{AdminID: adminID})
+			tb, _ := model.CreateTGBind(model.Engine, adminID, "0x0000000000000000000000000000000000000000", "", "")
+			foundTBs, err := model.FindTGBindBy(model.Engine, &model.TelegramBind{AdminID: adminID})
 			assert.Nil(t, err)
 			assert.Equal(t, 1, len(foundTBs))
 			foundTB := foundTBs[0]
@@ -55,7 +55,7 @@ func Test_FindTGBindBy(t *testing.T) {
 
 		t.Run("not found", func(t *testing.T) {
 			before_each(t)
-			foundTBs, err := model.FindTGBindBy(&model.TelegramBind{AdminID: int64(1)})
+			foundTBs, err := model.FindTGBindBy(model.Engine, &model.TelegramBind{AdminID: int64(1)})
 			assert.Nil(t, err)
 			assert.Equal(t, 0, len(foundTBs))
 		})
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func Test_TGCursor(t *testing.T) {
+	t.Run("save and move", func(t *testing.T) {
+		before_each(t)
+		bind, err := model.CreateTGBind(model.Engine, int64(1337), "0x0000000000000000000000000000000000000000", "Ethereum", "ETH")
+		require.Nil(t, err)
+		group, err := model.CreateTGGroup(model.Engine, bind, -100, "Group")
+		require.Nil(t, err)
+
+		_, err = model.TGCursorFind(model.Engine, group.Id)
+		assert.True(t, xerrors.Is(err, model.ErrTGCursorNotFound))
+
+		require.Nil(t, model.TGCursorSave(model.Engine, &model.TelegramCursor{GroupID: group.Id, BlockHeight: 100, EventIndex: 1}))
+		require.Nil(t, model.TGCursorSave(model.Engine, &model.TelegramCursor{GroupID: group.Id, BlockHeight: 101, EventIndex: 0}))
+		found, err := model.TGCursorFind(model.Engine, group.Id)
+		require.Nil(t, err)
+		assert.Equal(t, uint64(101), found.BlockHeight)
+		assert.Equal(t, uint(0), found.EventIndex)
+
+		_, err = model.DeleteTGBind(model.Engine, []*model.TelegramBind{bind})
+		require.Nil(t, err)
+		_, err = model.TGCursorFind(model.Engine, group.Id)
+		assert.True(t, xerrors.Is(err, model.ErrTGCursorNotFound))
+	})
+
+	t.Run("passed", func(t *testing.T) {
+		cursor := model.TelegramCursor{BlockHeight: 100, EventIndex: 2}
+		assert.True(t, cursor.Passed(&model.Event{BlockHeight: 99, Index: 5}))
+		assert.True(t, cursor.Passed(&model.Event{BlockHeight: 100, Index: 1}))
+		assert.False(t, cursor.Passed(&model.Event{BlockHeight: 100, Index: 2}))
+		assert.False(t, cursor.Passed(&model.Event{BlockHeight: 101, Index: 0}))
+	})
+}
+
+func Test_FindPublishEventsBetween(t *testing.T) {
+	t.Run("in order", func(t *testing.T) {
+		before_each(t)
+		_, err := model.InsertEventsIgnoreExisting(model.Engine, []*model.Event{
+			{Chain: chainName, BlockHeight: 102, Index: 0, TxHash: "0x3", Type: model.EventTypePublish, NFTId: 0x300000000},
+			{Chain: chainName, BlockHeight: 101, Index: 2, TxHash: "0x2", Type: model.EventTypePublish, NFTId: 0x200000000},
+			{Chain: chainName, BlockHeight: 101, Index: 1, TxHash: "0x2", Type: model.EventTypeTransfer, NFTId: 0x200000001},
+			{Chain: chainName, BlockHeight: 100, Index: 0, TxHash: "0x1", Type: model.EventTypePublish, NFTId: 0x100000000},
+			{Chain: "polygon", BlockHeight: 101, Index: 0, TxHash: "0x4", Type: model.EventTypePublish, NFTId: 0x400000000},
+		})
+		require.Nil(t, err)
+
+		events, err := model.FindPublishEventsBetween(model.Engine, chainName, 101, 102)
+		require.Nil(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, model.TokenId(0x200000000), events[0].NFTId)
+		assert.Equal(t, model.TokenId(0x300000000), events[1].NFTId)
+	})
+}
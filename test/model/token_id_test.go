@@ -0,0 +1,58 @@
+package model
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func Test_ParseTokenId(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		id, err := model.ParseTokenId("18446744073709551615")
+		assert.Nil(t, err)
+		assert.Equal(t, model.TokenId(0xffffffffffffffff), id)
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		_, err := model.ParseTokenId("18446744073709551616")
+		assert.True(t, xerrors.Is(err, model.ErrTokenIdOutOfRange))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, s := range []string{"", "-1", "0x10", "abc"} {
+			_, err := model.ParseTokenId(s)
+			assert.True(t, xerrors.Is(err, model.ErrTokenIdInvalid), s)
+		}
+	})
+}
+
+func Test_TokenIdFromBig(t *testing.T) {
+	t.Run("uint256", func(t *testing.T) {
+		id := new(big.Int).Lsh(big.NewInt(1), 255)
+		_, err := model.TokenIdFromBig(id)
+		assert.True(t, xerrors.Is(err, model.ErrTokenIdOutOfRange))
+	})
+}
+
+func Test_TokenIdStorage(t *testing.T) {
+	t.Run("keeps ids above 2^63", func(t *testing.T) {
+		before_each(t)
+		nft := model.NFT{
+			Chain:         chainName,
+			NFTID:         model.TokenId(0xfffffffe00000002),
+			Parent:        model.TokenId(0xfffffffe00000001),
+			MaxShillCount: 10,
+			Owner:         "0x0000004215285644116b17436372d569a4ed3a1d",
+		}
+		_, err := model.Engine.Insert(&nft)
+		assert.Nil(t, err)
+
+		found, err := model.FindNFT(model.Engine, chainName, model.TokenId(0xfffffffe00000002))
+		assert.Nil(t, err)
+		assert.Equal(t, nft.Parent, found.Parent)
+		assert.Equal(t, uint32(0xfffffffe), found.IssueId())
+	})
+}
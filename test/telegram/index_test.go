@@ -8,20 +8,11 @@ import (
 	"time"
 
 	"github.com/SparkNFT/key_server/config"
-	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
 	"github.com/SparkNFT/key_server/telegram"
 )
 
-func db_clean_data() {
-	model.Engine.Where("1 = 1").Delete(new(model.Key))
-	model.Engine.Where("1 = 1").Delete(new(model.BlockLog))
-	model.Engine.Where("1 = 1").Delete(new(model.NFT))
-	model.Engine.Where("1 = 1").Delete(new(model.Event))
-	model.Engine.Where("1 = 1").Delete(new(model.TelegramBind))
-}
-
 func before_each(t *testing.T) {
-	db_clean_data()
 	// Clear StateMachines
 	telegram.Conversations = make(map[int64]*telegram.Conversation, 0)
 }
@@ -33,8 +24,7 @@ func TestMain(m *testing.M) {
 
 	config.ConfigPath = "../../config/config.test.json"
 	config.Init()
-	model.Init()
-	telegram.Init(true)
+	telegram.Init(true, repository.NewMemory())
 
 	before_each(nil)
 	result := m.Run()
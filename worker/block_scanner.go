@@ -2,87 +2,174 @@ package worker
 
 import (
 	"context"
-	"fmt"
 	"math/big"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/SparkNFT/key_server/abi"
 	"github.com/SparkNFT/key_server/chain"
 	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/metrics"
 	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/xerrors"
-	"xorm.io/xorm"
+)
+
+const (
+	// reorgRescanBlocks is how many blocks up to the checkpoint are
+	// rescanned when the checkpointed block is no longer on chain.
+	reorgRescanBlocks = 32
 )
 
 var (
-	scanLock map[string]*sync.Mutex
+	scanLock     = make(map[string]*sync.Mutex)
+	scanLockLock sync.Mutex
 )
 
-func CheckBlockScannerConfig(chainName string) {
-	if config.C.Chain[chainName].ContractAddress == "" || config.C.Chain[chainName].RPCUrl == "" {
-		panic(fmt.Sprintf(
-			"Chain config invalid. ContractAddress: %s, RPC URL: %s",
-			config.C.Chain[chainName].ContractAddress,
-			config.C.Chain[chainName].RPCUrl,
-		))
+// scanLockOf returns the lock held while a block of given chain is
+// being fetched.
+func scanLockOf(chainName string) *sync.Mutex {
+	scanLockLock.Lock()
+	defer scanLockLock.Unlock()
+	lock, ok := scanLock[chainName]
+	if !ok {
+		lock = new(sync.Mutex)
+		scanLock[chainName] = lock
+	}
+	return lock
+}
+
+func CheckBlockScannerConfig(chainName string) error {
+	chainConfig, ok := config.Get().Chain[chainName]
+	if !ok || chainConfig == nil {
+		return xerrors.Errorf("%w: %s", chain.ErrChainNotConfigured, chainName)
+	}
+	if chainConfig.ContractAddress == "" || len(chainConfig.RPCEndpoints()) == 0 {
+		return xerrors.Errorf(
+			"Chain config invalid. ContractAddress: %s, RPC endpoints: %d",
+			chainConfig.ContractAddress,
+			len(chainConfig.RPCEndpoints()),
+		)
 	}
 
-	if config.C.Chain[chainName].BlockHeight == 0 {
-		panic("BlockHeight not set.")
+	if chainConfig.BlockHeight == 0 {
+		return xerrors.New("BlockHeight not set.")
 	}
+	return nil
+}
+
+// StartBlockScanners supervises a BlockScannerWorker for every enabled
+// chain. Only the instance holding leader lock of a chain scans it.
+// Scanners follow config reloads: see scanners.reconcile.
+func StartBlockScanners(ctx context.Context, store repository.Store) error {
+	for _, chainName := range config.EnabledChains() {
+		if err := CheckBlockScannerConfig(chainName); err != nil {
+			return xerrors.Errorf("error in config of chain %s: %w", chainName, err)
+		}
+	}
+
+	manager := newScanners(ctx, store)
+	manager.reconcile(config.Get())
+	config.OnReload(func(old, new *config.Config) {
+		manager.reconcile(new)
+	})
+	return nil
 }
 
-func BlockScannerWorker(chainName string) {
-	blockHeight := checkBlockHeight(chainName)
+// BlockScannerWorker scans blocks of given chain until ctx is
+// cancelled. RPC calls of a block being fetched are cancelled too: the
+// block is fetched again on next start.
+func BlockScannerWorker(ctx context.Context, store repository.Store, chainName string) error {
+	blockHeight, err := checkBlockHeight(store, chainName)
+	if err != nil {
+		return err
+	}
 	l := log.WithFields(log.Fields{"chain": chainName, "worker": "BlockScannerWorker"})
 
 	contract, client, err := chain.Init(chainName)
 	if err != nil {
-		panic(xerrors.Errorf("error when initializing worker: %w", err))
+		return xerrors.Errorf("error when initializing worker: %w", err)
 	}
-	if scanLock == nil {
-		scanLock = make(map[string]*sync.Mutex, 0)
+	lock := scanLockOf(chainName)
+	defer metrics.WorkerStopped("block_scanner", chainName)
+	defer clearScannerStatus(chainName)
+
+	var heads *headWatcher
+	if chainConfigOf(chainName).ScanMode == config.ScanModeSubscribe {
+		heads = newHeadWatcher(chainName, client)
+		go heads.run(ctx)
 	}
-	lock := new(sync.Mutex)
-	scanLock[chainName] = lock
 
 	for {
+		metrics.WorkerHeartbeatNow("block_scanner", chainName)
 		lock.Lock()
 
-		if err := fetch_block(chainName, contract, client, blockHeight); err != nil {
-			l.WithFields(logrus.Fields{"chain": chainName, "height": blockHeight}).Warnf("Block fetch failed: %s", err.Error())
+		chainConfig := chainConfigOf(chainName)
+		sleep := chainConfig.Sleep.Duration()
+		// Height whose confirmation is worth waiting a new head for.
+		confirmed_at := uint64(0)
+		err := fetch_block(ctx, store, chainName, contract, client, blockHeight)
+		if err != nil && ctx.Err() != nil {
 			lock.Unlock()
-			time.Sleep(config.C.Chain[chainName].FailSleepSeconds * time.Second)
-			continue
+			l.WithField("height", blockHeight).Infof("Stopping. Block cancelled, next height: %d", blockHeight)
+			return nil
+		}
+		if err != nil {
+			reason := fetchFailureReason(err)
+			metrics.ScannerFetchFailures.WithLabelValues(chainName, reason).Inc()
+			l.WithFields(logrus.Fields{"chain": chainName, "height": blockHeight}).Warnf("Block fetch failed: %s", err.Error())
+			sleep = chainConfig.FailSleep.Duration()
+			if reason == "slow_down" {
+				confirmed_at = blockHeight + uint64(chainConfig.BlockConfirmCount)
+			}
+		} else {
+			blockHeight += 1
+			if heads != nil && heads.head.Load() < blockHeight+uint64(chainConfig.BlockConfirmCount) {
+				confirmed_at = blockHeight + uint64(chainConfig.BlockConfirmCount)
+			}
 		}
-
-		blockHeight += 1
 		lock.Unlock()
-		time.Sleep(config.C.Chain[chainName].SleepSeconds * time.Second)
+
+		if heads != nil && confirmed_at != 0 {
+			heads.waitFor(ctx, confirmed_at, sleep)
+		} else {
+			select {
+			case <-ctx.Done():
+			case <-time.After(sleep):
+			}
+		}
+		if ctx.Err() != nil {
+			l.WithField("height", blockHeight).Infof("Stopping. Next height: %d", blockHeight)
+			return nil
+		}
 	}
 }
 
 // checkBlockHeight returns next block height should be fetched
-func checkBlockHeight(chainName string) (block_height uint64) {
+func checkBlockHeight(store repository.Store, chainName string) (block_height uint64, err error) {
 	l := logrus.WithFields(log.Fields{"chain": chainName, "worker": "check_block_height"})
-	config_block_height := config.C.Chain[chainName].BlockHeight
-
-	found_block, err := model.BlockLogFindFirst(chainName)
-	if err != nil {
-		l.Warnf("error when fetching latest block height: %s", err.Error())
-		l.Warnf("Using config-file specified height")
-	}
+	config_block_height := chainConfigOf(chainName).BlockHeight
 
 	found_block_height := uint64(0)
-	if found_block != nil {
-		found_block_height = found_block.BlockHeight
-		found_block_height += 1
+	checkpoint, err := store.BlockLogs().FindCheckpoint(chainName)
+	switch {
+	case err == nil:
+		found_block_height = checkpoint.BlockHeight + 1
+	case xerrors.Is(err, model.ErrCheckpointNotFound):
+		// Deployments before checkpoints were introduced only have BlockLogs.
+		if found_block, err := store.BlockLogs().FindLatestScanned(chainName); err == nil {
+			l.Infof("No checkpoint yet. Resuming from latest scanned BlockLog %d", found_block.BlockHeight)
+			found_block_height = found_block.BlockHeight + 1
+		}
+	default:
+		return 0, xerrors.Errorf("error when fetching checkpoint: %w", err)
+	}
+	if found_block_height == 0 {
+		l.Infof("Using config-file specified height")
 	}
 
 	if config_block_height > found_block_height {
@@ -91,170 +178,240 @@ func checkBlockHeight(chainName string) (block_height uint64) {
 		block_height = found_block_height
 	}
 	if block_height == uint64(0) {
-		panic(xerrors.Errorf("Block height: both config file and DB fetching failed."))
+		return 0, xerrors.Errorf("Block height: both config file and DB fetching failed.")
 	}
-	return block_height
+	return block_height, nil
 }
 
 // fetch_block fetches specific height of a block and saves all
 // related events in DB.
-func fetch_block(chainName string, contract *abi.SparkLink, client *ethclient.Client, block_height uint64) (err error) {
+func fetch_block(ctx context.Context, store repository.Store, chainName string, contract *abi.SparkLink, client chain.Client, block_height uint64) (err error) {
 	l := log.WithFields(log.Fields{"chain": chainName, "worker": "fetch_block", "height": block_height})
-	newest_block, err := client.BlockNumber(context.Background())
+	newest_block, err := client.BlockNumber(ctx)
 	if err != nil {
-		return xerrors.Errorf("error when fetching newest block number: %w", err)
+		return fetchFailed("rpc", xerrors.Errorf("error when fetching newest block number: %w", err))
+	}
+	updateScannerStatus(chainName, func(status *ScannerStatus) {
+		status.ChainHead = newest_block
+	})
+	wait_block_count := uint64(chainConfigOf(chainName).BlockConfirmCount)
+	if newest_block >= wait_block_count {
+		metrics.ScannerConfirmedHead.WithLabelValues(chainName).Set(float64(newest_block - wait_block_count))
 	}
-	wait_block_count := uint64(config.C.Chain[chainName].BlockConfirmCount)
 	if newest_block < (block_height + wait_block_count) {
-		return xerrors.Errorf("Slow down. Newest: %d, current: %d, should wait: %d", newest_block, block_height, wait_block_count)
+		return fetchFailed("slow_down", xerrors.Errorf("Slow down. Newest: %d, current: %d, should wait: %d", newest_block, block_height, wait_block_count))
 	}
 
-	session := model.Engine.NewSession()
-	defer session.Close()
+	err = store.BlockLogs().Start(chainName, block_height)
+	if xerrors.Is(err, model.ErrBlockLogExists) {
+		l.WithField("height", block_height).Debug("Using existed BlockLog record")
+	} else if err != nil {
+		return fetchFailed("db", xerrors.Errorf("error when starting a blocklog: %w", err))
+	}
 
-	err = model.BlockLogStart(session, chainName, block_height)
+	header, logs, err := chain.GetBlockLogsOf(ctx, client, chainName, new(big.Int).SetUint64(block_height))
 	if err != nil {
-		session.Rollback()
-		if strings.Contains(err.Error(), "duplicate key value") {
-			l.WithField("height", block_height).Debug("Using existed BlockLog record")
-		} else {
-			return xerrors.Errorf("error when starting a blocklog: %w", err)
-		}
-
+		return fetchFailed("rpc", xerrors.Errorf("error when fetching block: %w", err))
 	}
 
-	logs, err := chain.GetAllLogsOf(client, chainName, big.NewInt(int64(block_height)))
-	if err != nil {
-		session.Rollback()
-		return xerrors.Errorf("error when fetching block: %w", err)
+	if err := rescan_reorg(ctx, store, chainName, contract, client, block_height, header); err != nil {
+		return err
 	}
 
 	l.WithFields(log.Fields{"count": len(logs)}).Info("Log fetched.")
-	events, err := create_events(contract, session, chainName, logs)
-	if err != nil {
-		session.Rollback()
-		return xerrors.Errorf("%w", err)
-	}
-	err = session.Commit()
+	events, err := create_events(contract, store.Events(), chainName, logs)
 	if err != nil {
-		return xerrors.Errorf("error when commiting changes: %w", err)
+		return fetchFailed("events", xerrors.Errorf("%w", err))
 	}
 
 	// create NFT from events
-	err = create_nfts(contract, session, chainName, events)
+	err = store.Transaction(func(tx repository.Store) error {
+		if err := create_nfts(ctx, contract, tx.NFTs(), chainName, events); err != nil {
+			return xerrors.Errorf("error when creating NFT: %w", err)
+		}
+		if err := update_nfts(tx.NFTs(), chainName, events); err != nil {
+			return xerrors.Errorf("error when updating NFT: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return xerrors.Errorf("error when creating NFT: %w", err)
+		return fetchFailed("nft", err)
 	}
-	err = update_nfts(contract, session, chainName, events)
+
+	// If all set, finish BlockLog and move checkpoint together.
+	err = store.Transaction(func(tx repository.Store) error {
+		if err := tx.BlockLogs().Finish(chainName, block_height); err != nil {
+			return xerrors.Errorf("error when finishing a block: %w", err)
+		}
+		if err := tx.BlockLogs().SaveCheckpoint(chainName, block_height, header.Hash().Hex()); err != nil {
+			return xerrors.Errorf("error when saving checkpoint: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return xerrors.Errorf("error when updating NFT: %w", err)
+		return fetchFailed("db", err)
 	}
-	err = session.Commit()
-	if err != nil {
-		return xerrors.Errorf("error when commiting changes: %w", err)
+	updateScannerStatus(chainName, func(status *ScannerStatus) {
+		status.Head = block_height
+	})
+	metrics.ScannerBlocksProcessed.WithLabelValues(chainName).Inc()
+	for _, event := range events {
+		metrics.ScannerEventsProcessed.WithLabelValues(chainName, string(event.Type)).Inc()
 	}
 
-	// If all set,
-	err = model.BlockLogFinish(session, chainName, block_height)
+	// Clean old BlockLog
+	keep_count, keep_age := chainConfigOf(chainName).BlockLogRetention.Limits()
+	_, err = store.BlockLogs().Clean(chainName, keep_count, keep_age)
 	if err != nil {
-		return xerrors.Errorf("error when finishing a block: %w", err)
+		return fetchFailed("db", xerrors.Errorf("error when cleaning BlockLog: %w", err))
 	}
 
-	err = session.Commit()
-	if err != nil {
-		return xerrors.Errorf("error when commiting changes: %w", err)
-	}
+	return nil
+}
 
-	// Clean old BlockLog
-	err = model.BlockLogClean(chainName)
-	if err != nil {
-		return xerrors.Errorf("error when cleaning BlockLog: %w", err)
+// rescan_reorg checks parent hash of the block being fetched against
+// checkpoint. If they differ, the checkpointed block has been reorged
+// out: recent blocks are rescanned like Reindex does, and checkpoint is
+// moved to the new parent.
+func rescan_reorg(ctx context.Context, store repository.Store, chainName string, contract *abi.SparkLink, client chain.Client, block_height uint64, header *types.Header) error {
+	checkpoint, err := store.BlockLogs().FindCheckpoint(chainName)
+	if xerrors.Is(err, model.ErrCheckpointNotFound) {
+		return nil
+	} else if err != nil {
+		return fetchFailed("db", xerrors.Errorf("error when fetching checkpoint: %w", err))
+	}
+	from, reorged := reorgRescanFrom(checkpoint, block_height, header.ParentHash.Hex(), chainConfigOf(chainName).BlockHeight)
+	if !reorged {
+		return nil
 	}
+	l := log.WithFields(log.Fields{"chain": chainName, "worker": "rescan_reorg", "height": block_height})
+	l.Warnf("Reorg detected. Checkpoint %d: %s, parent hash: %s. Rescanning from %d", checkpoint.BlockHeight, checkpoint.BlockHash, header.ParentHash.Hex(), from)
 
+	options := ReindexOptions{Store: store, Chain: chainName}
+	for height := from; height < block_height; height++ {
+		diff, err := diffBlock(ctx, store, chainName, contract, client, height)
+		if err != nil {
+			return fetchFailed("reorg", xerrors.Errorf("error when rescanning block %d: %w", height, err))
+		}
+		if err := applyReindexDiff(ctx, contract, options, diff); err != nil {
+			return fetchFailed("reorg", xerrors.Errorf("error when applying block %d: %w", height, err))
+		}
+	}
+	if err := store.BlockLogs().SaveCheckpoint(chainName, block_height-1, header.ParentHash.Hex()); err != nil {
+		return fetchFailed("db", xerrors.Errorf("error when saving checkpoint: %w", err))
+	}
 	return nil
 }
 
-// create_events filter and save all logs as events in DB.
-func create_events(contract *abi.SparkLink, session *xorm.Session, chainName string, logs []types.Log) (result []*model.Event, err error) {
-	publish_events, err := chain.FilterEventPublish(contract, logs)
-	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
+// reorgRescanFrom returns the height rescanning starts from, if
+// checkpoint is right before block_height but its hash is not
+// parent_hash. Blocks below lowest are never rescanned.
+func reorgRescanFrom(checkpoint *model.Checkpoint, block_height uint64, parent_hash string, lowest uint64) (from uint64, reorged bool) {
+	if checkpoint.BlockHeight+1 != block_height || checkpoint.BlockHash == parent_hash {
+		return 0, false
 	}
-	publish, err := model.CreateFromBlockEventPublish(session, chainName, publish_events)
-	if err != nil {
-		return nil, xerrors.Errorf("%w", err)
+	from = lowest
+	if block_height > reorgRescanBlocks && block_height-reorgRescanBlocks > from {
+		from = block_height - reorgRescanBlocks
+	}
+	if from == 0 {
+		from = 1
 	}
+	return from, true
+}
 
-	transfer_events, err := chain.FilterEventTransfer(contract, logs)
+// create_events filter and save all logs as events in DB. Events
+// already saved are ignored, while all parsed events are returned.
+func create_events(contract *abi.SparkLink, repo repository.Events, chainName string, logs []types.Log) (result []*model.Event, err error) {
+	l := log.WithFields(log.Fields{"chain": chainName, "worker": "create_events"})
+	publish_events, err := chain.FilterEventPublish(contract, logs)
 	if err != nil {
 		return nil, xerrors.Errorf("%w", err)
 	}
-	transfer, err := model.CreateFromBlockEventTransfer(session, chainName, transfer_events)
+	publish := model.EventsFromPublish(chainName, publish_events)
+	l.WithFields(log.Fields{"count": len(publish), "type": "Publish"}).Debugf("Publish event ready to be inserted: %+v", publish)
+
+	transfer_events, err := chain.FilterEventTransfer(contract, logs)
 	if err != nil {
 		return nil, xerrors.Errorf("%w", err)
 	}
+	transfer := model.EventsFromTransfer(chainName, transfer_events)
+	l.WithFields(log.Fields{"count": len(transfer), "type": "Transfer"}).Debugf("Transfer event ready to be inserted")
+
 	result = make([]*model.Event, 0, len(logs))
 	result = append(result, publish...)
 	result = append(result, transfer...)
+	if len(result) == 0 {
+		return result, nil
+	}
+	affected, err := repo.CreateIgnoreExisting(result)
+	if err != nil {
+		return nil, xerrors.Errorf("error when inserting events to DB: %w", err)
+	}
+	l.WithField("affected", affected).Debugf("Insert finished")
 	return result, nil
 }
 
 // create_nfts create NFT model records
-func create_nfts(contract *abi.SparkLink, session *xorm.Session, chainName string, events []*model.Event) (err error) {
+func create_nfts(ctx context.Context, contract *abi.SparkLink, repo repository.NFTs, chainName string, events []*model.Event) (err error) {
 	if len(events) == 0 {
 		return nil
 	}
 
 	l := log.WithFields(log.Fields{"chain": chainName, "worker": "create_nfts"})
+	opts := &bind.CallOpts{Context: ctx}
 	nfts := make([]*model.NFT, 0)
-	existed_parent_nft_ids := make([]uint64, 0)
+	existed_parent_nft_ids := make([]model.TokenId, 0)
 	for _, event := range events {
-		if !event.IsMint() {
+		if !event.IsMint() || event.TokenIdOverflow {
+			continue
+		}
+		// Block may be processed again after a failure.
+		if _, err := repo.Find(chainName, event.NFTId); err == nil {
+			l.WithField("NFTID", event.NFTId).Debugf("NFT exists. Skipped.")
 			continue
+		} else if !xerrors.Is(err, model.ErrNFTNotFound) {
+			return xerrors.Errorf("error when finding NFT %s: %w", event.NFTId, err)
 		}
-		parent, err := chain.GetParentOf(contract, event.NFTId) // parent == 0 : Root NFT
+		parent, err := chain.GetParentOf(ctx, contract, uint64(event.NFTId)) // parent == 0 : Root NFT
 		if err != nil {
-			return xerrors.Errorf("error when getting parent of %d: %w", event.NFTId, err)
+			return xerrors.Errorf("error when getting parent of %s: %w", event.NFTId, err)
 		}
-		max_shill_count, err := contract.GetShillTimesByNFTId(nil, event.NFTId)
+		max_shill_count, err := contract.GetShillTimesByNFTId(opts, uint64(event.NFTId))
 		if err != nil {
-			return xerrors.Errorf("error when getting remain shill count of %d: %w", event.NFTId, err)
+			return xerrors.Errorf("error when getting remain shill count of %s: %w", event.NFTId, err)
 		}
 
-		token_addr, err := contract.GetTokenAddrByNFTId(nil, event.NFTId)
+		token_addr, err := contract.GetTokenAddrByNFTId(opts, uint64(event.NFTId))
 		if err != nil {
-			return xerrors.Errorf("error when getting TokenAddr of %d: %w", event.NFTId, err)
+			return xerrors.Errorf("error when getting TokenAddr of %s: %w", event.NFTId, err)
 		}
 
 		nft := &model.NFT{
 			Chain:         chainName,
 			NFTID:         event.NFTId,
-			Parent:        parent,
+			Parent:        model.TokenId(parent),
 			ShillCount:    0,
 			MaxShillCount: max_shill_count,
 			Owner:         event.To,
 			TokenAddr:     token_addr.Hex(),
 		}
 		if parent != uint64(0) {
-			existed_parent_nft_ids = append(existed_parent_nft_ids, parent)
+			existed_parent_nft_ids = append(existed_parent_nft_ids, model.TokenId(parent))
 		}
 		nfts = append(nfts, nft)
 	}
 
 	if len(nfts) != 0 {
-		affected, err := model.Engine.Insert(&nfts)
-		if err != nil {
-			return xerrors.Errorf("error when inserting NFT record: %w", err)
-		}
-		if int(affected) != len(nfts) {
-			return xerrors.Errorf("error when Inserting NFT Record: records inserted mismatch total length: %d - %d", affected, len(nfts))
+		if err := repo.Create(nfts); err != nil {
+			return xerrors.Errorf("%w", err)
 		}
 	} else {
 		l.Debugf("No NFT created.")
 	}
 
 	if len(existed_parent_nft_ids) > 0 {
-		err := increase_shill_count(session, chainName, existed_parent_nft_ids)
+		err := increase_shill_count(repo, chainName, existed_parent_nft_ids)
 		if err != nil {
 			return xerrors.Errorf("%w", err)
 		}
@@ -264,19 +421,18 @@ func create_nfts(contract *abi.SparkLink, session *xorm.Session, chainName strin
 }
 
 // increase_shill_count increases 1 shill count for every given nft ids
-func increase_shill_count(session *xorm.Session, chainName string, nft_ids []uint64) error {
+func increase_shill_count(repo repository.NFTs, chainName string, nft_ids []model.TokenId) error {
 	l := log.WithFields(log.Fields{"worker": "increase_shill_count"})
 	for _, nft_id := range nft_ids {
-		nft, err := model.FindNFT(chainName, nft_id)
+		nft, err := repo.Find(chainName, nft_id)
 		if err != nil {
-			return xerrors.Errorf("error when finding NFT ID %d: %w", nft_id, err)
+			return xerrors.Errorf("error when finding NFT ID %s: %w", nft_id, err)
 		}
 
 		nft.ShillCount += 1
 		l.WithFields(log.Fields{"NFTID": nft.NFTID, "ShillCount": nft.ShillCount}).Infof("Updating NFT shill count")
-		_, err = session.Cols("shill_count").ID(nft.Id).Update(nft)
-		if err != nil {
-			return xerrors.Errorf("error when updating NFT %d: %w", nft.NFTID, err)
+		if err = repo.Update(nft, "shill_count"); err != nil {
+			return xerrors.Errorf("%w", err)
 		}
 	}
 
@@ -284,28 +440,27 @@ func increase_shill_count(session *xorm.Session, chainName string, nft_ids []uin
 }
 
 // update_nfts update existed NFT records
-func update_nfts(contract *abi.SparkLink, session *xorm.Session, chainName string, events []*model.Event) (err error) {
+func update_nfts(repo repository.NFTs, chainName string, events []*model.Event) (err error) {
 	if len(events) == 0 {
 		return nil
 	}
 	l := log.WithFields(log.Fields{"worker": "update_nfts"})
 	for _, event := range events {
-		if event.IsMint() {
+		if event.IsMint() || event.TokenIdOverflow {
 			continue
 		}
 
-		nft, err := model.FindNFT(chainName, event.NFTId)
+		nft, err := repo.Find(chainName, event.NFTId)
 		if err != nil {
-			return xerrors.Errorf("error when update NFT %d: %w", event.NFTId, err)
+			return xerrors.Errorf("error when update NFT %s: %w", event.NFTId, err)
 		}
 
 		// Update NFT fields
 		l.WithFields(log.Fields{"NFTID": nft.NFTID, "Owner": event.To}).Infof("Updating NFT owner")
 		nft.Owner = event.To
 
-		_, err = session.Cols("owner").ID(nft.Id).Update(nft)
-		if err != nil {
-			return xerrors.Errorf("error when updating NFT %d: %w", nft.NFTID, err)
+		if err = repo.Update(nft, "owner"); err != nil {
+			return xerrors.Errorf("%w", err)
 		}
 	}
 	return nil
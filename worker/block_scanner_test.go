@@ -1,6 +1,7 @@
 package worker
 
 import (
+	"context"
 	"math/rand"
 	"testing"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/SparkNFT/key_server/chain"
 	"github.com/SparkNFT/key_server/config"
 	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 )
@@ -22,6 +24,7 @@ func before_each(t *testing.T) {
 
 	config.ConfigPath = "../config/config.test.json"
 	config.Init()
+	config.C.DB.AutoMigrate = true
 	model.Init()
 
 	db_clean_data()
@@ -32,11 +35,13 @@ func db_clean_data() {
 	model.Engine.Where("1 = 1").Delete(new(model.BlockLog))
 	model.Engine.Where("1 = 1").Delete(new(model.NFT))
 	model.Engine.Where("1 = 1").Delete(new(model.Event))
+	model.Engine.Where("1 = 1").Delete(new(model.Checkpoint))
 }
 
 func Test_fetch_block(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		before_each(t)
+		store := repository.NewSQL(model.Engine)
 		contract, client, err := chain.Init(chainName)
 		assert.Nil(t, err)
 		// https://rinkeby.etherscan.io/tx/0xc7a6953f78c0610518888a8d071a87c16f1df210bf8aeee70053da0305f09e81
@@ -46,7 +51,7 @@ func Test_fetch_block(t *testing.T) {
 		assert.False(t, found)
 		assert.Nil(t, err)
 
-		err = fetch_block(chainName, contract, client, height)
+		err = fetch_block(context.Background(), store, chainName, contract, client, height)
 		if err != nil {
 			t.Logf("%+v", err)
 		}
@@ -85,5 +90,32 @@ func Test_fetch_block(t *testing.T) {
 		assert.True(t, found)
 		assert.Nil(t, err)
 		assert.True(t, block_log.Scanned)
+
+		checkpoint, err := store.BlockLogs().FindCheckpoint(chainName)
+		assert.Nil(t, err)
+		assert.Equal(t, height, checkpoint.BlockHeight)
+		assert.NotEmpty(t, checkpoint.BlockHash)
+
+		next_height, err := checkBlockHeight(store, chainName)
+		assert.Nil(t, err)
+		assert.Equal(t, height+1, next_height)
 	})
 }
+
+func Test_reorgRescanFrom(t *testing.T) {
+	checkpoint := &model.Checkpoint{Chain: chainName, BlockHeight: 100, BlockHash: "0xaaaa"}
+
+	_, reorged := reorgRescanFrom(checkpoint, 101, "0xaaaa", 1)
+	assert.False(t, reorged)
+	// Config height moved past checkpoint: nothing to compare
+	_, reorged = reorgRescanFrom(checkpoint, 200, "0xbbbb", 200)
+	assert.False(t, reorged)
+
+	from, reorged := reorgRescanFrom(checkpoint, 101, "0xbbbb", 1)
+	assert.True(t, reorged)
+	assert.Equal(t, uint64(101-reorgRescanBlocks), from)
+	from, _ = reorgRescanFrom(checkpoint, 101, "0xbbbb", 90)
+	assert.Equal(t, uint64(90), from)
+	from, _ = reorgRescanFrom(&model.Checkpoint{BlockHeight: 3, BlockHash: "0xaaaa"}, 4, "0xbbbb", 0)
+	assert.Equal(t, uint64(1), from)
+}
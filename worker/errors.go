@@ -0,0 +1,33 @@
+package worker
+
+import "golang.org/x/xerrors"
+
+// errWorkerPanicked wraps panics recovered by supervisor.
+var errWorkerPanicked = xerrors.New("panic")
+
+// fetchError marks why fetch_block failed, for metrics.
+type fetchError struct {
+	reason string
+	err    error
+}
+
+func (e *fetchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fetchError) Unwrap() error {
+	return e.err
+}
+
+func fetchFailed(reason string, err error) error {
+	return &fetchError{reason: reason, err: err}
+}
+
+// fetchFailureReason returns reason given in fetchFailed, or "unknown".
+func fetchFailureReason(err error) string {
+	fetch_err := &fetchError{}
+	if xerrors.As(err, &fetch_err) {
+		return fetch_err.reason
+	}
+	return "unknown"
+}
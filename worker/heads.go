@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/SparkNFT/key_server/chain"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// headResubscribeAfter is the pause before subscribing again after
+	// a subscription is lost or refused.
+	headResubscribeAfter = 5 * time.Second
+	// headSilentAfter is how long a scanner waits for a new head on a
+	// live subscription before asking the chain anyway.
+	headSilentAfter = time.Minute
+)
+
+// headWatcher follows new heads of a chain over a subscription. It
+// only wakes the scanner up: which block to fetch next is still
+// decided by the scanner, so a lost or late head never skips a height.
+type headWatcher struct {
+	chainName string
+	client    chain.Client
+	log       *logrus.Entry
+
+	head      atomic.Uint64 // Highest head seen
+	connected atomic.Bool
+	notify    chan struct{} // Signalled on new head or disconnection
+	retry     time.Duration
+}
+
+func newHeadWatcher(chainName string, client chain.Client) *headWatcher {
+	return &headWatcher{
+		chainName: chainName,
+		client:    client,
+		log:       logrus.WithFields(logrus.Fields{"chain": chainName, "worker": "headWatcher"}),
+		notify:    make(chan struct{}, 1),
+		retry:     headResubscribeAfter,
+	}
+}
+
+// run keeps a new head subscription alive until ctx is cancelled.
+func (watcher *headWatcher) run(ctx context.Context) {
+	for {
+		watcher.follow(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watcher.retry):
+		}
+	}
+}
+
+// follow subscribes once and consumes heads until the subscription ends.
+func (watcher *headWatcher) follow(ctx context.Context) {
+	headers := make(chan *types.Header, 16)
+	sub, err := watcher.client.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		if ctx.Err() == nil {
+			watcher.log.Warnf("Cannot subscribe to new heads, polling instead: %s", err.Error())
+		}
+		return
+	}
+	defer sub.Unsubscribe()
+	watcher.connected.Store(true)
+	watcher.log.Info("Subscribed to new heads.")
+	defer watcher.disconnect()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-sub.Err():
+			if err != nil {
+				watcher.log.Warnf("New head subscription lost, polling instead: %s", err.Error())
+			}
+			return
+		case header := <-headers:
+			if header == nil || header.Number == nil {
+				continue
+			}
+			height := header.Number.Uint64()
+			for {
+				seen := watcher.head.Load()
+				if height <= seen || watcher.head.CompareAndSwap(seen, height) {
+					break
+				}
+			}
+			watcher.wake()
+		}
+	}
+}
+
+func (watcher *headWatcher) disconnect() {
+	watcher.connected.Store(false)
+	watcher.wake()
+}
+
+func (watcher *headWatcher) wake() {
+	select {
+	case watcher.notify <- struct{}{}:
+	default:
+	}
+}
+
+// waitFor returns once a head of at least height is notified, the
+// subscription is lost, or ctx is done. While not subscribed it simply
+// sleeps for poll, which is what a polling scanner does.
+func (watcher *headWatcher) waitFor(ctx context.Context, height uint64, poll time.Duration) {
+	timeout := poll
+	if watcher.connected.Load() {
+		timeout = headSilentAfter
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	// Only heads arriving after the block was found unconfirmed count,
+	// so a lagging endpoint cannot make the scanner spin.
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case <-watcher.notify:
+			if !watcher.connected.Load() || watcher.head.Load() >= height {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/chain"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+// fakeHeads hands out subscriptions fed by test, refusing while down.
+type fakeHeads struct {
+	chain.Client
+	lock          sync.Mutex
+	down          bool
+	subscriptions []*fakeSubscription
+}
+
+type fakeSubscription struct {
+	headers chan<- *types.Header
+	err     chan error
+}
+
+func (sub *fakeSubscription) Err() <-chan error { return sub.err }
+func (sub *fakeSubscription) Unsubscribe()      {}
+
+func (heads *fakeHeads) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	heads.lock.Lock()
+	defer heads.lock.Unlock()
+	if heads.down {
+		return nil, xerrors.New("connection refused")
+	}
+	sub := &fakeSubscription{headers: ch, err: make(chan error, 1)}
+	heads.subscriptions = append(heads.subscriptions, sub)
+	return sub, nil
+}
+
+func (heads *fakeHeads) latest() *fakeSubscription {
+	heads.lock.Lock()
+	defer heads.lock.Unlock()
+	if len(heads.subscriptions) == 0 {
+		return nil
+	}
+	return heads.subscriptions[len(heads.subscriptions)-1]
+}
+
+func start_head_watcher(t *testing.T, heads *fakeHeads) *headWatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	watcher := newHeadWatcher(chainName, heads)
+	watcher.retry = 10 * time.Millisecond
+	go watcher.run(ctx)
+	return watcher
+}
+
+func Test_headWatcher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("wakes up on confirming head", func(t *testing.T) {
+		heads := &fakeHeads{}
+		watcher := start_head_watcher(t, heads)
+		require.Eventually(t, watcher.connected.Load, time.Second, time.Millisecond)
+
+		done := make(chan struct{})
+		go func() {
+			watcher.waitFor(ctx, 12, time.Hour)
+			close(done)
+		}()
+		heads.latest().headers <- &types.Header{Number: big.NewInt(11)}
+		select {
+		case <-done:
+			t.Fatal("woken up before height is confirmed")
+		case <-time.After(50 * time.Millisecond):
+		}
+		heads.latest().headers <- &types.Header{Number: big.NewInt(12)}
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("not woken up by new head")
+		}
+		assert.Equal(t, uint64(12), watcher.head.Load())
+	})
+
+	t.Run("falls back to polling and resubscribes", func(t *testing.T) {
+		heads := &fakeHeads{}
+		watcher := start_head_watcher(t, heads)
+		require.Eventually(t, watcher.connected.Load, time.Second, time.Millisecond)
+
+		done := make(chan struct{})
+		go func() {
+			watcher.waitFor(ctx, 100, time.Hour)
+			close(done)
+		}()
+		heads.lock.Lock()
+		heads.down = true
+		heads.lock.Unlock()
+		heads.latest().err <- xerrors.New("websocket: close 1006")
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("waiting on a lost subscription")
+		}
+		assert.False(t, watcher.connected.Load())
+
+		start := time.Now()
+		watcher.waitFor(ctx, 100, 20*time.Millisecond)
+		watcher.waitFor(ctx, 100, 20*time.Millisecond)
+		assert.Less(t, time.Since(start), time.Second, "polls every poll interval while disconnected")
+
+		heads.lock.Lock()
+		heads.down = false
+		heads.lock.Unlock()
+		require.Eventually(t, watcher.connected.Load, time.Second, time.Millisecond)
+		heads.lock.Lock()
+		defer heads.lock.Unlock()
+		assert.Len(t, heads.subscriptions, 2)
+	})
+
+	t.Run("stops with ctx", func(t *testing.T) {
+		watcher := start_head_watcher(t, &fakeHeads{})
+		require.Eventually(t, watcher.connected.Load, time.Second, time.Millisecond)
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		watcher.waitFor(cancelled, 100, time.Hour)
+	})
+}
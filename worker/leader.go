@@ -0,0 +1,117 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SparkNFT/key_server/metrics"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// How often a standby instance tries to become leader, and how
+	// often a leader checks its lock.
+	leaderCheckInterval = 10 * time.Second
+)
+
+var (
+	leaders     = make(map[string]bool)
+	leadersLock sync.RWMutex
+)
+
+// WithLeaderLock wraps fn so that it only runs while this instance holds
+// the lock of (name, chain) in store. Instances not holding the lock
+// stand by and take over once the leader is gone.
+func WithLeaderLock(store repository.Store, name, chainName string, fn WorkerFunc) WorkerFunc {
+	key := name + ":" + chainName
+	l := logrus.WithFields(logrus.Fields{"module": "leader", "worker": key})
+
+	return func(ctx context.Context) error {
+		setLeader(name, chainName, false)
+		for {
+			lock, err := store.TryLock(ctx, key)
+			if err != nil {
+				return err
+			}
+			if lock != nil {
+				l.Infof("Became leader.")
+				setLeader(name, chainName, true)
+				err = runAsLeader(ctx, lock, fn)
+				setLeader(name, chainName, false)
+				if name == "block_scanner" {
+					// A former leader must not report a scanner it no
+					// longer runs.
+					clearScannerStatus(chainName)
+				}
+				if release_err := lock.Release(); release_err != nil {
+					l.Warnf("%s", release_err.Error())
+				}
+				l.Infof("Stepped down.")
+				return err
+			}
+
+			l.Debugf("Standing by.")
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(leaderCheckInterval):
+			}
+		}
+	}
+}
+
+// runAsLeader runs fn until it returns, or lock is lost.
+func runAsLeader(ctx context.Context, lock repository.Lock, fn WorkerFunc) error {
+	leader_ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lost := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-leader_ctx.Done():
+				return
+			case <-time.After(leaderCheckInterval):
+			}
+			if err := lock.Alive(leader_ctx); err != nil && leader_ctx.Err() == nil {
+				lost <- err
+				cancel()
+				return
+			}
+		}
+	}()
+
+	err := fn(leader_ctx)
+	select {
+	case lost_err := <-lost:
+		return xerrors.Errorf("leadership lost: %w", lost_err)
+	default:
+		return err
+	}
+}
+
+func setLeader(name, chainName string, leader bool) {
+	leadersLock.Lock()
+	defer leadersLock.Unlock()
+	leaders[name+":"+chainName] = leader
+
+	value := 0.0
+	if leader {
+		value = 1
+	}
+	metrics.WorkerLeader.WithLabelValues(name, chainName).Set(value)
+}
+
+// leaderOf returns nil if worker does not use leader election.
+func leaderOf(name, chainName string) *bool {
+	leadersLock.RLock()
+	defer leadersLock.RUnlock()
+	leader, ok := leaders[name+":"+chainName]
+	if !ok {
+		return nil
+	}
+	return &leader
+}
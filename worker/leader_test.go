@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithLeaderLock(t *testing.T) {
+	store := repository.NewMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	leader := WithLeaderLock(store, "test", "ethereum", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+	stopped := make(chan error, 1)
+	go func() { stopped <- leader(ctx) }()
+	<-started
+	assert.True(t, *leaderOf("test", "ethereum"))
+
+	// Lock of (name, chain) is taken: a standby does not run.
+	lock, err := store.TryLock(ctx, "test:ethereum")
+	assert.Nil(t, err)
+	assert.Nil(t, lock)
+
+	cancel()
+	select {
+	case err := <-stopped:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("leader did not stop")
+	}
+	assert.False(t, *leaderOf("test", "ethereum"))
+	lock, err = store.TryLock(context.Background(), "test:ethereum")
+	assert.Nil(t, err)
+	assert.NotNil(t, lock)
+}
+
+func Test_WithLeaderLock_clears_scanner_status(t *testing.T) {
+	store := repository.NewMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	reported := make(chan struct{})
+	leader := WithLeaderLock(store, "block_scanner", "leader_test", func(ctx context.Context) error {
+		updateScannerStatus("leader_test", func(status *ScannerStatus) { status.Head = 100 })
+		close(reported)
+		<-ctx.Done()
+		return nil
+	})
+	stopped := make(chan error, 1)
+	go func() { stopped <- leader(ctx) }()
+	<-reported
+	_, ok := ScannerStatusOf("leader_test")
+	assert.True(t, ok)
+
+	cancel()
+	assert.Nil(t, <-stopped)
+	_, ok = ScannerStatusOf("leader_test")
+	assert.False(t, ok, "stepped down instance reports no scanner")
+}
@@ -0,0 +1,344 @@
+package worker
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SparkNFT/key_server/abi"
+	"github.com/SparkNFT/key_server/chain"
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+const (
+	reindexFetchRetry = 3
+)
+
+// ReindexDiff is the difference between events on chain and events in
+// DB of a block. Events are identified by (chain, tx_hash, event_index).
+type ReindexDiff struct {
+	Height  uint64
+	Added   []*model.Event // On chain, not in DB
+	Removed []*model.Event // In DB, not on chain (or duplicated)
+	Changed []*model.Event // In DB with different data. Id is of DB row, other fields are from chain
+}
+
+func (diff ReindexDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+// ReindexOptions describes a reindex job.
+type ReindexOptions struct {
+	Store   repository.Store
+	Chain   string
+	From    uint64 // Inclusive
+	To      uint64 // Inclusive
+	Workers int
+	DryRun  bool // Only report diffs
+	// Report is called with diff of every block, in height order.
+	Report func(diff *ReindexDiff)
+}
+
+type ReindexSummary struct {
+	Blocks  uint64
+	Added   int
+	Removed int
+	Changed int
+}
+
+type reindexResult struct {
+	diff *ReindexDiff
+	err  error
+}
+
+// Reindex re-processes a block range. Blocks are fetched and diffed in
+// parallel, while changes are applied one block at a time in height
+// order. Checkpoint and BlockLogs are left untouched.
+func Reindex(ctx context.Context, options ReindexOptions) (summary ReindexSummary, err error) {
+	if options.From > options.To {
+		return summary, xerrors.Errorf("invalid block range: %d > %d", options.From, options.To)
+	}
+	if options.Workers < 1 {
+		options.Workers = 1
+	}
+	contract, client, err := chain.Init(options.Chain)
+	if err != nil {
+		return summary, xerrors.Errorf("error when initializing chain: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	heights := make(chan uint64)
+	results := make(chan reindexResult)
+	go func() {
+		defer close(heights)
+		for height := options.From; height <= options.To; height++ {
+			select {
+			case heights <- height:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for height := range heights {
+				diff, err := diffBlockWithRetry(ctx, options.Store, options.Chain, contract, client, height)
+				select {
+				case results <- reindexResult{diff: diff, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Results arrive out of order. Buffer them until the next height
+	// is ready.
+	pending := make(map[uint64]*ReindexDiff)
+	next := options.From
+	for result := range results {
+		if result.err != nil {
+			return summary, result.err
+		}
+		pending[result.diff.Height] = result.diff
+		for {
+			diff, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if err = applyReindexDiff(ctx, contract, options, diff); err != nil {
+				return summary, xerrors.Errorf("error when applying block %d: %w", diff.Height, err)
+			}
+			summary.Blocks += 1
+			summary.Added += len(diff.Added)
+			summary.Removed += len(diff.Removed)
+			summary.Changed += len(diff.Changed)
+			next += 1
+		}
+	}
+	if ctx.Err() != nil {
+		return summary, ctx.Err()
+	}
+	return summary, nil
+}
+
+func diffBlockWithRetry(ctx context.Context, store repository.Store, chainName string, contract *abi.SparkLink, client chain.Client, height uint64) (diff *ReindexDiff, err error) {
+	l := logrus.WithFields(logrus.Fields{"chain": chainName, "worker": "reindex", "height": height})
+	for attempt := 1; ; attempt++ {
+		diff, err = diffBlock(ctx, store, chainName, contract, client, height)
+		if err == nil || attempt >= reindexFetchRetry {
+			return diff, err
+		}
+		l.Warnf("Attempt %d failed: %s", attempt, err.Error())
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+}
+
+// diffBlock compares events of a block on chain with those in DB.
+func diffBlock(ctx context.Context, store repository.Store, chainName string, contract *abi.SparkLink, client chain.Client, height uint64) (diff *ReindexDiff, err error) {
+	_, logs, err := chain.GetBlockLogsOf(ctx, client, chainName, new(big.Int).SetUint64(height))
+	if err != nil {
+		return nil, xerrors.Errorf("error when fetching block %d: %w", height, err)
+	}
+	publish_logs, err := chain.FilterEventPublish(contract, logs)
+	if err != nil {
+		return nil, xerrors.Errorf("%w", err)
+	}
+	transfer_logs, err := chain.FilterEventTransfer(contract, logs)
+	if err != nil {
+		return nil, xerrors.Errorf("%w", err)
+	}
+	on_chain := append(model.EventsFromPublish(chainName, publish_logs), model.EventsFromTransfer(chainName, transfer_logs)...)
+
+	in_db, err := store.Events().FindInBlock(chainName, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffEvents(height, on_chain, in_db), nil
+}
+
+func diffEvents(height uint64, on_chain, in_db []*model.Event) (diff *ReindexDiff) {
+	diff = &ReindexDiff{Height: height}
+	saved := make(map[string]*model.Event, len(in_db))
+	for _, event := range in_db {
+		if _, duplicated := saved[event.Key()]; duplicated {
+			diff.Removed = append(diff.Removed, event)
+			continue
+		}
+		saved[event.Key()] = event
+	}
+
+	for _, event := range on_chain {
+		found, ok := saved[event.Key()]
+		if !ok {
+			diff.Added = append(diff.Added, event)
+			continue
+		}
+		delete(saved, event.Key())
+		if !found.SameAs(*event) {
+			changed := *event
+			changed.Id = found.Id
+			diff.Changed = append(diff.Changed, &changed)
+		}
+	}
+	for _, event := range saved {
+		diff.Removed = append(diff.Removed, event)
+	}
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Id < diff.Removed[j].Id })
+	return diff
+}
+
+// applyReindexDiff saves diff of a block and NFTs affected by it in one
+// transaction: NFTs of added events are created or updated, while those
+// of removed events are reverted. If ctx is cancelled while calling
+// contract, nothing of the block is saved.
+func applyReindexDiff(ctx context.Context, contract *abi.SparkLink, options ReindexOptions, diff *ReindexDiff) (err error) {
+	if options.Report != nil {
+		options.Report(diff)
+	}
+	if options.DryRun || diff.Empty() {
+		return nil
+	}
+
+	err = options.Store.Transaction(func(tx repository.Store) error {
+		for _, event := range diff.Removed {
+			if err := tx.Events().Delete(event.Id); err != nil {
+				return err
+			}
+		}
+		for _, event := range diff.Changed {
+			if err := tx.Events().Update(event); err != nil {
+				return err
+			}
+		}
+		if len(diff.Added) > 0 {
+			if _, err := tx.Events().CreateIgnoreExisting(diff.Added); err != nil {
+				return xerrors.Errorf("error when inserting events: %w", err)
+			}
+		}
+
+		if err := reindex_nfts(ctx, contract, tx, options.Chain, diff.Added); err != nil {
+			return err
+		}
+		reverted := make([]*model.Event, 0, len(diff.Removed)+len(diff.Changed))
+		reverted = append(reverted, diff.Removed...)
+		reverted = append(reverted, diff.Changed...)
+		return unindex_nfts(tx, options.Chain, reverted)
+	})
+	if err != nil {
+		return xerrors.Errorf("%w", err)
+	}
+	return nil
+}
+
+// reindex_nfts creates NFTs missing in DB, and updates owners if no later
+// transfer is saved.
+func reindex_nfts(ctx context.Context, contract *abi.SparkLink, store repository.Store, chainName string, events []*model.Event) (err error) {
+	transfers := make([]*model.Event, 0)
+	for _, event := range events {
+		if !event.IsTransfer() || event.IsMint() || event.TokenIdOverflow {
+			continue
+		}
+		later, err := store.Events().HasLaterTransfer(event)
+		if err != nil {
+			return err
+		}
+		if !later {
+			transfers = append(transfers, event)
+		}
+	}
+
+	// create_nfts skips existing NFTs.
+	if err = create_nfts(ctx, contract, store.NFTs(), chainName, events); err != nil {
+		return xerrors.Errorf("error when creating NFT: %w", err)
+	}
+	if err = update_nfts(store.NFTs(), chainName, transfers); err != nil {
+		return xerrors.Errorf("error when updating NFT: %w", err)
+	}
+	return nil
+}
+
+// unindex_nfts reverts NFTs touched by events removed from (or moved
+// within) the chain, using Transfers still saved: NFTs no longer minted
+// are deleted and their parents lose a shill, while others go back to the
+// owner of their latest Transfer.
+func unindex_nfts(store repository.Store, chainName string, events []*model.Event) (err error) {
+	l := logrus.WithFields(logrus.Fields{"chain": chainName, "worker": "unindex_nfts"})
+	seen := make(map[model.TokenId]bool, len(events))
+	for _, event := range events {
+		if !event.IsTransfer() || event.TokenIdOverflow || seen[event.NFTId] {
+			continue
+		}
+		seen[event.NFTId] = true
+
+		nft, err := store.NFTs().Find(chainName, event.NFTId)
+		if xerrors.Is(err, model.ErrNFTNotFound) {
+			continue
+		} else if err != nil {
+			return xerrors.Errorf("%w", err)
+		}
+
+		latest, err := store.Events().FindLatestTransfer(chainName, nft.NFTID)
+		if xerrors.Is(err, model.ErrEventNotFound) {
+			l.WithField("NFTID", nft.NFTID).Infof("NFT no longer minted. Deleting")
+			if err := store.NFTs().Delete(chainName, nft.NFTID); err != nil {
+				return xerrors.Errorf("%w", err)
+			}
+			if err := decrease_shill_count(store.NFTs(), chainName, nft.Parent); err != nil {
+				return xerrors.Errorf("%w", err)
+			}
+			continue
+		} else if err != nil {
+			return xerrors.Errorf("%w", err)
+		}
+
+		if nft.Owner != latest.To {
+			l.WithFields(logrus.Fields{"NFTID": nft.NFTID, "Owner": latest.To}).Infof("Reverting NFT owner")
+			nft.Owner = latest.To
+			if err := store.NFTs().Update(nft, "owner"); err != nil {
+				return xerrors.Errorf("%w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// decrease_shill_count takes back a shill of parent, if parent is not a
+// root placeholder and still exists.
+func decrease_shill_count(repo repository.NFTs, chainName string, parent model.TokenId) error {
+	if parent == 0 {
+		return nil
+	}
+	nft, err := repo.Find(chainName, parent)
+	if xerrors.Is(err, model.ErrNFTNotFound) {
+		return nil
+	} else if err != nil {
+		return xerrors.Errorf("error when finding NFT ID %s: %w", parent, err)
+	}
+	if nft.ShillCount == 0 {
+		return nil
+	}
+	nft.ShillCount -= 1
+	return repo.Update(nft, "shill_count")
+}
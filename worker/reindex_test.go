@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SparkNFT/key_server/model"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func Test_diffEvents(t *testing.T) {
+	event := func(id uint64, tx_hash string, index uint, to string) *model.Event {
+		return &model.Event{
+			Id:          id,
+			Chain:       chainName,
+			BlockHeight: 100,
+			TxHash:      tx_hash,
+			Index:       index,
+			Type:        model.EventTypeTransfer,
+			To:          to,
+		}
+	}
+
+	on_chain := []*model.Event{
+		event(0, "0xAA", 0, "0x1"), // unchanged
+		event(0, "0xaa", 1, "0x2"), // changed
+		event(0, "0xbb", 0, "0x3"), // added
+	}
+	in_db := []*model.Event{
+		event(1, "0xaa", 0, "0x1"),
+		event(2, "0xaa", 0, "0x1"), // duplicated
+		event(3, "0xaa", 1, "0x9"),
+		event(4, "0xcc", 0, "0x4"), // not on chain
+	}
+
+	diff := diffEvents(100, on_chain, in_db)
+	assert.Equal(t, uint64(100), diff.Height)
+	assert.Len(t, diff.Added, 1)
+	assert.Equal(t, "0xbb", diff.Added[0].TxHash)
+
+	assert.Len(t, diff.Changed, 1)
+	assert.Equal(t, uint64(3), diff.Changed[0].Id)
+	assert.Equal(t, "0x2", diff.Changed[0].To)
+
+	assert.Len(t, diff.Removed, 2)
+	assert.Equal(t, uint64(2), diff.Removed[0].Id)
+	assert.Equal(t, uint64(4), diff.Removed[1].Id)
+
+	assert.True(t, diffEvents(100, nil, nil).Empty())
+}
+
+func Test_applyReindexDiff_reorg(t *testing.T) {
+	store := repository.NewMemory()
+	owner, buyer := "0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000002"
+	zero := "0x0000000000000000000000000000000000000000"
+	root, child := model.TokenId(0x100000001), model.TokenId(0x100000002)
+	transfer := func(height uint64, tx_hash string, nft_id model.TokenId, from, to string) *model.Event {
+		return &model.Event{Chain: chainName, BlockHeight: height, TxHash: tx_hash, Type: model.EventTypeTransfer, NFTId: nft_id, From: from, To: to}
+	}
+
+	// Block 90 mints root. Block 100 mints its child and sells root.
+	_, err := store.Events().CreateIgnoreExisting([]*model.Event{
+		transfer(90, "0x90", root, zero, owner),
+		transfer(100, "0xa0", child, zero, owner),
+		transfer(100, "0xa1", root, owner, buyer),
+	})
+	require.Nil(t, err)
+	require.Nil(t, store.NFTs().Create([]*model.NFT{
+		{Chain: chainName, NFTID: root, ShillCount: 1, MaxShillCount: 10, Owner: buyer},
+		{Chain: chainName, NFTID: child, Parent: root, MaxShillCount: 10, Owner: owner},
+	}))
+
+	// Block 100 is reorged away, leaving an empty block.
+	in_db, err := store.Events().FindInBlock(chainName, 100)
+	require.Nil(t, err)
+	diff := diffEvents(100, []*model.Event{}, in_db)
+	require.Len(t, diff.Removed, 2)
+	require.Nil(t, applyReindexDiff(context.Background(), nil, ReindexOptions{Store: store, Chain: chainName}, diff))
+
+	_, err = store.NFTs().Find(chainName, child)
+	assert.True(t, xerrors.Is(err, model.ErrNFTNotFound), "NFT minted in orphaned block is gone")
+	nft, err := store.NFTs().Find(chainName, root)
+	require.Nil(t, err)
+	assert.Equal(t, uint16(0), nft.ShillCount)
+	assert.Equal(t, owner, nft.Owner)
+}
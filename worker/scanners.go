@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/SparkNFT/key_server/repository"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// Last config seen of each chain, for scanners of chains removed by
+	// a reload to finish their block.
+	lastChainConfigs     = make(map[string]*config.ChainConfig)
+	lastChainConfigsLock sync.Mutex
+)
+
+// chainConfigOf returns current config of chain. A chain removed by a
+// config reload keeps its last config until its scanner stops.
+func chainConfigOf(chainName string) *config.ChainConfig {
+	lastChainConfigsLock.Lock()
+	defer lastChainConfigsLock.Unlock()
+	if chainConfig, ok := config.Get().Chain[chainName]; ok && chainConfig != nil {
+		lastChainConfigs[chainName] = chainConfig
+		return chainConfig
+	}
+	return lastChainConfigs[chainName]
+}
+
+// runningScanner is a supervised BlockScannerWorker of one chain.
+type runningScanner struct {
+	cancel context.CancelFunc
+	done   <-chan struct{}
+	// Scanner restarts if these change. Other fields of ChainConfig are
+	// read on every block.
+	rpc             string
+	contractAddress string
+}
+
+// scanners keeps one BlockScannerWorker running for each enabled chain.
+type scanners struct {
+	lock    sync.Mutex
+	ctx     context.Context
+	running map[string]*runningScanner
+	// worker builds WorkerFunc scanning a chain.
+	worker func(chainName string) WorkerFunc
+}
+
+func newScanners(ctx context.Context, store repository.Store) *scanners {
+	return &scanners{
+		ctx:     ctx,
+		running: make(map[string]*runningScanner),
+		worker: func(chainName string) WorkerFunc {
+			return WithLeaderLock(store, "block_scanner", chainName, func(ctx context.Context) error {
+				return BlockScannerWorker(ctx, store, chainName)
+			})
+		},
+	}
+}
+
+// reconcile starts scanners of newly enabled chains, and stops scanners
+// of chains removed or disabled in c. A scanner whose RPC endpoints or
+// contract changed is restarted after its current block. Returns once
+// stopped scanners are gone; s is not locked while waiting for them.
+func (s *scanners) reconcile(c *config.Config) {
+	stopped := s.update(c)
+	for _, done := range stopped {
+		<-done
+	}
+}
+
+// update cancels scanners to stop and starts missing ones. Returns
+// done channels of cancelled scanners.
+func (s *scanners) update(c *config.Config) (stopped map[string]<-chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.ctx.Err() != nil {
+		return nil
+	}
+	l := logrus.WithField("module", "scanners")
+
+	stopped = make(map[string]<-chan struct{})
+	for chainName, scanner := range s.running {
+		chainConfig, ok := c.Chain[chainName]
+		if ok && chainConfig != nil && chainConfig.Enabled &&
+			rpcOf(chainConfig) == scanner.rpc && chainConfig.ContractAddress == scanner.contractAddress {
+			continue
+		}
+		l.WithField("chain", chainName).Infof("Stopping scanner.")
+		scanner.cancel()
+		stopped[chainName] = scanner.done
+		delete(s.running, chainName)
+	}
+
+	for chainName, chainConfig := range c.Chain {
+		if chainConfig == nil || !chainConfig.Enabled {
+			continue
+		}
+		if _, ok := s.running[chainName]; ok {
+			continue
+		}
+		if err := CheckBlockScannerConfig(chainName); err != nil {
+			l.WithField("chain", chainName).Errorf("Not starting scanner: %s", err.Error())
+			continue
+		}
+		s.start(chainName, chainConfig, stopped[chainName])
+	}
+	return stopped
+}
+
+// start supervises a scanner of chain. If previous is not nil, the
+// scanner waits for it to be closed first, so that a restarted scanner
+// never runs next to the one it replaces.
+func (s *scanners) start(chainName string, chainConfig *config.ChainConfig, previous <-chan struct{}) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	worker := s.worker(chainName)
+	done := Supervise(ctx, "block_scanner", chainName, func(ctx context.Context) error {
+		if previous != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-previous:
+			}
+		}
+		return worker(ctx)
+	})
+	s.running[chainName] = &runningScanner{
+		cancel:          cancel,
+		done:            done,
+		rpc:             rpcOf(chainConfig),
+		contractAddress: chainConfig.ContractAddress,
+	}
+}
+
+// rpcOf summarizes RPC endpoints, pool settings and scan mode of chain,
+// to tell if they are changed.
+func rpcOf(chainConfig *config.ChainConfig) string {
+	summary, _ := json.Marshal([]interface{}{chainConfig.RPCEndpoints(), chainConfig.RPC, chainConfig.ScanMode})
+	return string(summary)
+}
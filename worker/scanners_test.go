@@ -0,0 +1,153 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SparkNFT/key_server/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func scanners_config(rpc_urls map[string]string) *config.Config {
+	c := &config.Config{Chain: map[string]*config.ChainConfig{}}
+	for name, rpc_url := range rpc_urls {
+		c.Chain[name] = &config.ChainConfig{
+			Enabled:         true,
+			RPCUrl:          rpc_url,
+			ContractAddress: "0x7B5B92B0eD1DfeafdbD724b177A7733Bda67497F",
+			BlockHeight:     1,
+		}
+	}
+	return c
+}
+
+func Test_scanners_reconcile(t *testing.T) {
+	defer config.Set(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lock := sync.Mutex{}
+	running := map[string]int{}
+	started := map[string]int{}
+	s := newScanners(ctx, nil)
+	s.worker = func(chainName string) WorkerFunc {
+		return func(ctx context.Context) error {
+			lock.Lock()
+			running[chainName] += 1
+			started[chainName] += 1
+			lock.Unlock()
+			<-ctx.Done()
+			lock.Lock()
+			running[chainName] -= 1
+			lock.Unlock()
+			return nil
+		}
+	}
+	snapshot := func() (map[string]int, map[string]int) {
+		lock.Lock()
+		defer lock.Unlock()
+		r, s := map[string]int{}, map[string]int{}
+		for k, v := range running {
+			r[k] = v
+		}
+		for k, v := range started {
+			s[k] = v
+		}
+		return r, s
+	}
+
+	c := scanners_config(map[string]string{"ethereum": "https://eth.example.com", "bsc": "https://bsc.example.com"})
+	config.Set(c)
+	s.reconcile(c)
+	assert.Eventually(t, func() bool {
+		r, _ := snapshot()
+		return r["ethereum"] == 1 && r["bsc"] == 1
+	}, time.Second, time.Millisecond)
+
+	// bsc removed, matic added, ethereum RPC changed
+	c = scanners_config(map[string]string{"ethereum": "https://eth2.example.com", "matic": "https://matic.example.com"})
+	config.Set(c)
+	s.reconcile(c)
+	assert.Eventually(t, func() bool {
+		r, s := snapshot()
+		return r["ethereum"] == 1 && r["bsc"] == 0 && r["matic"] == 1 && s["ethereum"] == 2
+	}, time.Second, time.Millisecond)
+
+	// Timing only: nothing restarts
+	c = scanners_config(map[string]string{"ethereum": "https://eth2.example.com", "matic": "https://matic.example.com"})
+	c.Chain["ethereum"].Sleep = config.Duration(time.Minute)
+	c.Chain["matic"].Enabled = false
+	config.Set(c)
+	s.reconcile(c)
+	assert.Eventually(t, func() bool {
+		r, s := snapshot()
+		return r["ethereum"] == 1 && r["matic"] == 0 && s["ethereum"] == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, time.Minute, chainConfigOf("ethereum").Sleep.Duration())
+}
+
+func Test_chainConfigOf(t *testing.T) {
+	defer config.Set(nil)
+	c := scanners_config(map[string]string{"ethereum": "https://eth.example.com"})
+	config.Set(c)
+	assert.Equal(t, "https://eth.example.com", chainConfigOf("ethereum").RPCUrl)
+
+	// Removed chain keeps its last config
+	config.Set(scanners_config(map[string]string{}))
+	assert.Equal(t, "https://eth.example.com", chainConfigOf("ethereum").RPCUrl)
+	assert.Nil(t, chainConfigOf("nonexist"))
+}
+
+func Test_scanners_reconcile_slow_stop(t *testing.T) {
+	defer config.Set(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	release := make(chan struct{})
+	runs := make(chan string, 4)
+	s := newScanners(ctx, nil)
+	s.worker = func(chainName string) WorkerFunc {
+		return func(ctx context.Context) error {
+			runs <- chainName
+			<-ctx.Done()
+			<-release // Finishing a slow block
+			return nil
+		}
+	}
+
+	c := scanners_config(map[string]string{"ethereum": "https://eth.example.com"})
+	config.Set(c)
+	s.reconcile(c)
+	assert.Equal(t, "ethereum", <-runs)
+
+	// RPC changed: old scanner is slow to stop.
+	c = scanners_config(map[string]string{"ethereum": "https://eth2.example.com"})
+	config.Set(c)
+	reconciled := make(chan struct{})
+	go func() {
+		defer close(reconciled)
+		s.reconcile(c)
+	}()
+
+	// Not locked while waiting, and replacement does not run yet.
+	assert.Eventually(t, func() bool {
+		if !s.lock.TryLock() {
+			return false
+		}
+		defer s.lock.Unlock()
+		return s.running["ethereum"] != nil && s.running["ethereum"].rpc == rpcOf(c.Chain["ethereum"])
+	}, time.Second, time.Millisecond)
+	select {
+	case <-runs:
+		t.Fatal("replacement runs next to old scanner")
+	case <-reconciled:
+		t.Fatal("reconcile returned before old scanner stopped")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-reconciled
+	assert.Equal(t, "ethereum", <-runs)
+}
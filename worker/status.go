@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SparkNFT/key_server/metrics"
+)
+
+// ScannerStatus is a snapshot of a running BlockScannerWorker.
+type ScannerStatus struct {
+	Chain     string
+	Head      uint64 // Latest block height fully scanned
+	ChainHead uint64 // Newest block height seen on chain
+	UpdatedAt time.Time
+
+	HeadUpdatedAt time.Time // When Head last advanced
+}
+
+var (
+	scannerStatus     = make(map[string]*ScannerStatus)
+	scannerStatusLock sync.RWMutex
+)
+
+// Lag returns how many blocks the scanner is behind chain head.
+func (status ScannerStatus) Lag() uint64 {
+	if status.ChainHead <= status.Head {
+		return 0
+	}
+	return status.ChainHead - status.Head
+}
+
+// ScannerStatusOf returns status of the scanner of given chain running
+// in current process. ok is false if no scanner has reported yet.
+func ScannerStatusOf(chainName string) (status ScannerStatus, ok bool) {
+	scannerStatusLock.RLock()
+	defer scannerStatusLock.RUnlock()
+	found, ok := scannerStatus[chainName]
+	if !ok {
+		return ScannerStatus{}, false
+	}
+	return *found, true
+}
+
+func updateScannerStatus(chainName string, update func(status *ScannerStatus)) {
+	scannerStatusLock.Lock()
+	defer scannerStatusLock.Unlock()
+	status, ok := scannerStatus[chainName]
+	if !ok {
+		status = &ScannerStatus{Chain: chainName}
+		scannerStatus[chainName] = status
+	}
+	head := status.Head
+	update(status)
+	status.UpdatedAt = time.Now()
+	if status.Head != head || status.HeadUpdatedAt.IsZero() {
+		status.HeadUpdatedAt = status.UpdatedAt
+	}
+
+	metrics.ScannerHead.WithLabelValues(chainName).Set(float64(status.Head))
+	metrics.ScannerChainHead.WithLabelValues(chainName).Set(float64(status.ChainHead))
+	metrics.ScannerLag.WithLabelValues(chainName).Set(float64(status.Lag()))
+}
+
+// clearScannerStatus forgets status of the scanner of given chain, once
+// it is no longer running in current process. Health checks and chain
+// info then fall back to what the current leader saved in DB.
+func clearScannerStatus(chainName string) {
+	scannerStatusLock.Lock()
+	defer scannerStatusLock.Unlock()
+	delete(scannerStatus, chainName)
+
+	metrics.ScannerHead.DeleteLabelValues(chainName)
+	metrics.ScannerChainHead.DeleteLabelValues(chainName)
+	metrics.ScannerLag.DeleteLabelValues(chainName)
+}
@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/SparkNFT/key_server/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func Test_updateScannerStatus(t *testing.T) {
+	updateScannerStatus("status_test", func(status *ScannerStatus) {
+		status.ChainHead = 110
+		status.Head = 100
+	})
+
+	status, ok := ScannerStatusOf("status_test")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(10), status.Lag())
+	assert.Equal(t, float64(100), testutil.ToFloat64(metrics.ScannerHead.WithLabelValues("status_test")))
+	assert.Equal(t, float64(10), testutil.ToFloat64(metrics.ScannerLag.WithLabelValues("status_test")))
+}
+
+func Test_fetchFailureReason(t *testing.T) {
+	err := xerrors.Errorf("wrapped: %w", fetchFailed("slow_down", xerrors.New("Slow down")))
+	assert.Equal(t, "slow_down", fetchFailureReason(err))
+	assert.Contains(t, err.Error(), "Slow down")
+	assert.Equal(t, "unknown", fetchFailureReason(xerrors.New("other")))
+}
@@ -0,0 +1,204 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+type WorkerState string
+
+const (
+	WorkerStarting   WorkerState = "starting"
+	WorkerRunning    WorkerState = "running"
+	WorkerRestarting WorkerState = "restarting" // Waiting for backoff after a failure
+	WorkerStopped    WorkerState = "stopped"
+)
+
+const (
+	supervisorMinBackoff = 1 * time.Second
+	supervisorMaxBackoff = 5 * time.Minute
+	// Backoff is reset if worker has run longer than this before failing.
+	supervisorHealthyRun = 1 * time.Minute
+)
+
+// WorkerFunc runs until ctx is cancelled. Returning an error or
+// panicking makes supervisor restart it.
+type WorkerFunc func(ctx context.Context) error
+
+// WorkerStatus is a snapshot of a supervised worker.
+type WorkerStatus struct {
+	Name      string      `json:"name"`
+	Chain     string      `json:"chain"`
+	State     WorkerState `json:"state"`
+	Restarts  uint        `json:"restarts"`
+	StartedAt time.Time   `json:"started_at"`
+	// LastFailure is why worker last restarted, "error" or "panic". Its
+	// error is in LastError and logs, never served: it may tell hosts or
+	// SQL.
+	LastFailure string `json:"last_failure,omitempty"`
+	LastError   string `json:"-"`
+	// Leader is nil if worker does not use leader election. Workers
+	// not being leader are standing by.
+	Leader *bool `json:"leader"`
+}
+
+// Supervisor runs workers in goroutines, restarting them with
+// exponential backoff when they fail, until context is cancelled.
+type Supervisor struct {
+	lock    sync.RWMutex
+	workers map[string]*WorkerStatus
+	wg      sync.WaitGroup
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+var (
+	supervisor = NewSupervisor()
+)
+
+func NewSupervisor() *Supervisor {
+	return &Supervisor{
+		workers:    make(map[string]*WorkerStatus),
+		minBackoff: supervisorMinBackoff,
+		maxBackoff: supervisorMaxBackoff,
+	}
+}
+
+// Supervise starts fn under default supervisor.
+func Supervise(ctx context.Context, name, chainName string, fn WorkerFunc) <-chan struct{} {
+	return supervisor.Go(ctx, name, chainName, fn)
+}
+
+// Wait blocks until all workers of default supervisor are stopped.
+func Wait() {
+	supervisor.Wait()
+}
+
+// Statuses returns status of all workers of default supervisor.
+func Statuses() []WorkerStatus {
+	return supervisor.Statuses()
+}
+
+// Go starts fn in a goroutine and keeps it running until ctx is done.
+// Returned channel is closed once worker is stopped.
+func (s *Supervisor) Go(ctx context.Context, name, chainName string, fn WorkerFunc) <-chan struct{} {
+	key := name + ":" + chainName
+	s.lock.Lock()
+	s.workers[key] = &WorkerStatus{Name: name, Chain: chainName, State: WorkerStarting}
+	s.lock.Unlock()
+
+	done := make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(done)
+		s.run(ctx, key, fn)
+	}()
+	return done
+}
+
+func (s *Supervisor) run(ctx context.Context, key string, fn WorkerFunc) {
+	l := logrus.WithFields(logrus.Fields{"module": "supervisor", "worker": key})
+	backoff := s.minBackoff
+	for {
+		started_at := time.Now()
+		s.update(key, func(status *WorkerStatus) {
+			status.State = WorkerRunning
+			status.StartedAt = started_at
+		})
+
+		err := runProtected(ctx, fn)
+		if ctx.Err() != nil {
+			l.Infof("Worker stopped.")
+			s.update(key, func(status *WorkerStatus) {
+				status.State = WorkerStopped
+			})
+			return
+		}
+		if err == nil {
+			err = xerrors.New("worker returned without being stopped")
+		}
+
+		if time.Since(started_at) > supervisorHealthyRun {
+			backoff = s.minBackoff
+		}
+		l.Errorf("Worker failed, restarting in %s: %s", backoff, err.Error())
+		s.update(key, func(status *WorkerStatus) {
+			status.State = WorkerRestarting
+			status.Restarts += 1
+			status.LastFailure = failureOf(err)
+			status.LastError = err.Error()
+		})
+
+		select {
+		case <-ctx.Done():
+			s.update(key, func(status *WorkerStatus) {
+				status.State = WorkerStopped
+			})
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// runProtected converts panic in fn into error.
+func runProtected(ctx context.Context, fn WorkerFunc) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logrus.WithFields(logrus.Fields{"module": "supervisor", "stack": string(debug.Stack())}).
+				Errorf("Worker panicked: %s", fmt.Sprint(recovered))
+			err = xerrors.Errorf("%w: %s", errWorkerPanicked, fmt.Sprint(recovered))
+		}
+	}()
+	return fn(ctx)
+}
+
+// failureOf tells a panic from an error returned by worker.
+func failureOf(err error) string {
+	if xerrors.Is(err, errWorkerPanicked) {
+		return "panic"
+	}
+	return "error"
+}
+
+func (s *Supervisor) update(key string, fn func(status *WorkerStatus)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fn(s.workers[key])
+}
+
+// Wait blocks until all workers are stopped.
+func (s *Supervisor) Wait() {
+	s.wg.Wait()
+}
+
+// Statuses returns status of all workers, sorted by name and chain.
+func (s *Supervisor) Statuses() []WorkerStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	result := make([]WorkerStatus, 0, len(s.workers))
+	for _, status := range s.workers {
+		status := *status
+		status.Leader = leaderOf(status.Name, status.Chain)
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Chain < result[j].Chain
+	})
+	return result
+}
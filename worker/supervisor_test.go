@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func Test_Supervisor(t *testing.T) {
+	t.Run("restart on panic and error", func(t *testing.T) {
+		s := NewSupervisor()
+		s.minBackoff = time.Millisecond
+		s.maxBackoff = 4 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+
+		runs := int32(0)
+		s.Go(ctx, "test", "ethereum", func(ctx context.Context) error {
+			switch atomic.AddInt32(&runs, 1) {
+			case 1:
+				panic("boom")
+			case 2:
+				return xerrors.New("failed")
+			default:
+				<-ctx.Done()
+				return nil
+			}
+		})
+
+		assert.Eventually(t, func() bool {
+			statuses := s.Statuses()
+			return statuses[0].State == WorkerRunning && statuses[0].Restarts == 2
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, "failed", s.Statuses()[0].LastError)
+		assert.Equal(t, "error", s.Statuses()[0].LastFailure)
+		served, err := json.Marshal(s.Statuses()[0])
+		require.Nil(t, err)
+		assert.NotContains(t, string(served), "failed")
+
+		cancel()
+		s.Wait()
+		assert.Equal(t, WorkerStopped, s.Statuses()[0].State)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+	})
+
+	t.Run("stop during backoff", func(t *testing.T) {
+		s := NewSupervisor()
+		s.minBackoff = time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+		s.Go(ctx, "test", "bsc", func(ctx context.Context) error {
+			return xerrors.New("failed")
+		})
+
+		assert.Eventually(t, func() bool {
+			return s.Statuses()[0].State == WorkerRestarting
+		}, time.Second, time.Millisecond)
+		cancel()
+		s.Wait()
+		assert.Equal(t, WorkerStopped, s.Statuses()[0].State)
+	})
+}